@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/handler/bulk"
+	"go.ectobit.com/arc/handler/invite"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/arc/repository/postgres"
+	"go.ectobit.com/arc/send/smtp"
+	"go.ectobit.com/lax"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errRowsFailed     = errors.New("rows failed")
+)
+
+// splitCommand splits leading positional arguments forming the command from the flags.
+func splitCommand(args []string) (string, []string) {
+	i := 0
+
+	for i < len(args) && !strings.HasPrefix(args[i], "-") {
+		i++
+	}
+
+	return strings.Join(args[:i], " "), args[i:]
+}
+
+// newKeyring creates keyring either from keys directory or from the statically configured key.
+func newKeyring(cfg *config) (*token.Keyring, error) {
+	if cfg.JWT.KeysDir != "" {
+		keyring, err := token.LoadKeyring(cfg.JWT.KeysDir, cfg.JWT.Algorithm, keysRetention(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("load keyring: %w", err)
+		}
+
+		return keyring, nil
+	}
+
+	key, err := token.NewKey(cfg.JWT.Algorithm, cfg.JWT.Secret, cfg.JWT.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("key: %w", err)
+	}
+
+	return token.NewStaticKeyring(key), nil
+}
+
+// reloadKeys periodically reloads keyring so that keys rotated by other instances are picked up.
+func reloadKeys(keyring *token.Keyring, interval time.Duration, log lax.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := keyring.Reload(); err != nil {
+			log.Warn("reload keys", lax.Error(err))
+		}
+	}
+}
+
+// deleteScheduledUsers periodically deletes users whose scheduled deletion time has passed.
+func deleteScheduledUsers(usersRepo repository.Users, interval time.Duration, log lax.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := usersRepo.DeleteScheduled(context.Background())
+		if err != nil {
+			log.Warn("delete scheduled users", lax.Error(err))
+
+			continue
+		}
+
+		if count != 0 {
+			log.Info("deleted scheduled users", lax.Uint("count", uint(count)))
+		}
+	}
+}
+
+// rotateKeys implements keys rotate command.
+func rotateKeys(cfg *config) {
+	if cfg.JWT.KeysDir == "" {
+		exit("rotate keys", token.ErrRotationUnsupported)
+	}
+
+	key, err := token.NewKeyring(cfg.JWT.KeysDir, cfg.JWT.Algorithm, keysRetention(cfg)).Rotate()
+	if err != nil {
+		exit("rotate keys", err)
+	}
+
+	fmt.Printf("new signing key %s (%s)\n", key.ID(), key.Algorithm()) //nolint:forbidigo
+}
+
+// keysRetention returns period during which retired keys remain valid, which is the lifetime of the longest living
+// token.
+func keysRetention(cfg *config) time.Duration {
+	if cfg.JWT.AuthTokenExp > cfg.JWT.RefreshTokenExp {
+		return cfg.JWT.AuthTokenExp
+	}
+
+	return cfg.JWT.RefreshTokenExp
+}
+
+// importUsers implements users import command. Users are read from standard input and report is written to standard
+// output. Command fails if any row fails.
+func importUsers(cfg *config) {
+	log := mustCreateLogger(cfg.Log.Format, cfg.Log.Level)
+	defer log.Flush()
+
+	bulkUsers := newBulkUsers(cfg, log)
+
+	report, err := bulkUsers.Import(context.Background(), os.Stdin, &bulk.Options{
+		Format:  cfg.Bulk.Format,
+		Invite:  cfg.Bulk.Invite,
+		Inviter: invite.CLIInviter,
+		DryRun:  cfg.Bulk.DryRun,
+	})
+	if err != nil {
+		exit("import users", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		exit("write report", err)
+	}
+
+	if report.Failed != 0 {
+		exit("import users", fmt.Errorf("%w: %d of %d", errRowsFailed, report.Failed, report.Total))
+	}
+}
+
+// exportUsers implements users export command. Users are written to standard output.
+func exportUsers(cfg *config) {
+	log := mustCreateLogger(cfg.Log.Format, cfg.Log.Level)
+	defer log.Flush()
+
+	if err := newBulkUsers(cfg, log).Export(context.Background(), os.Stdout, cfg.Bulk.Format); err != nil {
+		exit("export users", err)
+	}
+}
+
+// newBulkUsers creates users importer and exporter connected to the database and mail server.
+func newBulkUsers(cfg *config, log *lax.ZapAdapter) *bulk.Users {
+	pool, err := postgres.Connect(context.Background(), cfg.DSN, log, cfg.Log.Level)
+	if err != nil {
+		exit("postgres", err)
+	}
+
+	mailer := smtp.NewMailer(cfg.SMTP.Host, uint16(cfg.SMTP.Port), cfg.SMTP.Username, cfg.SMTP.Password,
+		cfg.SMTP.Sender, log)
+
+	usersRepository := postgres.NewUserRepository(pool)
+	inviter := invite.NewInviter(postgres.NewInvitationsRepository(pool), usersRepository, mailer,
+		cfg.ExternalURL.String(), cfg.FrontendInvitationPath, cfg.InvitationExp)
+
+	return bulk.NewUsers(usersRepository, inviter, log)
+}
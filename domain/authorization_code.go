@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+)
+
+// AuthorizationCode contains OAuth authorization code data.
+type AuthorizationCode struct {
+	ClientID      string
+	UserID        string
+	FamilyID      string
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	AuthTime      *time.Time
+	Used          *time.Time
+	Expires       *time.Time
+	Created       *time.Time
+}
+
+// IsValidVerifier checks if provided PKCE code verifier matches S256 code challenge.
+func (c *AuthorizationCode) IsValidVerifier(codeVerifier string) bool {
+	if codeVerifier == "" {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(codeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(hash[:])
+
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(c.CodeChallenge)) == 1
+}
@@ -0,0 +1,37 @@
+package domain_test
+
+import (
+	"testing"
+
+	"go.ectobit.com/arc/domain"
+)
+
+func TestIsValidVerifier(t *testing.T) {
+	t.Parallel()
+
+	// Example from RFC 7636 appendix B.
+	code := domain.AuthorizationCode{ //nolint:exhaustruct
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}
+
+	tests := map[string]struct {
+		in   string
+		want bool
+	}{
+		"valid":   {"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", true},
+		"invalid": {"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXx", false},
+		"empty":   {"", false},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			if got := code.IsValidVerifier(test.in); got != test.want {
+				t.Errorf("IsValidVerifier(%q) = %t; want %t", test.in, got, test.want)
+			}
+		})
+	}
+}
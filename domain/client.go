@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"time"
+)
+
+// Client contains OAuth client data.
+type Client struct {
+	ID           string
+	Name         string
+	SecretHash   []byte
+	RedirectURIs []string
+	Scopes       []string
+	Trusted      bool
+	Created      *time.Time
+}
+
+// HasRedirectURI checks if redirect uri is registered for the client. Redirect uris are matched exactly.
+func (c *Client) HasRedirectURI(redirectURI string) bool {
+	return contains(c.RedirectURIs, redirectURI)
+}
+
+// HasScope checks if client is allowed to request provided scope.
+func (c *Client) HasScope(scope string) bool {
+	return contains(c.Scopes, scope)
+}
+
+// IsConfidential checks if client authenticates using secret.
+func (c *Client) IsConfidential() bool {
+	return len(c.SecretHash) > 0
+}
+
+// IsValidSecret checks if provided plain secret matches hashed secret of confidential client.
+func (c *Client) IsValidSecret(plainSecret string) bool {
+	if plainSecret == "" || !c.IsConfidential() {
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(plainSecret))
+
+	return subtle.ConstantTimeCompare(hash[:], c.SecretHash) == 1
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package domain_test
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"go.ectobit.com/arc/domain"
+)
+
+func TestHasRedirectURI(t *testing.T) {
+	t.Parallel()
+
+	client := domain.Client{ //nolint:exhaustruct
+		RedirectURIs: []string{"https://app.example.com/callback"},
+	}
+
+	tests := map[string]struct {
+		in   string
+		want bool
+	}{
+		"registered":     {"https://app.example.com/callback", true},
+		"different path": {"https://app.example.com/callback/other", false},
+		"query appended": {"https://app.example.com/callback?next=/", false},
+		"empty":          {"", false},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			if got := client.HasRedirectURI(test.in); got != test.want {
+				t.Errorf("HasRedirectURI(%q) = %t; want %t", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsValidSecret(t *testing.T) {
+	t.Parallel()
+
+	hash := sha256.Sum256([]byte("secret"))
+
+	confidential := domain.Client{SecretHash: hash[:]} //nolint:exhaustruct
+	public := domain.Client{}                          //nolint:exhaustruct
+
+	if !confidential.IsValidSecret("secret") {
+		t.Error(`IsValidSecret("secret") = false; want true`)
+	}
+
+	if confidential.IsValidSecret("other") || confidential.IsValidSecret("") {
+		t.Error("IsValidSecret() = true for invalid secret; want false")
+	}
+
+	if public.IsConfidential() || public.IsValidSecret("secret") {
+		t.Error("public client treated as confidential")
+	}
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// Consent contains scopes the user has allowed the OAuth client to access.
+type Consent struct {
+	UserID   string
+	ClientID string
+	Scopes   []string
+	Created  *time.Time
+	Updated  *time.Time
+}
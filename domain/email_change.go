@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// EmailChange contains pending change of the user's email address. Change is confirmed by the token sent to the new
+// address and may be canceled by the token sent to the old one.
+type EmailChange struct {
+	UserID           string
+	NewEmail         string
+	ConfirmTokenHash []byte
+	CancelTokenHash  []byte
+	Expires          time.Time
+	Created          *time.Time
+}
+
+// IsExpired checks if email change is expired at provided time.
+func (c *EmailChange) IsExpired(now time.Time) bool {
+	return !now.Before(c.Expires)
+}
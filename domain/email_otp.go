@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"time"
+)
+
+// Purposes of email OTP challenges. Codes issued for one purpose can't be used for another one.
+const (
+	EmailOTPLogin  = "login"
+	EmailOTPStepUp = "step_up"
+)
+
+// EmailOTP contains hash of the one-time password sent to the user per email, either as the second factor at login
+// or to re-authenticate before sensitive operation.
+type EmailOTP struct {
+	UserID   string
+	Purpose  string
+	CodeHash []byte
+	Attempts int
+	Expires  time.Time
+	Created  *time.Time
+}
+
+// IsExpired checks if challenge is expired at provided time.
+func (o *EmailOTP) IsExpired(now time.Time) bool {
+	return !now.Before(o.Expires)
+}
+
+// Matches compares provided code hash with the stored one in constant time.
+func (o *EmailOTP) Matches(codeHash []byte) bool {
+	return subtle.ConstantTimeCompare(o.CodeHash, codeHash) == 1
+}
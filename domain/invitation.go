@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Invitation contains invitation of a new user sent by administrator or another authorized user. Invitee chooses
+// password while accepting the invitation and gets the intended role. Token is not stored, just its hash.
+type Invitation struct {
+	ID        string
+	Email     string
+	Role      string
+	Inviter   string
+	TokenHash []byte
+	Expires   time.Time
+	Accepted  *time.Time
+	Created   *time.Time
+}
+
+// IsExpired checks if invitation is expired at provided time.
+func (i *Invitation) IsExpired(now time.Time) bool {
+	return !now.Before(i.Expires)
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// MagicLink contains hash of the single-use login token sent to the user per email.
+type MagicLink struct {
+	UserID    string
+	TokenHash []byte
+	Expires   time.Time
+	Created   *time.Time
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// RefreshToken contains refresh token data.
+type RefreshToken struct {
+	FamilyID string
+	UserID   string
+	Used     *time.Time
+	Expires  *time.Time
+	Created  *time.Time
+	Revoked  *time.Time
+}
+
+// IsActive checks if refresh token may still be rotated.
+func (t *RefreshToken) IsActive() bool {
+	return t.Used == nil && t.Revoked == nil && t.Expires != nil && t.Expires.After(time.Now())
+}
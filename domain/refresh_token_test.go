@@ -0,0 +1,37 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+func TestRefreshTokenIsActive(t *testing.T) {
+	t.Parallel()
+
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+
+	tests := map[string]struct {
+		in   domain.RefreshToken
+		want bool
+	}{
+		"active":  {domain.RefreshToken{Expires: &future}, true},                  //nolint:exhaustruct
+		"used":    {domain.RefreshToken{Expires: &future, Used: &past}, false},    //nolint:exhaustruct
+		"revoked": {domain.RefreshToken{Expires: &future, Revoked: &past}, false}, //nolint:exhaustruct
+		"expired": {domain.RefreshToken{Expires: &past}, false},                   //nolint:exhaustruct
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.in.IsActive(); got != test.want {
+				t.Errorf("IsActive() = %t; want %t", got, test.want)
+			}
+		})
+	}
+}
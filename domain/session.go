@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// Session contains data of the device user is logged in from. Session is identified by its refresh token family.
+type Session struct {
+	ID        string
+	UserID    string
+	UserAgent string
+	IP        string
+	Created   *time.Time
+	LastSeen  *time.Time
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TOTP parameters as expected by common authenticator apps.
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+	totpSkew   = 1
+	totpModulo = 1_000_000
+)
+
+// TOTP contains time-based one-time password (RFC 6238) second factor data of the user.
+type TOTP struct {
+	UserID    string
+	Secret    string
+	LastStep  int64
+	Failures  int
+	Confirmed *time.Time
+	Created   *time.Time
+}
+
+// IsConfirmed checks if enrollment has been confirmed by the first valid code, so that second factor is required
+// at login.
+func (t *TOTP) IsConfirmed() bool {
+	return t.Confirmed != nil
+}
+
+// Validate checks if code is valid at provided time, allowing one time step of clock skew in both directions.
+// Codes of already used time steps are rejected. Time step of the valid code is returned and should be stored as
+// the last used one.
+func (t *TOTP) Validate(code string, now time.Time) (int64, bool) {
+	step := now.Unix() / int64(TOTPPeriod.Seconds())
+
+	for i := int64(-totpSkew); i <= totpSkew; i++ {
+		if step+i <= t.LastStep {
+			continue
+		}
+
+		expected, err := TOTPCode(t.Secret, step+i)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+// TOTPCode calculates code of base32 encoded secret for the time step.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("decode secret: %w", err)
+	}
+
+	var counter [8]byte
+
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f                                    //nolint:gomnd
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff //nolint:gomnd
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%totpModulo), nil
+}
@@ -0,0 +1,93 @@
+package domain_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+func TestTOTPCode(t *testing.T) {
+	t.Parallel()
+
+	// RFC 6238 appendix B test vectors for SHA1, truncated to 6 digits
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := map[string]struct {
+		in   int64
+		want string
+	}{
+		"59":          {59, "287082"},
+		"1111111109":  {1111111109, "081804"},
+		"1111111111":  {1111111111, "050471"},
+		"1234567890":  {1234567890, "005924"},
+		"2000000000":  {2000000000, "279037"},
+		"20000000000": {20000000000, "353130"},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := domain.TOTPCode(secret, test.in/30)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != test.want {
+				t.Errorf("TOTPCode(%d) = %q; want %q", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / 30
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	code := func(step int64) string {
+		code, err := domain.TOTPCode(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return code
+	}
+
+	tests := map[string]struct {
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOk   bool
+	}{
+		"current":       {code(step), 0, step, true},
+		"previous step": {code(step - 1), 0, step - 1, true},
+		"next step":     {code(step + 1), 0, step + 1, true},
+		"too old":       {code(step - 2), 0, 0, false},
+		"reused":        {code(step), step, 0, false},
+		"invalid":       {"000000", 0, 0, false},
+		"empty":         {"", 0, 0, false},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			totp := &domain.TOTP{Secret: secret, LastStep: test.lastStep} //nolint:exhaustruct
+
+			gotStep, gotOk := totp.Validate(test.code, now)
+			if gotStep != test.wantStep || gotOk != test.wantOk {
+				t.Errorf("Validate(%q) = %d, %t; want %d, %t", test.code, gotStep, gotOk, test.wantStep,
+					test.wantOk)
+			}
+		})
+	}
+}
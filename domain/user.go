@@ -1,23 +1,38 @@
 package domain
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// User contains user data.
+// User contains user data. Activation and recovery tokens are not stored, just their hashes, so only their issue
+// times are known.
 type User struct {
-	ID              string
-	Email           string
-	Password        []byte
-	ActivationToken string
-	RecoveryToken   string
-	Activated       *time.Time
-	Created         *time.Time
-	Updated         *time.Time
-	Active          *bool
+	ID                    string
+	Email                 string
+	Password              []byte
+	Role                  string
+	ActivationTokenIssued *time.Time
+	RecoveryTokenIssued   *time.Time
+	Activated             *time.Time
+	Created               *time.Time
+	Updated               *time.Time
+	Active                *bool
+	Status                UserStatus
+	DeletionScheduled     *time.Time
+	Profile
+}
+
+// Profile contains user data which may be updated by the user. Locale is BCP 47 language tag, timezone is IANA
+// time zone name and metadata is arbitrary JSON object.
+type Profile struct {
+	DisplayName string
+	Locale      string
+	Timezone    string
+	Metadata    json.RawMessage
 }
 
 // IsActive checks if user account is activated.
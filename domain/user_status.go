@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// User statuses set by administrators.
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusBanned    = "banned"
+)
+
+// UserStatus contains status of the user account together with its reason and subject of the administrator who
+// set it. It is independent of account activation. Suspension without end time lasts until lifted.
+type UserStatus struct {
+	Status  string
+	Until   *time.Time
+	Reason  string
+	Actor   string
+	Changed *time.Time
+}
+
+// IsBlocked checks if the status prevents user from logging in at provided time.
+func (s *UserStatus) IsBlocked(now time.Time) bool {
+	switch s.Status {
+	case StatusBanned:
+		return true
+	case StatusSuspended:
+		return s.Until == nil || now.Before(*s.Until)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,40 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+func TestUserStatusIsBlocked(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := map[string]struct {
+		status domain.UserStatus
+		want   bool
+	}{
+		"unset":              {domain.UserStatus{}, false},                                              //nolint:exhaustruct
+		"active":             {domain.UserStatus{Status: domain.StatusActive}, false},                   //nolint:exhaustruct
+		"banned":             {domain.UserStatus{Status: domain.StatusBanned}, true},                    //nolint:exhaustruct
+		"suspended":          {domain.UserStatus{Status: domain.StatusSuspended}, true},                 //nolint:exhaustruct
+		"suspended until":    {domain.UserStatus{Status: domain.StatusSuspended, Until: &future}, true}, //nolint:exhaustruct
+		"suspension expired": {domain.UserStatus{Status: domain.StatusSuspended, Until: &past}, false},  //nolint:exhaustruct
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			if got := test.status.IsBlocked(now); got != test.want {
+				t.Errorf("IsBlocked() = %t; want %t", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// WebAuthn ceremonies a challenge may be used for.
+const (
+	WebAuthnRegistration   = "registration"
+	WebAuthnAuthentication = "authentication"
+)
+
+// WebAuthnCredential contains public key credential (passkey) registered by the user's authenticator.
+type WebAuthnCredential struct {
+	ID        []byte
+	UserID    string
+	Name      string
+	PublicKey []byte
+	SignCount uint32
+	LastUsed  *time.Time
+	Created   *time.Time
+}
+
+// WebAuthnChallenge contains hash of the challenge issued for a single ceremony. Authentication challenge without
+// user is issued for passwordless login, while the one bound to the user is used as the second factor.
+type WebAuthnChallenge struct {
+	Hash     []byte
+	UserID   string
+	Ceremony string
+	Expires  time.Time
+	Created  *time.Time
+}
+
+// IsPasswordless checks if challenge has been issued for passwordless login.
+func (c *WebAuthnChallenge) IsPasswordless() bool {
+	return c.UserID == ""
+}
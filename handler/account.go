@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// DeleteAccount schedules deletion of the current user's account once the password is confirmed. Account and all
+// its data are deleted after configured grace period, unless deletion is canceled by /users/me/restore. All tokens
+// of the user are revoked and user is notified per email. Step-up token is required besides the current password.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me [delete]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Param password body request.Password true "The current password"
+// @Success 202 {object} response.User
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Delete the current user.
+func (h *UsersHandler) DeleteAccount(res http.ResponseWriter, req *http.Request) { //nolint:funlen
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	password, err := request.PasswordFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !domainUser.IsValidPassword(password.Password) {
+		response.RenderErrorStatus(res, http.StatusForbidden, "invalid password", h.log)
+
+		return
+	}
+
+	domainUser, err = h.usersRepo.ScheduleDeletion(req.Context(), claims.Subject,
+		time.Now().Add(h.accountDeletionGrace))
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("schedule deletion", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.revokeAll(req.Context(), claims.Subject); err != nil {
+		h.log.Warn("revoke all", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message := fmt.Sprintf("Your account is going to be deleted on %s. Log in and restore it before if you change "+
+		"your mind.", domainUser.DeletionScheduled.UTC().Format(time.RFC1123))
+
+	if err := h.sender.Send(domainUser.Email, "Account deletion", message); err != nil {
+		h.log.Warn("send account deletion notification", lax.Error(err))
+	}
+
+	if h.cookies != nil {
+		h.cookies.clear(res)
+	}
+
+	response.Render(res, http.StatusAccepted, response.FromDomainUser(domainUser), h.log)
+}
+
+// RestoreAccount cancels scheduled deletion of the current user's account.
+//
+// @Tags users
+// @Router /users/me/restore [post]
+// @Security BearerAuth
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Cancel deletion of the current user.
+func (h *UsersHandler) RestoreAccount(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	if err := h.usersRepo.CancelDeletion(req.Context(), claims.Subject); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, "deletion not scheduled", h.log)
+
+			return
+		}
+
+		h.log.Warn("cancel deletion", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// Export renders all data stored about the current user as JSON attachment, including status history, invitations
+// sent or accepted by the user and pending email changes.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/export [get]
+// @Security BearerAuth
+// @Success 200 {object} response.Export
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Export data of the current user.
+func (h *UsersHandler) Export(res http.ResponseWriter, req *http.Request) { //nolint:cyclop,funlen
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	methods, err := h.mfaMethods(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("mfa methods", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainSessions, err := h.sessionsRepo.FindAll(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find sessions", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainCredentials, err := h.webAuthnRepo.FindCredentials(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find webauthn credentials", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainConsents, err := h.consentsRepo.FindAll(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find consents", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainStatuses, err := h.usersRepo.FindStatusHistory(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find status history", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainInvitations, err := h.invitationsRepo.FindAllByUser(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find invitations", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainEmailChanges, err := h.emailChangesRepo.FindAll(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find email changes", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	export := &response.Export{
+		User:                response.FromDomainUser(domainUser),
+		MFAMethods:          methods,
+		Sessions:            make([]*response.Session, 0, len(domainSessions)),
+		WebAuthnCredentials: make([]*response.WebAuthnCredential, 0, len(domainCredentials)),
+		Consents:            make([]*response.Consent, 0, len(domainConsents)),
+		StatusHistory:       make([]*response.UserStatus, 0, len(domainStatuses)),
+		Invitations:         make([]*response.Invitation, 0, len(domainInvitations)),
+		EmailChanges:        make([]*response.EmailChange, 0, len(domainEmailChanges)),
+		Exported:            time.Now().UTC(),
+	}
+
+	for i := range domainSessions {
+		export.Sessions = append(export.Sessions, response.FromDomainSession(&domainSessions[i], claims.SessionID))
+	}
+
+	for i := range domainCredentials {
+		export.WebAuthnCredentials = append(export.WebAuthnCredentials,
+			response.FromDomainWebAuthnCredential(&domainCredentials[i]))
+	}
+
+	for i := range domainConsents {
+		export.Consents = append(export.Consents, response.FromDomainConsent(&domainConsents[i]))
+	}
+
+	for i := range domainStatuses {
+		export.StatusHistory = append(export.StatusHistory, response.FromDomainUserStatus(&domainStatuses[i]))
+	}
+
+	for i := range domainInvitations {
+		export.Invitations = append(export.Invitations, response.FromDomainInvitation(&domainInvitations[i]))
+	}
+
+	for i := range domainEmailChanges {
+		export.EmailChanges = append(export.EmailChanges, response.FromDomainEmailChange(&domainEmailChanges[i]))
+	}
+
+	res.Header().Set("Content-Disposition", `attachment; filename="arc-export.json"`)
+	response.Render(res, http.StatusOK, export, h.log)
+}
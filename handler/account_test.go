@@ -0,0 +1,179 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}                                                 //nolint:exhaustruct
+	server := newAccountServer(t, jwt, usersHandlerOptions{sender: sender}) //nolint:exhaustruct
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doJSON(t, http.MethodDelete, server.URL+"/users/me", authToken, `{"password":"wrong"}`, http.StatusForbidden,
+		nil)
+
+	var user response.User
+
+	doJSON(t, http.MethodDelete, server.URL+"/users/me", authToken, fmt.Sprintf(`{"password":%q}`, testPassword),
+		http.StatusAccepted, &user)
+
+	if user.DeletionScheduled == nil || time.Until(*user.DeletionScheduled) < 23*time.Hour {
+		t.Errorf("DeleteAccount() = %+v; want deletion scheduled after grace period", user)
+	}
+
+	if _, ok := sender.message("john.doe@sixpack.com"); !ok {
+		t.Error("no notification sent; want account deletion notification")
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me/export", authToken, "", http.StatusUnauthorized, nil)
+}
+
+func TestRestoreAccount(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	usersRepo := &usersRepositoryFake{ //nolint:exhaustruct
+		deletions: map[string]time.Time{"john": time.Now().Add(time.Hour)},
+	}
+	server := newAccountServer(t, jwt, usersHandlerOptions{usersRepo: usersRepo}) //nolint:exhaustruct
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var user response.User
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me", authToken, "", http.StatusOK, &user)
+
+	if user.DeletionScheduled == nil {
+		t.Fatalf("Profile() = %+v; want deletion scheduled", user)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/restore", authToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/restore", authToken, "", http.StatusNotFound, nil)
+
+	user = response.User{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me", authToken, "", http.StatusOK, &user)
+
+	if user.DeletionScheduled != nil {
+		t.Errorf("Profile() = %+v; want deletion canceled", user)
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sessionsRepo := &sessionsRepositoryFake{sessions: map[string]*domain.Session{ //nolint:exhaustruct
+		"current": {ID: "current", UserID: "john", UserAgent: "Firefox", IP: "192.0.2.1"}, //nolint:exhaustruct
+		"foreign": {ID: "foreign", UserID: "jane", UserAgent: "Edge", IP: "192.0.2.3"},    //nolint:exhaustruct
+	}}
+	usersRepo := &usersRepositoryFake{statuses: map[string][]domain.UserStatus{ //nolint:exhaustruct
+		"john": {
+			{Status: domain.StatusSuspended, Reason: "spam", Actor: "admin"}, //nolint:exhaustruct
+			{Status: domain.StatusActive, Actor: "admin"},                    //nolint:exhaustruct
+		},
+	}}
+	invitationsRepo := &invitationsRepositoryFake{ //nolint:exhaustruct
+		invitations: map[string]*domain.Invitation{
+			"sent":     {ID: "sent", Email: "joe.doe@sixpack.com", Inviter: "john"},      //nolint:exhaustruct
+			"accepted": {ID: "accepted", Email: "john.doe@sixpack.com", Inviter: "jane"}, //nolint:exhaustruct
+			"foreign":  {ID: "foreign", Email: "jim.doe@sixpack.com", Inviter: "jane"},   //nolint:exhaustruct
+		},
+		acceptedBy: map[string]string{"accepted": "john"},
+	}
+	emailChangesRepo := &emailChangesRepositoryFake{emailChanges: map[string]*domain.EmailChange{ //nolint:exhaustruct
+		"john": {UserID: "john", NewEmail: "john@sixpack.com", Expires: time.Now().Add(time.Hour)}, //nolint:exhaustruct
+	}}
+	server := newAccountServer(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo:        usersRepo,
+		sessionsRepo:     sessionsRepo,
+		invitationsRepo:  invitationsRepo,
+		emailChangesRepo: emailChangesRepo,
+	})
+
+	authToken, _, err := jwt.Tokens("john", "current", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var export response.Export
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me/export", authToken, "", http.StatusOK, &export)
+
+	if export.User == nil || export.User.ID != "john" || export.User.Email != "john.doe@sixpack.com" {
+		t.Errorf("Export() user = %+v; want john", export.User)
+	}
+
+	if len(export.Sessions) != 1 || !export.Sessions[0].Current {
+		t.Errorf("Export() sessions = %+v; want just the current session", export.Sessions)
+	}
+
+	if len(export.MFAMethods) != 0 || len(export.WebAuthnCredentials) != 0 || len(export.Consents) != 0 {
+		t.Errorf("Export() = %+v; want no second factors and consents", export)
+	}
+
+	if len(export.StatusHistory) != 2 || export.StatusHistory[0].Status != domain.StatusActive {
+		t.Errorf("Export() status history = %+v; want two changes, the newest first", export.StatusHistory)
+	}
+
+	if len(export.Invitations) != 2 {
+		t.Errorf("Export() invitations = %+v; want sent and accepted invitation", export.Invitations)
+	}
+
+	if len(export.EmailChanges) != 1 || export.EmailChanges[0].NewEmail != "john@sixpack.com" {
+		t.Errorf("Export() email changes = %+v; want pending change", export.EmailChanges)
+	}
+}
+
+func newAccountServer(t *testing.T, jwt *token.JWT, opts usersHandlerOptions) *httptest.Server {
+	t.Helper()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+
+	if opts.sessionsRepo == nil {
+		opts.sessionsRepo = &sessionsRepositoryFake{} //nolint:exhaustruct
+	}
+
+	opts.revocationsRepo = revocationsRepo
+	usersHandler := newUsersHandler(t, jwt, opts)
+
+	mux := chi.NewRouter()
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, opts.sessionsRepo, log))
+		r.Get("/users/me", usersHandler.Profile)
+		r.Delete("/users/me", usersHandler.DeleteAccount)
+		r.Post("/users/me/restore", usersHandler.RestoreAccount)
+		r.Get("/users/me/export", usersHandler.Export)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
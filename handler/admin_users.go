@@ -0,0 +1,542 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/bulk"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+const maxImportSize = 32 << 20
+
+var errUnsupportedMediaType = errors.New("unsupported media type, expected text/csv or application/x-ndjson")
+
+// AdminUsersHandler contains user management http handlers for administrators.
+type AdminUsersHandler struct {
+	usersRepo         repository.Users
+	refreshTokensRepo repository.RefreshTokens
+	revocationsRepo   repository.Revocations
+	bulk              *bulk.Users
+	log               lax.Logger
+}
+
+// NewAdminUsersHandler creates user management handler for administrators.
+func NewAdminUsersHandler(ur repository.Users, rtr repository.RefreshTokens, rr repository.Revocations,
+	bu *bulk.Users, log lax.Logger,
+) *AdminUsersHandler {
+	return &AdminUsersHandler{
+		usersRepo:         ur,
+		refreshTokensRepo: rtr,
+		revocationsRepo:   rr,
+		bulk:              bu,
+		log:               log,
+	}
+}
+
+// FindAll renders page of users matching filters, without their passwords. Link to the next page is set in Link
+// header as well.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/users [get]
+// @Security BearerAuth
+// @Param email query string false "Email address prefix"
+// @Param active query bool false "Active users only or inactive only"
+// @Param createdFrom query string false "Created at or after (RFC 3339)"
+// @Param createdTo query string false "Created before (RFC 3339)"
+// @Param sort query string false "Sort order" Enums(created, -created, email, -email)
+// @Param limit query int false "Page size" minimum(1) maximum(100) default(50)
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} response.UsersPage
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Summary List users.
+func (h *AdminUsersHandler) FindAll(res http.ResponseWriter, req *http.Request) {
+	query, err := request.UsersQueryFromURL(req.URL.Query())
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	page, err := h.usersRepo.FindAll(req.Context(), query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			response.RenderErrorStatus(res, http.StatusBadRequest, "invalid cursor", h.log)
+
+			return
+		}
+
+		h.log.Warn("find users", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	users := &response.UsersPage{ //nolint:exhaustruct
+		Users: make([]*response.AdminUser, 0, len(page.Users)),
+		Total: page.Total,
+	}
+
+	for i := range page.Users {
+		users.Users = append(users.Users, response.FromDomainUserAdmin(&page.Users[i]))
+	}
+
+	if page.Next != nil {
+		users.Next = page.Next.String()
+
+		values := req.URL.Query()
+		values.Set("cursor", users.Next)
+		next := url.URL{Path: req.URL.Path, RawQuery: values.Encode()} //nolint:exhaustruct
+
+		res.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+
+	response.Render(res, http.StatusOK, users, h.log)
+}
+
+// FindOne renders the user.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/users/{id} [get]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.AdminUser
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Get user.
+func (h *AdminUsersHandler) FindOne(res http.ResponseWriter, req *http.Request) {
+	domainUser, err := h.usersRepo.FindOne(req.Context(), chi.URLParam(req, "id"))
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.FromDomainUserAdmin(domainUser), h.log)
+}
+
+// Create creates already activated user, so no activation link is sent.
+//
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Router /admin/users [post]
+// @Security BearerAuth
+// @Param user body request.UserRegistration true "User"
+// @Success 201 {object} response.AdminUser
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Create user.
+func (h *AdminUsersHandler) Create(res http.ResponseWriter, req *http.Request) {
+	userRegistration, err := request.UserRegistrationFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.CreateActivated(req.Context(), userRegistration.Email,
+		userRegistration.HashedPassword)
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueViolation) {
+			response.RenderErrorStatus(res, http.StatusConflict, "already registered", h.log)
+
+			return
+		}
+
+		h.log.Warn("create activated user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusCreated, response.FromDomainUserAdmin(domainUser), h.log)
+}
+
+// SetStatus sets status of the user and records it in the status history together with the reason and subject of
+// the administrator. Tokens of suspended and banned users are revoked, so they are rejected immediately.
+//
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Router /admin/users/{id}/status [put]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param status body request.UserStatus true "Status"
+// @Success 200 {object} response.AdminUser
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Set user status.
+func (h *AdminUsersHandler) SetStatus(res http.ResponseWriter, req *http.Request) {
+	userStatus, err := request.UserStatusFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	h.setStatus(res, req, &domain.UserStatus{ //nolint:exhaustruct
+		Status: userStatus.Status,
+		Until:  userStatus.Until,
+		Reason: userStatus.Reason,
+	})
+}
+
+// Disable suspends the user until enabled again. It is kept for compatibility and is equal to setting status
+// suspended without end time and with reason disabled.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/users/{id}/disable [post]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.AdminUser
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Disable user.
+func (h *AdminUsersHandler) Disable(res http.ResponseWriter, req *http.Request) {
+	h.setStatus(res, req, &domain.UserStatus{Status: domain.StatusSuspended, Reason: "disabled"}) //nolint:exhaustruct
+}
+
+// Enable lifts suspension or ban of the user. It is kept for compatibility and is equal to setting status active.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/users/{id}/enable [post]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} response.AdminUser
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Enable user.
+func (h *AdminUsersHandler) Enable(res http.ResponseWriter, req *http.Request) {
+	h.setStatus(res, req, &domain.UserStatus{Status: domain.StatusActive}) //nolint:exhaustruct
+}
+
+// setStatus sets status of the user on behalf of the current administrator. Tokens of suspended and banned users
+// are revoked.
+func (h *AdminUsersHandler) setStatus(res http.ResponseWriter, req *http.Request, status *domain.UserStatus) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	id := chi.URLParam(req, "id")
+	status.Actor = claims.Subject
+
+	domainUser, err := h.usersRepo.SetStatus(req.Context(), id, status)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("set status", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if domainUser.Status.IsBlocked(time.Now()) {
+		if err := revokeAll(req.Context(), h.revocationsRepo, h.refreshTokensRepo, id); err != nil {
+			h.log.Warn("revoke all", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+	}
+
+	response.Render(res, http.StatusOK, response.FromDomainUserAdmin(domainUser), h.log)
+}
+
+// StatusHistory renders status changes of the user, the newest first.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/users/{id}/status/history [get]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {array} response.UserStatus
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary List user status changes.
+func (h *AdminUsersHandler) StatusHistory(res http.ResponseWriter, req *http.Request) {
+	id := chi.URLParam(req, "id")
+
+	if _, err := h.usersRepo.FindOne(req.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainStatuses, err := h.usersRepo.FindStatusHistory(req.Context(), id)
+	if err != nil {
+		h.log.Warn("find status history", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	statuses := make([]*response.UserStatus, 0, len(domainStatuses))
+
+	for i := range domainStatuses {
+		statuses = append(statuses, response.FromDomainUserStatus(&domainStatuses[i]))
+	}
+
+	response.Render(res, http.StatusOK, statuses, h.log)
+}
+
+// Logout revokes all tokens and sessions of the user.
+//
+// @Tags admin
+// @Router /admin/users/{id}/logout [post]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Log user out everywhere.
+func (h *AdminUsersHandler) Logout(res http.ResponseWriter, req *http.Request) {
+	id := chi.URLParam(req, "id")
+
+	if _, err := h.usersRepo.FindOne(req.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := revokeAll(req.Context(), h.revocationsRepo, h.refreshTokensRepo, id); err != nil {
+		h.log.Warn("revoke all", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// Delete deletes the user together with all user's data immediately. Tokens already issued to the user are
+// revoked.
+//
+// @Tags admin
+// @Router /admin/users/{id} [delete]
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Delete user.
+func (h *AdminUsersHandler) Delete(res http.ResponseWriter, req *http.Request) {
+	id := chi.URLParam(req, "id")
+
+	if err := h.usersRepo.Delete(req.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("delete user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.revocationsRepo.RevokeAll(req.Context(), id); err != nil {
+		h.log.Warn("revoke tokens", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// Import imports users from CSV or JSON Lines body, chosen by content type, and renders result of each row. CSV has
+// to contain header row with email column and optional password, passwordHash and role columns. Users are created
+// already activated, either with plain password checked like at registration or with bcrypt hash of the password.
+// Invited users receive invitation to create their accounts instead, with intended role if set. Rows are just
+// validated in dry-run mode.
+//
+// @Tags admin
+// @Accept text/csv
+// @Accept application/x-ndjson
+// @Produce json
+// @Router /admin/users/import [post]
+// @Security BearerAuth
+// @Param users body string true "Users as CSV or JSON Lines"
+// @Param invite query bool false "Invite users to create their accounts"
+// @Param dryRun query bool false "Just validate users"
+// @Success 200 {object} bulk.Report
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Failure 415 {object} response.Error
+// @Failure 500
+// @Summary Import users.
+func (h *AdminUsersHandler) Import(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	format, err := importFormat(req.Header.Get("Content-Type"))
+	if err != nil {
+		response.RenderErrorStatus(res, http.StatusUnsupportedMediaType, err.Error(), h.log)
+
+		return
+	}
+
+	opts := &bulk.Options{Format: format, Inviter: claims.Subject} //nolint:exhaustruct
+
+	if opts.Invite, err = queryBool(req, "invite"); err != nil {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid invite", h.log)
+
+		return
+	}
+
+	if opts.DryRun, err = queryBool(req, "dryRun"); err != nil {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid dryRun", h.log)
+
+		return
+	}
+
+	report, err := h.bulk.Import(req.Context(), http.MaxBytesReader(res, req.Body, maxImportSize), opts)
+	if err != nil {
+		if errors.Is(err, bulk.ErrMissingEmail) {
+			response.RenderErrorStatus(res, http.StatusBadRequest, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("import users", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusBadRequest, "unreadable body", h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, report, h.log)
+}
+
+// Export streams all users as CSV or JSON Lines, without their passwords.
+//
+// @Tags admin
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Router /admin/users/export [get]
+// @Security BearerAuth
+// @Param format query string false "Format" Enums(csv, jsonl) default(csv)
+// @Success 200 {array} bulk.ExportedUser
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Summary Export users.
+func (h *AdminUsersHandler) Export(res http.ResponseWriter, req *http.Request) {
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = bulk.FormatCSV
+	}
+
+	contentType, ok := map[string]string{bulk.FormatCSV: "text/csv", bulk.FormatJSONL: "application/x-ndjson"}[format]
+	if !ok {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid format", h.log)
+
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="arc-users.%s"`, format))
+
+	if err := h.bulk.Export(req.Context(), res, format); err != nil {
+		// status has already been sent together with the first users
+		h.log.Warn("export users", lax.Error(err))
+	}
+}
+
+// importFormat returns import format matching the content type.
+func importFormat(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", errUnsupportedMediaType
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return bulk.FormatCSV, nil
+	case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+		return bulk.FormatJSONL, nil
+	default:
+		return "", errUnsupportedMediaType
+	}
+}
+
+// queryBool parses optional boolean query parameter.
+func queryBool(req *http.Request, name string) (bool, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(value) //nolint:wrapcheck
+}
@@ -0,0 +1,536 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/bulk"
+	"go.ectobit.com/arc/handler/invite"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/arc/send"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdminUsers(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	usersRepo := &usersRepositoryFake{}                                          //nolint:exhaustruct
+	refreshTokensRepo := &refreshTokensRepositoryFake{tokens: map[string]bool{}} //nolint:exhaustruct
+	revocationsRepo := &revocationsRepositoryFake{}                              //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}                                    //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo:         usersRepo,
+		refreshTokensRepo: refreshTokensRepo,
+		revocationsRepo:   revocationsRepo,
+		sessionsRepo:      sessionsRepo,
+	})
+	bulkUsers := bulk.NewUsers(usersRepo, newInviter(usersRepo, &send.Fake{}), log)
+	adminUsersHandler := handler.NewAdminUsersHandler(usersRepo, refreshTokensRepo, revocationsRepo, bulkUsers, log)
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login", usersHandler.Login)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Get("/users/me", usersHandler.Profile)
+		r.Group(func(r chi.Router) {
+			r.Use(newAuthorizer(usersRepo, log))
+			r.Get("/admin/users", adminUsersHandler.FindAll)
+			r.Post("/admin/users", adminUsersHandler.Create)
+			r.Get("/admin/users/{id}", adminUsersHandler.FindOne)
+			r.Delete("/admin/users/{id}", adminUsersHandler.Delete)
+			r.Put("/admin/users/{id}/status", adminUsersHandler.SetStatus)
+			r.Post("/admin/users/{id}/disable", adminUsersHandler.Disable)
+			r.Post("/admin/users/{id}/enable", adminUsersHandler.Enable)
+			r.Get("/admin/users/{id}/status/history", adminUsersHandler.StatusHistory)
+			r.Post("/admin/users/{id}/logout", adminUsersHandler.Logout)
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	adminToken, _, err := jwt.Tokens("admin", "admin-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	johnToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users response.UsersPage
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users", johnToken, "", http.StatusForbidden, nil)
+	doJSON(t, http.MethodGet, server.URL+"/admin/users", adminToken, "", http.StatusOK, &users)
+
+	if len(users.Users) != 2 || users.Users[0].ID != "john" || !users.Users[0].Active || users.Next != "" {
+		t.Errorf("FindAll() = %+v; want john and jane", users)
+	}
+
+	var user response.AdminUser
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/users", adminToken, loginBody("jane.doe@sixpack.com"),
+		http.StatusConflict, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/users", adminToken, loginBody("joe.doe@sixpack.com"),
+		http.StatusCreated, &user)
+
+	if user.Email != "joe.doe@sixpack.com" || !user.Active {
+		t.Errorf("Create() = %+v; want active user", user)
+	}
+
+	user = response.AdminUser{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPut, server.URL+"/admin/users/john/status", adminToken,
+		`{"status":"suspended","reason":"spam","until":"2999-01-01T00:00:00Z"}`, http.StatusOK, &user)
+
+	if user.Status.Status != "suspended" || user.Status.Actor != "admin" || user.Status.Until == nil {
+		t.Errorf("SetStatus() = %+v; want suspended user", user.Status)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me", johnToken, "", http.StatusUnauthorized, nil)
+
+	var loginErr response.Error
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"),
+		http.StatusForbidden, &loginErr)
+
+	if loginErr.Error != "account suspended until 2999-01-01T00:00:00Z" {
+		t.Errorf("Login() = error %q; want suspension", loginErr.Error)
+	}
+
+	doJSON(t, http.MethodPut, server.URL+"/admin/users/john/status", adminToken,
+		`{"status":"banned","reason":"fraud"}`, http.StatusOK, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"),
+		http.StatusForbidden, nil)
+	doJSON(t, http.MethodPut, server.URL+"/admin/users/john/status", adminToken, `{"status":"disabled"}`,
+		http.StatusBadRequest, nil)
+
+	user = response.AdminUser{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPut, server.URL+"/admin/users/john/status", adminToken, `{"status":"active"}`,
+		http.StatusOK, &user)
+
+	if user.Status.Status != "active" {
+		t.Errorf("SetStatus() = %+v; want active user", user.Status)
+	}
+
+	var history []response.UserStatus
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users/john/status/history", adminToken, "", http.StatusOK,
+		&history)
+
+	if len(history) != 3 || history[0].Status != "active" || history[2].Reason != "spam" {
+		t.Errorf("StatusHistory() = %+v; want active, banned and suspended", history)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusOK, nil)
+
+	user = response.AdminUser{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/users/john/disable", adminToken, "", http.StatusOK, &user)
+
+	if user.Status.Status != "suspended" || user.Status.Reason != "disabled" || user.Status.Until != nil {
+		t.Errorf("Disable() = %+v; want suspension without end", user.Status)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"),
+		http.StatusForbidden, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/users/john/enable", adminToken, "", http.StatusOK, &user)
+
+	if user.Status.Status != "active" || user.Status.Actor != "admin" {
+		t.Errorf("Enable() = %+v; want active user", user.Status)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusOK, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/users/jane/logout", adminToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/admin/users/jane", adminToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/admin/users/jane", adminToken, "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodGet, server.URL+"/admin/users/jane", adminToken, "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/users/jane/logout", adminToken, "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodPut, server.URL+"/admin/users/jane/status", adminToken,
+		`{"status":"banned","reason":"fraud"}`, http.StatusNotFound, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/users/jane/disable", adminToken, "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodGet, server.URL+"/admin/users/jane/status/history", adminToken, "", http.StatusNotFound,
+		nil)
+
+	users = response.UsersPage{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users", adminToken, "", http.StatusOK, &users)
+
+	if len(users.Users) != 1 || users.Total != 1 {
+		t.Errorf("FindAll() = %+v; want just john", users)
+	}
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	usersRepo := &usersRepositoryFake{roles: map[string]string{"jane": "admin", "joe": "owner"}} //nolint:exhaustruct
+	adminUsersHandler := handler.NewAdminUsersHandler(usersRepo,
+		&refreshTokensRepositoryFake{tokens: map[string]bool{}}, &revocationsRepositoryFake{}, //nolint:exhaustruct
+		bulk.NewUsers(usersRepo, newInviter(usersRepo, &send.Fake{}), log), log)
+
+	mux := chi.NewRouter()
+	mux.Use(mw.Verifier(jwt))
+	mux.Use(jwtauth.Authenticator)
+	mux.Use(mw.Validator(&revocationsRepositoryFake{}, &sessionsRepositoryFake{}, log)) //nolint:exhaustruct
+	mux.Use(newAuthorizer(usersRepo, log))
+	mux.Get("/admin/users", adminUsersHandler.FindAll)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := map[string]struct {
+		subject    string
+		grant      *token.Grant
+		wantStatus int
+	}{
+		"admin role":                {"jane", nil, http.StatusOK},
+		"other role":                {"joe", nil, http.StatusForbidden},
+		"no role":                   {"john", nil, http.StatusForbidden},
+		"client on behalf of admin": {"jane", &token.Grant{ClientID: testClientID, Scope: "openid"}, http.StatusForbidden},
+		"client on its own behalf":  {testClientID, &token.Grant{ClientID: testClientID, Scope: ""}, http.StatusForbidden},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		authToken, _, err := jwt.Tokens(test.subject, "session", test.grant)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			doJSON(t, http.MethodGet, server.URL+"/admin/users", authToken, "", test.wantStatus, nil)
+		})
+	}
+}
+
+// newAuthorizer creates authorizer enforcing the shipped Casbin model and policy.
+func newAuthorizer(usersRepo repository.Users, log lax.Logger) func(next http.Handler) http.Handler {
+	return mw.Authorizer(casbin.NewEnforcer("../authz_model.conf", "../authz_policy.csv"), usersRepo, log)
+}
+
+func TestAdminUsersPagination(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+	adminUsersHandler := handler.NewAdminUsersHandler(usersRepo,
+		&refreshTokensRepositoryFake{tokens: map[string]bool{}}, &revocationsRepositoryFake{}, //nolint:exhaustruct
+		bulk.NewUsers(usersRepo, newInviter(usersRepo, &send.Fake{}), log), log)
+
+	mux := chi.NewRouter()
+	mux.Use(mw.Verifier(jwt))
+	mux.Use(jwtauth.Authenticator)
+	mux.Get("/admin/users", adminUsersHandler.FindAll)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	adminToken, _, err := jwt.Tokens("admin", "admin-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first response.UsersPage
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users?sort=email&limit=1", adminToken, "", http.StatusOK, &first)
+
+	if len(first.Users) != 1 || first.Users[0].Email != "jane.doe@sixpack.com" || first.Total != 2 ||
+		first.Next == "" {
+		t.Fatalf("first page = %+v; want jane and next cursor", first)
+	}
+
+	var second response.UsersPage
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users?limit=1&cursor="+first.Next, adminToken, "", http.StatusOK,
+		&second)
+
+	if len(second.Users) != 1 || second.Users[0].Email != "john.doe@sixpack.com" || second.Next != "" {
+		t.Errorf("second page = %+v; want john on the last page", second)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users?sort=-email&cursor="+first.Next, adminToken, "",
+		http.StatusBadRequest, nil)
+
+	unknown := &repository.Cursor{Sort: "email", Value: "nobody@sixpack.com", ID: "nobody"}
+
+	for _, query := range []string{"limit=0", "limit=101", "active=maybe", "createdFrom=yesterday", "sort=password",
+		"cursor=invalid", "cursor=" + unknown.String()} {
+		doJSON(t, http.MethodGet, server.URL+"/admin/users?"+query, adminToken, "", http.StatusBadRequest, nil)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		server.URL+"/admin/users?sort=email&limit=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	want := `</admin/users?cursor=` + first.Next + `&limit=1&sort=email>; rel="next"`
+	if link := res.Header.Get("Link"); link != want {
+		t.Errorf("Link = %q; want %q", link, want)
+	}
+}
+
+func TestAdminUsersImport(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	usersRepo := &usersRepositoryFake{roles: map[string]string{"admin": "admin"}} //nolint:exhaustruct
+	sender := &senderFake{}                                                       //nolint:exhaustruct
+	adminUsersHandler := handler.NewAdminUsersHandler(usersRepo,
+		&refreshTokensRepositoryFake{tokens: map[string]bool{}}, &revocationsRepositoryFake{}, //nolint:exhaustruct
+		bulk.NewUsers(usersRepo, newInviter(usersRepo, sender), log), log)
+
+	mux := chi.NewRouter()
+	mux.Use(mw.Verifier(jwt))
+	mux.Use(jwtauth.Authenticator)
+	mux.Post("/admin/users/import", adminUsersHandler.Import)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	adminToken, _, err := jwt.Tokens("admin", "admin-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := fmt.Sprintf(`email, password, passwordHash
+joe.doe@sixpack.com,%[1]s,
+john.doe@sixpack.com,%[1]s,
+invalid,%[1]s,
+weak.doe@sixpack.com,123456,
+hashed.doe@sixpack.com,,%[2]s
+joe.doe@sixpack.com,%[1]s,
+both.doe@sixpack.com,%[1]s,%[2]s
+bad.hash@sixpack.com,,secret
+short.row@sixpack.com
+`, testPassword, passwordHash)
+
+	wantErrors := []string{
+		"", "already registered", "invalid email", "weak password", "", "duplicate email",
+		"either password or password hash allowed", "invalid password hash", "wrong number of fields",
+	}
+
+	for _, dryRun := range []bool{true, false} {
+		report := doImport(t, fmt.Sprintf("%s/admin/users/import?dryRun=%t", server.URL, dryRun), adminToken,
+			"text/csv; charset=utf-8", users, http.StatusOK)
+
+		if report.DryRun != dryRun || report.Total != len(wantErrors) || report.Succeeded != 2 {
+			t.Fatalf("Import() = %+v; want two of %d rows succeeded", report, len(wantErrors))
+		}
+
+		wantStatus := bulk.StatusCreated
+		if dryRun {
+			wantStatus = bulk.StatusValid
+		}
+
+		for i, result := range report.Results {
+			if result.Row != i+1 || result.Error != wantErrors[i] {
+				t.Errorf("row %d = %+v; want error %q", i+1, result, wantErrors[i])
+			}
+
+			if result.Error == "" && result.Status != wantStatus {
+				t.Errorf("row %d = status %s; want status %s", i+1, result.Status, wantStatus)
+			}
+		}
+
+		if _, err := usersRepo.FindOneByEmail(context.Background(), "joe.doe@sixpack.com"); (err == nil) == dryRun {
+			t.Errorf("FindOneByEmail() error = %v after import with dry run %t", err, dryRun)
+		}
+	}
+
+	domainUser, err := usersRepo.FindOneByEmail(context.Background(), "hashed.doe@sixpack.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !domainUser.IsValidPassword(testPassword) {
+		t.Error("imported password hash doesn't match password")
+	}
+
+	report := doImport(t, server.URL+"/admin/users/import?invite=true", adminToken, "application/x-ndjson",
+		`{"email":"invited.doe@sixpack.com","role":"admin"}
+
+{"email":"not.invited@sixpack.com","password":"secret"}
+not json
+{"email":"john.doe@sixpack.com"}
+{"email":"owner.doe@sixpack.com","role":"owner"}
+`, http.StatusOK)
+
+	if report.Total != 5 || report.Results[0].Status != bulk.StatusInvited || report.Results[0].ID == "" ||
+		report.Results[1].Error != "password not allowed when inviting" || report.Results[2].Error != "invalid json" ||
+		report.Results[3].Error != "already registered" || report.Results[4].Error != "role not allowed" {
+		t.Errorf("Import() = %+v; want invited, refused password, invalid json, already registered and refused role",
+			report.Results)
+	}
+
+	if message, ok := sender.message("invited.doe@sixpack.com"); !ok || !strings.HasPrefix(message, invitationLinkPrefix) {
+		t.Errorf("invitation = %q; want invitation link", message)
+	}
+
+	if _, err := usersRepo.FindOneByEmail(context.Background(), "invited.doe@sixpack.com"); err == nil {
+		t.Error("invited user created before accepting invitation")
+	}
+
+	report = doImport(t, server.URL+"/admin/users/import", adminToken, "text/csv", "email,password,role\n"+
+		"role.doe@sixpack.com,"+testPassword+",owner\n", http.StatusOK)
+
+	if report.Failed != 1 || report.Results[0].Error != "role allowed just when inviting" {
+		t.Errorf("Import() = %+v; want role refused without invitation", report.Results)
+	}
+
+	doImport(t, server.URL+"/admin/users/import", adminToken, "application/json", "[]",
+		http.StatusUnsupportedMediaType)
+	doImport(t, server.URL+"/admin/users/import", adminToken, "text/csv", "name\njoe", http.StatusBadRequest)
+	doImport(t, server.URL+"/admin/users/import?dryRun=maybe", adminToken, "text/csv", "email\n",
+		http.StatusBadRequest)
+}
+
+func TestAdminUsersExport(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+	adminUsersHandler := handler.NewAdminUsersHandler(usersRepo,
+		&refreshTokensRepositoryFake{tokens: map[string]bool{}}, &revocationsRepositoryFake{}, //nolint:exhaustruct
+		bulk.NewUsers(usersRepo, newInviter(usersRepo, &send.Fake{}), log), log)
+
+	mux := chi.NewRouter()
+	mux.Use(mw.Verifier(jwt))
+	mux.Use(jwtauth.Authenticator)
+	mux.Get("/admin/users/export", adminUsersHandler.Export)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	adminToken, _, err := jwt.Tokens("admin", "admin-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		query           string
+		wantContentType string
+		wantBody        string
+	}{
+		"csv": {
+			"", "text/csv", `id,email,active,status,displayName,locale,timezone,created,activated
+john,john.doe@sixpack.com,true,active,,,,2021-10-01T12:00:00Z,
+jane,jane.doe@sixpack.com,true,active,,,,2021-10-01T13:00:00Z,
+`,
+		},
+		"jsonl": {
+			"?format=jsonl", "application/x-ndjson",
+			`{"id":"john","email":"john.doe@sixpack.com","active":true,"status":"active","created":"2021-10-01T12:00:00Z"}
+{"id":"jane","email":"jane.doe@sixpack.com","active":true,"status":"active","created":"2021-10-01T13:00:00Z"}
+`,
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+				server.URL+"/admin/users/export"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+adminToken)
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != test.wantContentType {
+				t.Errorf("Export() = status %d, content type %q; want status 200, content type %q", res.StatusCode,
+					res.Header.Get("Content-Type"), test.wantContentType)
+			}
+
+			if string(body) != test.wantBody {
+				t.Errorf("Export() = %s; want %s", body, test.wantBody)
+			}
+		})
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/users/export?format=xml", adminToken, "", http.StatusBadRequest, nil)
+}
+
+func doImport(t *testing.T, url, authToken, contentType, body string, wantStatus int) *bulk.Report {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+	req.Header.Set("Content-Type", contentType)
+
+	var report bulk.Report
+
+	do(t, req, wantStatus, &report)
+
+	return &report
+}
+
+func newInviter(usersRepo *usersRepositoryFake, sender send.Sender) *invite.Inviter {
+	return invite.NewInviter(&invitationsRepositoryFake{usersRepo: usersRepo}, usersRepo, sender, //nolint:exhaustruct
+		"http://localhost:3000", "frontend-invitation-path", time.Hour)
+}
@@ -0,0 +1,491 @@
+// Package bulk implements import and export of users in bulk, either as CSV with header row or as JSON Lines.
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/invite"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported formats.
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// Statuses of imported rows.
+const (
+	StatusCreated = "created"
+	StatusInvited = "invited"
+	StatusValid   = "valid"
+	StatusFailed  = "failed"
+)
+
+const (
+	exportPageSize = 100
+	maxLineSize    = 64 << 10
+)
+
+// Errors.
+var (
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	ErrMissingEmail      = errors.New("missing email column")
+)
+
+// Options contains import options. Users are invited to create their accounts instead of importing passwords if
+// Invite is set, Inviter being the subject sending invitations. Rows are just validated, without storing users, if
+// DryRun is set.
+type Options struct {
+	Format  string
+	Invite  bool
+	Inviter string
+	DryRun  bool
+}
+
+// Row contains imported user. Either plain password, checked by the same rules as at registration, or bcrypt hash
+// of the password is required, unless users are invited. Role may be granted just to invited users.
+type Row struct {
+	Email        string `json:"email"`
+	Password     string `json:"password,omitempty"`
+	PasswordHash string `json:"passwordHash,omitempty"`
+	Role         string `json:"role,omitempty"`
+}
+
+// Result contains outcome of importing one row. Rows are numbered from one, not counting CSV header row. ID is ID of
+// the invitation for invited users.
+type Result struct {
+	Row    int    `json:"row"`
+	Email  string `json:"email,omitempty"`
+	Status string `json:"status" enums:"created,invited,valid,failed"`
+	ID     string `json:"id,omitempty" format:"uuid"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report contains results of all imported rows and their counts.
+type Report struct {
+	DryRun    bool      `json:"dryRun"`
+	Total     int       `json:"total"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Results   []*Result `json:"results"`
+}
+
+// ExportedUser contains user data written by export. Passwords are never exported.
+type ExportedUser struct {
+	ID          string     `json:"id"`
+	Email       string     `json:"email"`
+	Active      bool       `json:"active"`
+	Status      string     `json:"status"`
+	DisplayName string     `json:"displayName,omitempty"`
+	Locale      string     `json:"locale,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	Created     *time.Time `json:"created"`
+	Activated   *time.Time `json:"activated,omitempty"`
+}
+
+var exportColumns = []string{ //nolint:gochecknoglobals
+	"id", "email", "active", "status", "displayName", "locale", "timezone", "created", "activated",
+}
+
+// Users imports and exports users.
+type Users struct {
+	usersRepo repository.Users
+	inviter   *invite.Inviter
+	log       lax.Logger
+}
+
+// NewUsers creates users importer and exporter.
+func NewUsers(ur repository.Users, inviter *invite.Inviter, log lax.Logger) *Users {
+	return &Users{
+		usersRepo: ur,
+		inviter:   inviter,
+		log:       log,
+	}
+}
+
+// Import reads rows one by one and creates already activated users or invitations, reporting result of each row.
+// Invalid rows don't stop the import. Error is returned just if the input can't be read at all.
+func (u *Users) Import(ctx context.Context, input io.Reader, opts *Options) (*Report, error) {
+	report := &Report{DryRun: opts.DryRun, Results: []*Result{}} //nolint:exhaustruct
+	seen := map[string]bool{}
+
+	err := readRows(input, opts.Format, func(number int, row *Row, rowErr error) error {
+		result := &Result{Row: number, Status: StatusFailed} //nolint:exhaustruct
+
+		if row != nil {
+			result.Email = row.Email
+		}
+
+		if rowErr == nil {
+			rowErr = u.importRow(ctx, row, opts, seen, result)
+		}
+
+		if rowErr != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fmt.Errorf("import: %w", ctxErr)
+			}
+
+			result.Status = StatusFailed
+			result.Error = rowErr.Error()
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+
+		report.Total++
+		report.Results = append(report.Results, result)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Export writes all users ordered by creation time.
+func (u *Users) Export(ctx context.Context, output io.Writer, format string) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(output)
+
+		if err := writer.Write(exportColumns); err != nil {
+			return fmt.Errorf("write csv header: %w", err)
+		}
+
+		if err := u.export(ctx, func(user *ExportedUser) error {
+			return writer.Write(user.csvRecord()) //nolint:wrapcheck
+		}); err != nil {
+			return err
+		}
+
+		writer.Flush()
+
+		if err := writer.Error(); err != nil {
+			return fmt.Errorf("flush csv: %w", err)
+		}
+
+		return nil
+	case FormatJSONL:
+		encoder := json.NewEncoder(output)
+
+		return u.export(ctx, func(user *ExportedUser) error {
+			return encoder.Encode(user) //nolint:wrapcheck
+		})
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// export fetches users page by page and writes each of them.
+func (u *Users) export(ctx context.Context, write func(*ExportedUser) error) error {
+	query := &repository.UsersQuery{Sort: repository.UsersByCreated, Limit: exportPageSize} //nolint:exhaustruct
+
+	for {
+		page, err := u.usersRepo.FindAll(ctx, query)
+		if err != nil {
+			return fmt.Errorf("find users: %w", err)
+		}
+
+		for i := range page.Users {
+			if err := write(exportedUser(&page.Users[i])); err != nil {
+				return fmt.Errorf("write user: %w", err)
+			}
+		}
+
+		if page.Next == nil {
+			return nil
+		}
+
+		query.After = page.Next
+	}
+}
+
+// importRow validates the row and, unless in dry-run mode, creates the user or sends invitation if requested.
+// Emails already seen in the same import are refused.
+func (u *Users) importRow(ctx context.Context, row *Row, opts *Options, seen map[string]bool,
+	result *Result,
+) error {
+	if err := validateRow(row, opts.Invite); err != nil {
+		return err
+	}
+
+	if seen[row.Email] {
+		return errDuplicate
+	}
+
+	seen[row.Email] = true
+
+	if opts.DryRun {
+		if _, err := u.usersRepo.FindOneByEmail(ctx, row.Email); err == nil {
+			return errAlreadyRegistered
+		} else if !errors.Is(err, repository.ErrResourceNotFound) {
+			u.log.Warn("find user by email", lax.Error(err))
+
+			return errInternal
+		}
+
+		result.Status = StatusValid
+
+		return nil
+	}
+
+	if opts.Invite {
+		invitation, err := u.inviter.Invite(ctx, row.Email, row.Role, opts.Inviter)
+		if err != nil {
+			switch {
+			case errors.Is(err, invite.ErrAlreadyRegistered):
+				return errAlreadyRegistered
+			case errors.Is(err, invite.ErrRoleNotAllowed):
+				return errRoleNotAllowed
+			}
+
+			u.log.Warn("invite", lax.Error(err))
+
+			return errInternal
+		}
+
+		result.ID = invitation.ID
+		result.Status = StatusInvited
+
+		return nil
+	}
+
+	password, err := passwordHash(row)
+	if err != nil {
+		u.log.Warn("password hash", lax.Error(err))
+
+		return errInternal
+	}
+
+	domainUser, err := u.usersRepo.CreateActivated(ctx, row.Email, password)
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueViolation) {
+			return errAlreadyRegistered
+		}
+
+		u.log.Warn("create activated user", lax.Error(err))
+
+		return errInternal
+	}
+
+	result.ID = domainUser.ID
+	result.Status = StatusCreated
+
+	return nil
+}
+
+// Row errors reported to the client.
+var (
+	errDuplicate          = request.NewBadRequestError("duplicate email")
+	errAlreadyRegistered  = request.NewBadRequestError("already registered")
+	errPasswordAndHash    = request.NewBadRequestError("either password or password hash allowed")
+	errPasswordNotAllowed = request.NewBadRequestError("password not allowed when inviting")
+	errInvalidHash        = request.NewBadRequestError("invalid password hash")
+	errRoleWithoutInvite  = request.NewBadRequestError("role allowed just when inviting")
+	errRoleNotAllowed     = request.NewBadRequestError("role not allowed")
+	errInternal           = request.NewInternalServerError()
+)
+
+// validateRow validates the row by the same rules as at registration. Passwords are not imported for invited users.
+func validateRow(row *Row, invite bool) error {
+	row.Email = strings.TrimSpace(row.Email)
+	row.Role = strings.TrimSpace(row.Role)
+
+	if err := request.ValidateEmail(row.Email); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	if err := request.ValidateRole(row.Role); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	switch {
+	case invite:
+		if row.Password != "" || row.PasswordHash != "" {
+			return errPasswordNotAllowed
+		}
+	case row.Role != "":
+		return errRoleWithoutInvite
+	case row.PasswordHash != "":
+		if row.Password != "" {
+			return errPasswordAndHash
+		}
+
+		if _, err := bcrypt.Cost([]byte(row.PasswordHash)); err != nil {
+			return errInvalidHash
+		}
+	default:
+		if err := request.ValidatePassword(row.Password); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+
+	return nil
+}
+
+// passwordHash returns bcrypt hash of the password of validated row.
+func passwordHash(row *Row) ([]byte, error) {
+	if row.PasswordHash != "" {
+		return []byte(row.PasswordHash), nil
+	}
+
+	return domain.HashPassword(row.Password) //nolint:wrapcheck
+}
+
+// readRows reads rows in provided format and calls handle for each of them. Malformed rows are passed to handle
+// as errors, while errors returned by handle stop reading.
+func readRows(input io.Reader, format string, handle func(int, *Row, error) error) error {
+	switch format {
+	case FormatCSV:
+		return readCSV(input, handle)
+	case FormatJSONL:
+		return readJSONL(input, handle)
+	default:
+		return ErrUnsupportedFormat
+	}
+}
+
+// readCSV reads CSV rows. Header row has to contain email column and may contain password, passwordHash and role
+// columns. Other columns are ignored, so that exported users may be imported again.
+func readCSV(input io.Reader, handle func(int, *Row, error) error) error {
+	reader := csv.NewReader(input)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read csv header: %w", err)
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	if _, ok := columns["email"]; !ok {
+		return ErrMissingEmail
+	}
+
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		parseErr := &csv.ParseError{} //nolint:exhaustruct
+		if errors.As(err, &parseErr) {
+			if err := handle(number, nil, request.NewBadRequestError(parseErr.Err.Error())); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("read csv: %w", err)
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return record[i]
+			}
+
+			return ""
+		}
+
+		row := &Row{
+			Email: field("email"), Password: field("password"), PasswordHash: field("passwordHash"), Role: field("role"),
+		}
+
+		if err := handle(number, row, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONL reads JSON Lines rows, skipping empty lines.
+func readJSONL(input io.Reader, handle func(int, *Row, error) error) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+
+	for number := 0; scanner.Scan(); {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		number++
+
+		var row Row
+
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			if err := handle(number, nil, request.NewBadRequestError("invalid json")); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := handle(number, &row, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read jsonl: %w", err)
+	}
+
+	return nil
+}
+
+// exportedUser converts domain user to exported user.
+func exportedUser(user *domain.User) *ExportedUser {
+	status := user.Status.Status
+	if status == "" {
+		status = domain.StatusActive
+	}
+
+	return &ExportedUser{
+		ID:          user.ID,
+		Email:       user.Email,
+		Active:      user.Active != nil && *user.Active,
+		Status:      status,
+		DisplayName: user.DisplayName,
+		Locale:      user.Locale,
+		Timezone:    user.Timezone,
+		Created:     user.Created,
+		Activated:   user.Activated,
+	}
+}
+
+// csvRecord returns CSV record of the exported user in the order of export columns.
+func (e *ExportedUser) csvRecord() []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	}
+
+	return []string{
+		e.ID, e.Email, strconv.FormatBool(e.Active), e.Status, e.DisplayName, e.Locale, e.Timezone, formatTime(e.Created),
+		formatTime(e.Activated),
+	}
+}
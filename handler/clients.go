@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// ClientsHandler contains OAuth clients related http handlers.
+type ClientsHandler struct {
+	clientsRepo       repository.Clients
+	refreshTokensRepo repository.RefreshTokens
+	log               lax.Logger
+}
+
+// NewClientsHandler creates OAuth clients handler.
+func NewClientsHandler(cr repository.Clients, rtr repository.RefreshTokens, log lax.Logger) *ClientsHandler {
+	return &ClientsHandler{
+		clientsRepo:       cr,
+		refreshTokensRepo: rtr,
+		log:               log,
+	}
+}
+
+// Create registers new OAuth client. Secret of confidential client is returned just in this response.
+//
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Router /admin/clients [post]
+// @Security BearerAuth
+// @Param client body request.Client true "Client"
+// @Success 201 {object} response.Client
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Summary Register OAuth client.
+func (h *ClientsHandler) Create(res http.ResponseWriter, req *http.Request) {
+	client, err := request.ClientFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	var secret string
+
+	domainClient := &domain.Client{ //nolint:exhaustruct
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		Trusted:      client.Trusted,
+	}
+
+	if client.Confidential {
+		if secret, err = token.NewSecret(); err != nil {
+			h.log.Warn("client secret", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		domainClient.SecretHash = token.Hash(secret)
+	}
+
+	if domainClient, err = h.clientsRepo.Create(req.Context(), domainClient); err != nil {
+		h.log.Warn("create client", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	clientResponse := response.FromDomainClient(domainClient)
+	clientResponse.Secret = secret
+
+	response.Render(res, http.StatusCreated, clientResponse, h.log)
+}
+
+// RotateSecret generates new secret of OAuth client. Previous secret stops working immediately and refresh tokens
+// issued to the client are revoked. Public client becomes confidential.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/clients/{id}/secret [post]
+// @Security BearerAuth
+// @Param id path string true "Client ID"
+// @Success 200 {object} response.ClientSecret
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Rotate OAuth client secret.
+func (h *ClientsHandler) RotateSecret(res http.ResponseWriter, req *http.Request) {
+	secret, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("client secret", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.clientsRepo.SetSecret(req.Context(), chi.URLParam(req, "id"), token.Hash(secret)); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("set client secret", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.refreshTokensRepo.RevokeAllOfClient(req.Context(), chi.URLParam(req, "id")); err != nil {
+		h.log.Warn("revoke client refresh tokens", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, &response.ClientSecret{Secret: secret}, h.log)
+}
+
+// FindAll lists OAuth clients.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/clients [get]
+// @Security BearerAuth
+// @Success 200 {array} response.Client
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Summary List OAuth clients.
+func (h *ClientsHandler) FindAll(res http.ResponseWriter, req *http.Request) {
+	domainClients, err := h.clientsRepo.FindAll(req.Context())
+	if err != nil {
+		h.log.Warn("find all clients", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	clients := make([]*response.Client, 0, len(domainClients))
+
+	for i := range domainClients {
+		clients = append(clients, response.FromDomainClient(&domainClients[i]))
+	}
+
+	response.Render(res, http.StatusOK, clients, h.log)
+}
+
+// Delete deletes OAuth client together with its authorization codes, consents and refresh tokens.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/clients/{id} [delete]
+// @Security BearerAuth
+// @Param id path string true "Client ID"
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Delete OAuth client.
+func (h *ClientsHandler) Delete(res http.ResponseWriter, req *http.Request) {
+	if err := h.clientsRepo.Delete(req.Context(), chi.URLParam(req, "id")); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("delete client", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
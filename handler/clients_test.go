@@ -0,0 +1,69 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRotateClientSecret(t *testing.T) {
+	t.Parallel()
+
+	clientsRepo := &clientsRepositoryFake{clients: map[string]*domain.Client{
+		testServiceID: {ID: testServiceID, SecretHash: token.Hash(testServiceSecret)}, //nolint:exhaustruct
+	}}
+	refreshTokensRepo := &refreshTokensRepositoryFake{tokens: map[string]bool{}} //nolint:exhaustruct
+	clientsHandler := handler.NewClientsHandler(clientsRepo, refreshTokensRepo,
+		lax.NewZapAdapter(zaptest.NewLogger(t)))
+
+	ctx := context.Background()
+	refreshTokenHashes := map[string][]byte{}
+
+	for familyID, clientID := range map[string]string{"session": "", "service": testServiceID} {
+		refreshTokenHashes[familyID] = token.Hash(familyID + "-refresh-token")
+
+		if _, err := refreshTokensRepo.Create(ctx, familyID, "john", clientID, refreshTokenHashes[familyID],
+			time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := chi.NewRouter()
+	mux.Post("/admin/clients/{id}/secret", clientsHandler.RotateSecret)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/clients/unknown/secret", "", "", http.StatusNotFound, nil)
+
+	var secret response.ClientSecret
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/clients/"+testServiceID+"/secret", "", "", http.StatusOK, &secret)
+
+	if secret.Secret == "" || !bytes.Equal(clientsRepo.clients[testServiceID].SecretHash, token.Hash(secret.Secret)) {
+		t.Errorf("RotateSecret() = %+v; want new secret", secret)
+	}
+
+	if _, err := refreshTokensRepo.Rotate(ctx, refreshTokenHashes["service"], token.Hash("next"),
+		time.Now().Add(time.Hour)); !errors.Is(err, repository.ErrTokenRevoked) {
+		t.Errorf("Rotate(client refresh token) = error %v; want %v", err, repository.ErrTokenRevoked)
+	}
+
+	if _, err := refreshTokensRepo.Rotate(ctx, refreshTokenHashes["session"], token.Hash("next-session"),
+		time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("Rotate(session refresh token) = error %v; want nil", err)
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+)
+
+// Token cookie names. Auth token cookie name matches the one jwtauth.TokenFromCookie reads.
+const (
+	CookieAuthToken    = "jwt"
+	CookieRefreshToken = "refresh_token"
+)
+
+// ErrInvalidSameSite is returned when unknown same site policy is configured.
+var ErrInvalidSameSite = errors.New("invalid same site policy")
+
+// Cookies sets tokens in HttpOnly cookies for browser clients, so that tokens are not accessible to scripts.
+// Together with tokens, CSRF token is set in cookie readable by scripts, which has to be sent back in header as
+// required by mw.CSRF.
+type Cookies struct {
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+}
+
+// NewCookies creates cookies setter. Empty domain makes cookies host only.
+func NewCookies(domain string, secure bool, sameSite http.SameSite) *Cookies {
+	return &Cookies{domain: domain, secure: secure, sameSite: sameSite}
+}
+
+// ParseSameSite parses same site policy [strict|lax|none].
+func ParseSameSite(sameSite string) (http.SameSite, error) {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("%w: %s", ErrInvalidSameSite, sameSite)
+	}
+}
+
+// set sets auth, refresh and newly generated CSRF token cookies. Refresh token cookie is sent just to the refresh
+// and logout endpoints.
+func (c *Cookies) set(res http.ResponseWriter, jwt *token.JWT, authToken, refreshToken string) error {
+	csrfToken, err := token.NewSecret()
+	if err != nil {
+		return fmt.Errorf("csrf token: %w", err)
+	}
+
+	http.SetCookie(res, c.cookie(CookieAuthToken, authToken, "/", jwt.AuthTokenExp(), true))
+	http.SetCookie(res, c.cookie(CookieRefreshToken, refreshToken, "/users", jwt.RefreshTokenExp(), true))
+	http.SetCookie(res, c.cookie(mw.CSRFCookie, csrfToken, "/", jwt.RefreshTokenExp(), false))
+
+	return nil
+}
+
+// clear removes all cookies set by set.
+func (c *Cookies) clear(res http.ResponseWriter) {
+	http.SetCookie(res, c.cookie(CookieAuthToken, "", "/", -1, true))
+	http.SetCookie(res, c.cookie(CookieRefreshToken, "", "/users", -1, true))
+	http.SetCookie(res, c.cookie(mw.CSRFCookie, "", "/", -1, false))
+}
+
+func (c *Cookies) cookie(name, value, path string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	cookie := &http.Cookie{ //nolint:exhaustruct
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   c.secure,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	}
+
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+
+	return cookie
+}
+
+// refreshTokenFromCookie returns refresh token found in cookie or empty string.
+func refreshTokenFromCookie(req *http.Request) string {
+	cookie, err := req.Cookie(CookieRefreshToken)
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// RequestEmailChange sends confirmation link to the new email address of the current user and notice with cancel
+// link to the current one. Email address is changed just once confirmed. Previously requested change is
+// invalidated. Step-up token is required besides the current password.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/email [post]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Param emailChange body request.EmailChange true "New email address and the current password"
+// @Success 202
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Request email address change.
+func (h *UsersHandler) RequestEmailChange(res http.ResponseWriter, req *http.Request) { //nolint:cyclop,funlen
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	emailChange, err := request.EmailChangeFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !domainUser.IsValidPassword(emailChange.Password) {
+		response.RenderErrorStatus(res, http.StatusForbidden, "invalid password", h.log)
+
+		return
+	}
+
+	if strings.EqualFold(emailChange.Email, domainUser.Email) {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "email unchanged", h.log)
+
+		return
+	}
+
+	_, err = h.usersRepo.FindOneByEmail(req.Context(), emailChange.Email)
+	if err == nil {
+		response.RenderErrorStatus(res, http.StatusConflict, "email already in use", h.log)
+
+		return
+	}
+
+	if !errors.Is(err, repository.ErrResourceNotFound) {
+		h.log.Warn("find user by email", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	confirmToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("email change confirm token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	cancelToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("email change cancel token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.emailChangesRepo.Create(req.Context(), &domain.EmailChange{
+		UserID:           domainUser.ID,
+		NewEmail:         emailChange.Email,
+		ConfirmTokenHash: token.Hash(confirmToken),
+		CancelTokenHash:  token.Hash(cancelToken),
+		Expires:          time.Now().Add(h.emailChangeExp),
+		Created:          nil,
+	}); err != nil {
+		h.log.Warn("create email change", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message := fmt.Sprintf("%s/users/email/confirm/%s", h.externalURL, confirmToken)
+
+	if err := h.sender.Send(emailChange.Email, "Email address confirmation", message); err != nil {
+		h.log.Warn("send email change confirmation", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message = fmt.Sprintf("Change of your email address to %s was requested. If you didn't request it, cancel it at "+
+		"%s/%s/%s and change your password.", emailChange.Email, h.externalURL, h.frontendEmailCancelPath, cancelToken)
+
+	if err := h.sender.Send(domainUser.Email, "Email address change", message); err != nil {
+		h.log.Warn("send email change notice", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusAccepted, nil, h.log)
+}
+
+// ConfirmEmailChange changes email address of the user to the new one the token has been sent to. Confirmation
+// token expires after configured period.
+//
+// @Tags users
+// @Produce json
+// @Router /users/email/confirm/{token} [get]
+// @Param token path string true "Email change confirmation token"
+// @Success 200 {object} response.User
+// @Failure 400
+// @Failure 404 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 410 {object} response.Error
+// @Failure 500
+// @Summary Confirm email address change.
+func (h *UsersHandler) ConfirmEmailChange(res http.ResponseWriter, req *http.Request) {
+	confirmToken := chi.URLParam(req, "token")
+	if confirmToken == "" {
+		response.Render(res, http.StatusBadRequest, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.emailChangesRepo.Confirm(req.Context(), token.Hash(confirmToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+		case errors.Is(err, repository.ErrTokenExpired):
+			response.RenderErrorStatus(res, http.StatusGone, "email change token expired", h.log)
+		case errors.Is(err, repository.ErrUniqueViolation):
+			response.RenderErrorStatus(res, http.StatusConflict, "email already in use", h.log)
+		default:
+			h.log.Warn("confirm email change", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+		}
+
+		return
+	}
+
+	user := response.FromDomainUser(domainUser)
+	user.ID = ""
+
+	response.Render(res, http.StatusOK, user, h.log)
+}
+
+// CancelEmailChange cancels pending email address change using token from the link sent to the current address.
+//
+// @Tags users
+// @Router /users/email/cancel/{token} [post]
+// @Param token path string true "Email change cancel token"
+// @Success 204
+// @Failure 400
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Cancel email address change.
+func (h *UsersHandler) CancelEmailChange(res http.ResponseWriter, req *http.Request) {
+	cancelToken := chi.URLParam(req, "token")
+	if cancelToken == "" {
+		response.Render(res, http.StatusBadRequest, nil, h.log)
+
+		return
+	}
+
+	if err := h.emailChangesRepo.Cancel(req.Context(), token.Hash(cancelToken)); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("cancel email change", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
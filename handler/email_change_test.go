@@ -0,0 +1,231 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestEmailChange(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	sender := &senderFake{}                           //nolint:exhaustruct
+	emailChangesRepo := &emailChangesRepositoryFake{} //nolint:exhaustruct
+	revocationsRepo := &revocationsRepositoryFake{}   //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}         //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo:  revocationsRepo,
+		sessionsRepo:     sessionsRepo,
+		emailChangesRepo: emailChangesRepo,
+		sender:           sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Get("/users/email/confirm/{token}", usersHandler.ConfirmEmailChange)
+	mux.Post("/users/email/cancel/{token}", usersHandler.CancelEmailChange)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Post("/users/me/email", usersHandler.RequestEmailChange)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changeBody := func(email, password string) string {
+		return `{"email":"` + email + `","password":"` + password + `"}`
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken, changeBody("johnny@sixpack.com", "wrong"),
+		http.StatusForbidden, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("John.Doe@sixpack.com", testPassword), http.StatusBadRequest, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("jane.doe@sixpack.com", testPassword), http.StatusConflict, nil)
+
+	// canceled change may not be confirmed anymore
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("johnny@sixpack.com", testPassword), http.StatusAccepted, nil)
+
+	confirmURL := server.URL + emailChangeLink(t, sender, "johnny@sixpack.com")
+	cancelLink := emailChangeLink(t, sender, "john.doe@sixpack.com")
+	cancelURL := server.URL + "/users/email/cancel/" + cancelLink[strings.LastIndex(cancelLink, "/")+1:]
+
+	doJSON(t, http.MethodGet, cancelURL, "", "", http.StatusMethodNotAllowed, nil)
+	doJSON(t, http.MethodPost, cancelURL, "", "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodPost, cancelURL, "", "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodGet, confirmURL, "", "", http.StatusNotFound, nil)
+
+	// address taken in the meantime
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("johnny@sixpack.com", testPassword), http.StatusAccepted, nil)
+	emailChangesRepo.take("johnny@sixpack.com")
+	doJSON(t, http.MethodGet, server.URL+emailChangeLink(t, sender, "johnny@sixpack.com"), "", "",
+		http.StatusConflict, nil)
+
+	// expired change
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("john@sixpack.com", testPassword), http.StatusAccepted, nil)
+	emailChangesRepo.expire()
+	doJSON(t, http.MethodGet, server.URL+emailChangeLink(t, sender, "john@sixpack.com"), "", "", http.StatusGone, nil)
+
+	// the newest request invalidates the previous one
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("john@sixpack.com", testPassword), http.StatusAccepted, nil)
+
+	previousURL := server.URL + emailChangeLink(t, sender, "john@sixpack.com")
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email", authToken,
+		changeBody("john@sixpack.com", testPassword), http.StatusAccepted, nil)
+	doJSON(t, http.MethodGet, previousURL, "", "", http.StatusNotFound, nil)
+
+	var user response.User
+
+	doJSON(t, http.MethodGet, server.URL+emailChangeLink(t, sender, "john@sixpack.com"), "", "", http.StatusOK, &user)
+
+	if user.Email != "john@sixpack.com" {
+		t.Errorf("ConfirmEmailChange() = %+v; want new email", user)
+	}
+}
+
+var emailChangeLinkRegex = regexp.MustCompile(`/(users/email/confirm|frontend-email-cancel-path)/[\w-]+`)
+
+// emailChangeLink returns path of the link sent to the recipient.
+func emailChangeLink(t *testing.T, sender *senderFake, recipient string) string {
+	t.Helper()
+
+	message, ok := sender.message(recipient)
+	if !ok {
+		t.Fatalf("no message sent to %s", recipient)
+	}
+
+	link := emailChangeLinkRegex.FindString(message)
+	if link == "" {
+		t.Fatalf("message %q; want email change link", message)
+	}
+
+	return link
+}
+
+var _ repository.EmailChanges = (*emailChangesRepositoryFake)(nil)
+
+// emailChangesRepositoryFake keeps pending email changes by user ID and knows addresses taken meanwhile.
+type emailChangesRepositoryFake struct {
+	mu           sync.Mutex
+	emailChanges map[string]*domain.EmailChange
+	taken        map[string]bool
+}
+
+func (repo *emailChangesRepositoryFake) Create(ctx context.Context, emailChange *domain.EmailChange) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.emailChanges == nil {
+		repo.emailChanges = map[string]*domain.EmailChange{}
+	}
+
+	repo.emailChanges[emailChange.UserID] = emailChange
+
+	return nil
+}
+
+func (repo *emailChangesRepositoryFake) Confirm(ctx context.Context, confirmTokenHash []byte) (*domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for userID, emailChange := range repo.emailChanges {
+		if !bytes.Equal(emailChange.ConfirmTokenHash, confirmTokenHash) {
+			continue
+		}
+
+		if emailChange.IsExpired(time.Now()) {
+			delete(repo.emailChanges, userID)
+
+			return nil, repository.ErrTokenExpired
+		}
+
+		if repo.taken[emailChange.NewEmail] {
+			return nil, repository.ErrUniqueViolation
+		}
+
+		delete(repo.emailChanges, userID)
+
+		active := true
+
+		return &domain.User{ID: userID, Email: emailChange.NewEmail, Active: &active}, nil //nolint:exhaustruct
+	}
+
+	return nil, repository.ErrResourceNotFound
+}
+
+func (repo *emailChangesRepositoryFake) Cancel(ctx context.Context, cancelTokenHash []byte) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for userID, emailChange := range repo.emailChanges {
+		if bytes.Equal(emailChange.CancelTokenHash, cancelTokenHash) {
+			delete(repo.emailChanges, userID)
+
+			return nil
+		}
+	}
+
+	return repository.ErrResourceNotFound
+}
+
+func (repo *emailChangesRepositoryFake) FindAll(ctx context.Context, userID string) ([]domain.EmailChange, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	emailChanges := []domain.EmailChange{}
+
+	if emailChange, ok := repo.emailChanges[userID]; ok {
+		emailChanges = append(emailChanges, *emailChange)
+	}
+
+	return emailChanges, nil
+}
+
+func (repo *emailChangesRepositoryFake) take(email string) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.taken == nil {
+		repo.taken = map[string]bool{}
+	}
+
+	repo.taken[email] = true
+}
+
+func (repo *emailChangesRepositoryFake) expire() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, emailChange := range repo.emailChanges {
+		emailChange.Expires = time.Now().Add(-time.Second)
+	}
+}
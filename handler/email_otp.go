@@ -0,0 +1,339 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// MFAMethodEmailOTP is second factor method listed in MFA challenge if user has enabled codes sent per email.
+const MFAMethodEmailOTP = "email_otp"
+
+const (
+	maxEmailOTPAttempts    = 5
+	emailOTPResendInterval = time.Minute
+)
+
+// EnableEmailOTP requires code sent per email as the second factor at login of the current user.
+//
+// @Tags users
+// @Router /users/me/email-otp [post]
+// @Security BearerAuth
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary Enable email OTP second factor.
+func (h *UsersHandler) EnableEmailOTP(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	if err := h.emailOTPsRepo.Enable(req.Context(), claims.Subject); err != nil {
+		h.log.Warn("enable email otp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// DisableEmailOTP stops requiring code sent per email as the second factor at login of the current user. Step-up
+// token is required.
+//
+// @Tags users
+// @Router /users/me/email-otp [delete]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Disable email OTP second factor.
+func (h *UsersHandler) DisableEmailOTP(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	if err := h.emailOTPsRepo.Disable(req.Context(), claims.Subject); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, "email otp not enabled", h.log)
+
+			return
+		}
+
+		h.log.Warn("disable email otp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// SendLoginEmailOTP sends login code to the email address of the user who has passed the first factor and has
+// enabled email OTP. Previously sent code is invalidated. Code is exchanged for tokens at /users/login/mfa.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/email-otp [post]
+// @Param emailOTPLogin body request.EmailOTPLogin true "MFA challenge token"
+// @Success 202
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 403 {object} response.Error
+// @Failure 429 {object} response.Error
+// @Failure 500
+// @Summary Send login code per email.
+func (h *UsersHandler) SendLoginEmailOTP(res http.ResponseWriter, req *http.Request) {
+	emailOTPLogin, err := request.EmailOTPLoginFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	claims, err := h.jwt.ParseMFAToken(emailOTPLogin.MFAToken)
+	if err != nil {
+		h.log.Warn("parse mfa token", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid mfa token", h.log)
+
+		return
+	}
+
+	enabled, err := h.emailOTPsRepo.IsEnabled(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("is email otp enabled", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !enabled {
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "email otp not enabled", h.log)
+
+		return
+	}
+
+	h.sendEmailOTP(res, req, claims.Subject, domain.EmailOTPLogin)
+}
+
+// SendStepUpEmailOTP sends code to the email address of the current user, which may be exchanged for step-up token.
+// Previously sent code is invalidated.
+//
+// @Tags users
+// @Router /users/me/step-up/email-otp [post]
+// @Security BearerAuth
+// @Success 202
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 429 {object} response.Error
+// @Failure 500
+// @Summary Send step-up code per email.
+func (h *UsersHandler) SendStepUpEmailOTP(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	h.sendEmailOTP(res, req, claims.Subject, domain.EmailOTPStepUp)
+}
+
+// StepUp re-authenticates the current user by the code sent per email and issues short-lived step-up token bound
+// to the current session. Sensitive operations require it in X-Step-Up-Token header.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/step-up [post]
+// @Security BearerAuth
+// @Param stepUp body request.StepUp true "Code sent per email"
+// @Success 200 {object} response.StepUp
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 403 {object} response.Error
+// @Failure 429 {object} response.Error
+// @Failure 500
+// @Summary Re-authenticate by code sent per email.
+func (h *UsersHandler) StepUp(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	stepUp, err := request.StepUpFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	if err := h.verifyEmailOTP(req.Context(), claims.Subject, domain.EmailOTPStepUp, stepUp.Code); err != nil {
+		var reqErr *request.Error
+
+		if !errors.As(err, &reqErr) {
+			h.log.Warn("verify email otp", lax.Error(err))
+		}
+
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	stepUpToken, err := h.jwt.StepUpToken(claims.Subject, claims.SessionID)
+	if err != nil {
+		h.log.Warn("step-up token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, &response.StepUp{
+		StepUpToken: stepUpToken,
+		ExpiresIn:   int64(token.StepUpTokenExp.Seconds()),
+	}, h.log)
+}
+
+// sendEmailOTP generates new code of the purpose, stores its hash and sends it to the email address of the user.
+// Codes can't be resent more often than once per resend interval, nor before the challenge with too many failed
+// attempts expires, and resent code inherits failed attempts of the previous one.
+func (h *UsersHandler) sendEmailOTP(res http.ResponseWriter, req *http.Request, userID, purpose string) {
+	domainUser, err := h.usersRepo.FindOne(req.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if domainUser.Status.IsBlocked(time.Now()) {
+		response.RenderErrorStatus(res, http.StatusForbidden, blockedMessage(&domainUser.Status), h.log)
+
+		return
+	}
+
+	previous, err := h.emailOTPsRepo.FindOne(req.Context(), userID, purpose)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		h.log.Warn("find email otp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err == nil && previous.Created != nil && time.Since(*previous.Created) < emailOTPResendInterval {
+		response.RenderErrorStatus(res, http.StatusTooManyRequests, "code already sent", h.log)
+
+		return
+	}
+
+	if err == nil && !previous.IsExpired(time.Now()) && previous.Attempts >= maxEmailOTPAttempts {
+		response.RenderErrorStatus(res, http.StatusTooManyRequests, "too many failed attempts", h.log)
+
+		return
+	}
+
+	code, err := token.NewEmailOTPCode()
+	if err != nil {
+		h.log.Warn("email otp code", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.emailOTPsRepo.Create(req.Context(), &domain.EmailOTP{
+		UserID:   userID,
+		Purpose:  purpose,
+		CodeHash: token.HashEmailOTPCode(userID, purpose, code),
+		Attempts: 0,
+		Expires:  time.Now().Add(h.emailOTPExp),
+		Created:  nil,
+	}); err != nil {
+		h.log.Warn("create email otp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, h.emailOTPExp)
+
+	if err := h.sender.Send(domainUser.Email, "Verification code", message); err != nil {
+		h.log.Warn("send email otp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusAccepted, nil, h.log)
+}
+
+// verifyEmailOTP verifies and consumes code of the purpose sent to the user. Challenge is refused once expired or
+// after too many attempts. Every attempt is counted before the code is checked, so parallel attempts can't exceed
+// the limit. Failed verification returns request error.
+func (h *UsersHandler) verifyEmailOTP(ctx context.Context, userID, purpose, code string) error {
+	emailOTP, err := h.emailOTPsRepo.FindOne(ctx, userID, purpose)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid or expired code"}
+		}
+
+		return fmt.Errorf("find email otp: %w", err)
+	}
+
+	if emailOTP.IsExpired(time.Now()) {
+		return &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid or expired code"}
+	}
+
+	if err := h.emailOTPsRepo.Attempt(ctx, userID, purpose, maxEmailOTPAttempts); err != nil {
+		if errors.Is(err, repository.ErrTooManyAttempts) {
+			return &request.Error{StatusCode: http.StatusTooManyRequests, Message: "too many failed attempts"}
+		}
+
+		return fmt.Errorf("attempt email otp: %w", err)
+	}
+
+	if !emailOTP.Matches(token.HashEmailOTPCode(userID, purpose, code)) {
+		return &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid or expired code"}
+	}
+
+	if err := h.emailOTPsRepo.Delete(ctx, userID, purpose); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return &request.Error{StatusCode: http.StatusUnauthorized, Message: "code already used"}
+		}
+
+		return fmt.Errorf("delete email otp: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestEmailOTPLogin(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}                     //nolint:exhaustruct
+	emailOTPsRepo := &emailOTPsRepositoryFake{} //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{}         //nolint:exhaustruct
+	server := newEmailOTPServer(t, jwt, sender, emailOTPsRepo, usersRepo)
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var challenge response.MFAChallenge
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusOK, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email-otp", authToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusAccepted,
+		&challenge)
+
+	if len(challenge.Methods) != 1 || challenge.Methods[0] != "email_otp" {
+		t.Fatalf("Login() = %+v; want email_otp method", challenge)
+	}
+
+	sendBody := fmt.Sprintf(`{"mfaToken":%q}`, challenge.MFAToken)
+	mfaLogin := func(code string) string {
+		return fmt.Sprintf(`{"mfaToken":%q,"emailCode":%q}`, challenge.MFAToken, code)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", `{"mfaToken":"invalid"}`,
+		http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("123456"), http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusAccepted, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusTooManyRequests, nil)
+
+	code := emailOTPCode(t, sender)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(wrongCode(code)), http.StatusUnauthorized,
+		nil)
+
+	var user response.User
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(code), http.StatusOK, &user)
+
+	if user.AuthToken == "" || user.RefreshToken == "" {
+		t.Errorf("LoginMFA() = %+v; want tokens", user)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(code), http.StatusUnauthorized, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusAccepted, nil)
+	emailOTPsRepo.expire()
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(emailOTPCode(t, sender)),
+		http.StatusUnauthorized, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusAccepted, nil)
+
+	code = emailOTPCode(t, sender)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(wrongCode(code)), http.StatusUnauthorized,
+		nil)
+	emailOTPsRepo.allowResend()
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusAccepted, nil)
+
+	code = emailOTPCode(t, sender)
+	statuses := postInParallel(t, 10, server.URL+"/users/login/mfa", mfaLogin(wrongCode(code)))
+
+	if statuses[http.StatusUnauthorized] != 4 || statuses[http.StatusTooManyRequests] != 6 {
+		t.Errorf("parallel LoginMFA() = statuses %v; want four failures, counting the one before resend", statuses)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(code), http.StatusTooManyRequests, nil)
+	emailOTPsRepo.allowResend()
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusTooManyRequests, nil)
+	emailOTPsRepo.expire()
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusAccepted, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin(emailOTPCode(t, sender)), http.StatusOK,
+		nil)
+
+	if _, err := usersRepo.SetStatus(context.Background(), "john", &domain.UserStatus{ //nolint:exhaustruct
+		Status: domain.StatusBanned,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	emailOTPsRepo.expire()
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusForbidden,
+		nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/email-otp", "", sendBody, http.StatusForbidden, nil)
+}
+
+func TestStepUp(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}                                                                         //nolint:exhaustruct
+	server := newEmailOTPServer(t, jwt, sender, &emailOTPsRepositoryFake{}, &usersRepositoryFake{}) //nolint:exhaustruct
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSessionToken, _, err := jwt.Tokens("john", "other", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/email-otp", authToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/email-otp", authToken, "", http.StatusForbidden, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/step-up", authToken, `{"code":"123456"}`,
+		http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/me/step-up/email-otp", authToken, "", http.StatusAccepted, nil)
+
+	var stepUp response.StepUp
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/step-up", authToken,
+		fmt.Sprintf(`{"code":%q}`, emailOTPCode(t, sender)), http.StatusOK, &stepUp)
+
+	if stepUp.StepUpToken == "" || stepUp.ExpiresIn != 300 {
+		t.Fatalf("StepUp() = %+v; want step-up token", stepUp)
+	}
+
+	disable := func(authToken, stepUpToken string, wantStatus int) {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodDelete,
+			server.URL+"/users/me/email-otp", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+authToken)
+		req.Header.Set(mw.StepUpHeader, stepUpToken)
+
+		do(t, req, wantStatus, nil)
+	}
+
+	disable(otherSessionToken, stepUp.StepUpToken, http.StatusForbidden)
+	disable(authToken, authToken, http.StatusForbidden)
+	disable(authToken, stepUp.StepUpToken, http.StatusNoContent)
+	disable(authToken, stepUp.StepUpToken, http.StatusNotFound)
+}
+
+func newEmailOTPServer(t *testing.T, jwt *token.JWT, sender *senderFake,
+	emailOTPsRepo *emailOTPsRepositoryFake, usersRepo *usersRepositoryFake,
+) *httptest.Server {
+	t.Helper()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}       //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo:       usersRepo,
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+		emailOTPsRepo:   emailOTPsRepo,
+		sender:          sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login", usersHandler.Login)
+	mux.Post("/users/login/mfa", usersHandler.LoginMFA)
+	mux.Post("/users/login/email-otp", usersHandler.SendLoginEmailOTP)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Post("/users/me/email-otp", usersHandler.EnableEmailOTP)
+		r.Post("/users/me/step-up/email-otp", usersHandler.SendStepUpEmailOTP)
+		r.Post("/users/me/step-up", usersHandler.StepUp)
+		r.With(mw.StepUp(jwt, log)).Delete("/users/me/email-otp", usersHandler.DisableEmailOTP)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+var emailOTPCodeRegex = regexp.MustCompile(`\d{6}`)
+
+func emailOTPCode(t *testing.T, sender *senderFake) string {
+	t.Helper()
+
+	message, ok := sender.message("john.doe@sixpack.com")
+	if !ok {
+		t.Fatal("no code sent")
+	}
+
+	code := emailOTPCodeRegex.FindString(message)
+	if code == "" {
+		t.Fatalf("message %q; want code", message)
+	}
+
+	return code
+}
+
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "000001"
+	}
+
+	return "000000"
+}
+
+var _ repository.EmailOTPs = (*emailOTPsRepositoryFake)(nil)
+
+type emailOTPsRepositoryFake struct {
+	mu        sync.Mutex
+	enabled   map[string]bool
+	emailOTPs map[string]*domain.EmailOTP
+}
+
+func (repo *emailOTPsRepositoryFake) Enable(ctx context.Context, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.enabled == nil {
+		repo.enabled = map[string]bool{}
+	}
+
+	repo.enabled[userID] = true
+
+	return nil
+}
+
+func (repo *emailOTPsRepositoryFake) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return repo.enabled[userID], nil
+}
+
+func (repo *emailOTPsRepositoryFake) Disable(ctx context.Context, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if !repo.enabled[userID] {
+		return repository.ErrResourceNotFound
+	}
+
+	delete(repo.enabled, userID)
+	delete(repo.emailOTPs, userID+domain.EmailOTPLogin)
+
+	return nil
+}
+
+func (repo *emailOTPsRepositoryFake) Create(ctx context.Context, emailOTP *domain.EmailOTP) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.emailOTPs == nil {
+		repo.emailOTPs = map[string]*domain.EmailOTP{}
+	}
+
+	if previous, ok := repo.emailOTPs[emailOTP.UserID+emailOTP.Purpose]; ok && !previous.IsExpired(time.Now()) {
+		emailOTP.Attempts = previous.Attempts
+	}
+
+	created := time.Now()
+	emailOTP.Created = &created
+	repo.emailOTPs[emailOTP.UserID+emailOTP.Purpose] = emailOTP
+
+	return nil
+}
+
+func (repo *emailOTPsRepositoryFake) FindOne(ctx context.Context, userID, purpose string) (*domain.EmailOTP, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	emailOTP, ok := repo.emailOTPs[userID+purpose]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	found := *emailOTP
+
+	return &found, nil
+}
+
+func (repo *emailOTPsRepositoryFake) Attempt(ctx context.Context, userID, purpose string, maxAttempts int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	emailOTP, ok := repo.emailOTPs[userID+purpose]
+	if !ok || emailOTP.Attempts >= maxAttempts || emailOTP.IsExpired(time.Now()) {
+		return repository.ErrTooManyAttempts
+	}
+
+	emailOTP.Attempts++
+
+	return nil
+}
+
+func (repo *emailOTPsRepositoryFake) Delete(ctx context.Context, userID, purpose string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.emailOTPs[userID+purpose]; !ok {
+		return repository.ErrResourceNotFound
+	}
+
+	delete(repo.emailOTPs, userID+purpose)
+
+	return nil
+}
+
+// allowResend moves creation of all challenges before the resend interval.
+func (repo *emailOTPsRepositoryFake) allowResend() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	created := time.Now().Add(-time.Hour)
+
+	for _, emailOTP := range repo.emailOTPs {
+		emailOTP.Created = &created
+	}
+}
+
+// expire expires all challenges and moves their creation before the resend interval.
+func (repo *emailOTPsRepositoryFake) expire() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	created := time.Now().Add(-time.Hour)
+
+	for _, emailOTP := range repo.emailOTPs {
+		emailOTP.Expires = time.Now().Add(-time.Second)
+		emailOTP.Created = &created
+	}
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/handler/invite"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// InvitationsHandler contains user invitations related http handlers.
+type InvitationsHandler struct {
+	invitationsRepo repository.Invitations
+	inviter         *invite.Inviter
+	log             lax.Logger
+}
+
+// NewInvitationsHandler creates user invitations handler.
+func NewInvitationsHandler(ir repository.Invitations, inviter *invite.Inviter, log lax.Logger) *InvitationsHandler {
+	return &InvitationsHandler{
+		invitationsRepo: ir,
+		inviter:         inviter,
+		log:             log,
+	}
+}
+
+// Create invites new user by sending link to the frontend invitation page. Pending invitation of the same email
+// address is replaced. Inviter having a role may grant just the same role.
+//
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Router /admin/invitations [post]
+// @Security BearerAuth
+// @Param invitation body request.Invitation true "Invitation"
+// @Success 201 {object} response.Invitation
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Invite user.
+func (h *InvitationsHandler) Create(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	invitation, err := request.InvitationFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainInvitation, err := h.inviter.Invite(req.Context(), invitation.Email, invitation.Role, claims.Subject)
+	if err != nil {
+		switch {
+		case errors.Is(err, invite.ErrAlreadyRegistered):
+			response.RenderErrorStatus(res, http.StatusConflict, err.Error(), h.log)
+		case errors.Is(err, invite.ErrRoleNotAllowed):
+			response.RenderErrorStatus(res, http.StatusForbidden, err.Error(), h.log)
+		default:
+			h.log.Warn("invite", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+		}
+
+		return
+	}
+
+	response.Render(res, http.StatusCreated, response.FromDomainInvitation(domainInvitation), h.log)
+}
+
+// FindAll renders pending invitations, the newest first.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/invitations [get]
+// @Security BearerAuth
+// @Success 200 {array} response.Invitation
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Summary List invitations.
+func (h *InvitationsHandler) FindAll(res http.ResponseWriter, req *http.Request) {
+	domainInvitations, err := h.invitationsRepo.FindAll(req.Context())
+	if err != nil {
+		h.log.Warn("find invitations", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	invitations := make([]*response.Invitation, 0, len(domainInvitations))
+
+	for i := range domainInvitations {
+		invitations = append(invitations, response.FromDomainInvitation(&domainInvitations[i]))
+	}
+
+	response.Render(res, http.StatusOK, invitations, h.log)
+}
+
+// Delete revokes pending invitation.
+//
+// @Tags admin
+// @Router /admin/invitations/{id} [delete]
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 204
+// @Failure 401
+// @Failure 403
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Revoke invitation.
+func (h *InvitationsHandler) Delete(res http.ResponseWriter, req *http.Request) {
+	if err := h.invitationsRepo.Delete(req.Context(), chi.URLParam(req, "id")); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("delete invitation", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// FindOne renders email address and role of the pending invitation, so that frontend invitation page may show them.
+//
+// @Tags invitations
+// @Produce json
+// @Router /invitations/{token} [get]
+// @Param token path string true "Invitation token"
+// @Success 200 {object} response.Invitation
+// @Failure 404 {object} response.Error
+// @Failure 410 {object} response.Error
+// @Failure 500
+// @Summary Get invitation.
+func (h *InvitationsHandler) FindOne(res http.ResponseWriter, req *http.Request) {
+	domainInvitation, err := h.invitationsRepo.FindOneByToken(req.Context(), token.Hash(chi.URLParam(req, "token")))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+		case errors.Is(err, repository.ErrTokenExpired):
+			response.RenderErrorStatus(res, http.StatusGone, "invitation expired", h.log)
+		default:
+			h.log.Warn("find invitation", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+		}
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.FromDomainInvitationPublic(domainInvitation), h.log)
+}
+
+// Accept creates already activated account of the invitee with the chosen password and intended role, so no
+// activation link is sent. Invitation can be accepted just once.
+//
+// @Tags invitations
+// @Accept json
+// @Produce json
+// @Router /invitations/{token}/accept [post]
+// @Param token path string true "Invitation token"
+// @Param acceptance body request.InvitationAcceptance true "Password"
+// @Success 201 {object} response.User
+// @Failure 400 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 410 {object} response.Error
+// @Failure 500
+// @Summary Accept invitation.
+func (h *InvitationsHandler) Accept(res http.ResponseWriter, req *http.Request) {
+	acceptance, err := request.InvitationAcceptanceFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.invitationsRepo.Accept(req.Context(), token.Hash(chi.URLParam(req, "token")),
+		acceptance.HashedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+		case errors.Is(err, repository.ErrTokenExpired):
+			response.RenderErrorStatus(res, http.StatusGone, "invitation expired", h.log)
+		case errors.Is(err, repository.ErrUniqueViolation):
+			response.RenderErrorStatus(res, http.StatusConflict, "already registered", h.log)
+		default:
+			h.log.Warn("accept invitation", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+		}
+
+		return
+	}
+
+	response.Render(res, http.StatusCreated, response.FromDomainUser(domainUser), h.log)
+}
@@ -0,0 +1,310 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/invite"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+const invitationLinkPrefix = "http://localhost:3000/frontend-invitation-path/"
+
+func TestInvitations(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	sender := &senderFake{}                                                                        //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{roles: map[string]string{"jane": "owner", "admin": "admin"}} //nolint:exhaustruct
+	invitationsRepo := &invitationsRepositoryFake{usersRepo: usersRepo}                            //nolint:exhaustruct
+	inviter := invite.NewInviter(invitationsRepo, usersRepo, sender, "http://localhost:3000",
+		"frontend-invitation-path", time.Hour)
+	invitationsHandler := handler.NewInvitationsHandler(invitationsRepo, inviter, log)
+
+	mux := chi.NewRouter()
+	mux.Get("/invitations/{token}", invitationsHandler.FindOne)
+	mux.Post("/invitations/{token}/accept", invitationsHandler.Accept)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/admin/invitations", invitationsHandler.FindAll)
+		r.Post("/admin/invitations", invitationsHandler.Create)
+		r.Delete("/admin/invitations/{id}", invitationsHandler.Delete)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	adminToken, _, err := jwt.Tokens("admin", "admin-session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ownerToken, _, err := jwt.Tokens("jane", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	johnToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invitationBody := func(email, role string) string {
+		return fmt.Sprintf(`{"email":%q,"role":%q}`, email, role)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", adminToken, `{"email":"joe"}`,
+		http.StatusBadRequest, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", adminToken, invitationBody("john.doe@sixpack.com", ""),
+		http.StatusConflict, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", ownerToken, invitationBody("joe.doe@sixpack.com",
+		"admin"), http.StatusForbidden, nil)
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", johnToken, invitationBody("joe.doe@sixpack.com",
+		"owner"), http.StatusForbidden, nil)
+
+	var invitation response.Invitation
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", ownerToken, invitationBody("joe.doe@sixpack.com",
+		"owner"), http.StatusCreated, &invitation)
+
+	if invitation.Email != "joe.doe@sixpack.com" || invitation.Role != "owner" || invitation.Inviter != "jane" {
+		t.Errorf("Create() = %+v; want invitation of joe by jane", invitation)
+	}
+
+	invitationToken := invitationTokenFromMessage(t, sender, "joe.doe@sixpack.com")
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", adminToken, invitationBody("jim.doe@sixpack.com",
+		"admin"), http.StatusCreated, &invitation)
+
+	var invitations []response.Invitation
+
+	doJSON(t, http.MethodGet, server.URL+"/admin/invitations", adminToken, "", http.StatusOK, &invitations)
+
+	if len(invitations) != 2 {
+		t.Errorf("FindAll() = %+v; want two invitations", invitations)
+	}
+
+	doJSON(t, http.MethodDelete, server.URL+"/admin/invitations/"+invitation.ID, adminToken, "",
+		http.StatusNoContent, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/admin/invitations/"+invitation.ID, adminToken, "",
+		http.StatusNotFound, nil)
+
+	invitation = response.Invitation{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodGet, server.URL+"/invitations/"+invitationToken, "", "", http.StatusOK, &invitation)
+
+	if invitation.Email != "joe.doe@sixpack.com" || invitation.Role != "owner" || invitation.Inviter != "" {
+		t.Errorf("FindOne() = %+v; want public invitation of joe", invitation)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/invitations/unknown", "", "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodPost, server.URL+"/invitations/"+invitationToken+"/accept", "", `{"password":"pass"}`,
+		http.StatusBadRequest, nil)
+
+	var user response.User
+
+	doJSON(t, http.MethodPost, server.URL+"/invitations/"+invitationToken+"/accept", "",
+		`{"password":"`+testPassword+`"}`, http.StatusCreated, &user)
+
+	if user.Email != "joe.doe@sixpack.com" {
+		t.Errorf("Accept() = %+v; want joe", user)
+	}
+
+	domainUser, err := usersRepo.FindOneByEmail(context.Background(), "joe.doe@sixpack.com")
+	if err != nil || !domainUser.IsValidPassword(testPassword) {
+		t.Errorf("FindOneByEmail() = %+v, %v; want joe with chosen password", domainUser, err)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/invitations/"+invitationToken+"/accept", "",
+		`{"password":"`+testPassword+`"}`, http.StatusNotFound, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/admin/invitations", adminToken, invitationBody("jim.doe@sixpack.com", ""),
+		http.StatusCreated, nil)
+
+	invitationToken = invitationTokenFromMessage(t, sender, "jim.doe@sixpack.com")
+
+	invitationsRepo.expire()
+
+	doJSON(t, http.MethodGet, server.URL+"/invitations/"+invitationToken, "", "", http.StatusGone, nil)
+	doJSON(t, http.MethodPost, server.URL+"/invitations/"+invitationToken+"/accept", "",
+		`{"password":"`+testPassword+`"}`, http.StatusGone, nil)
+}
+
+func invitationTokenFromMessage(t *testing.T, sender *senderFake, recipient string) string {
+	t.Helper()
+
+	message, ok := sender.message(recipient)
+	if !ok || !strings.HasPrefix(message, invitationLinkPrefix) {
+		t.Fatalf("message %q to %s; want invitation link", message, recipient)
+	}
+
+	return strings.TrimPrefix(message, invitationLinkPrefix)
+}
+
+var _ repository.Invitations = (*invitationsRepositoryFake)(nil)
+
+// invitationsRepositoryFake keeps invitations by ID and creates users of accepted invitations in users repository,
+// remembering which user accepted which invitation.
+type invitationsRepositoryFake struct {
+	mu          sync.Mutex
+	usersRepo   *usersRepositoryFake
+	invitations map[string]*domain.Invitation
+	acceptedBy  map[string]string
+	lastID      int
+}
+
+func (repo *invitationsRepositoryFake) Create(ctx context.Context,
+	invitation *domain.Invitation,
+) (*domain.Invitation, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.invitations == nil {
+		repo.invitations = map[string]*domain.Invitation{}
+	}
+
+	for id, pending := range repo.invitations {
+		if pending.Email == invitation.Email && pending.Accepted == nil {
+			delete(repo.invitations, id)
+		}
+	}
+
+	repo.lastID++
+	created := time.Now()
+	stored := *invitation
+	stored.ID = fmt.Sprintf("invitation-%d", repo.lastID)
+	stored.Created = &created
+	repo.invitations[stored.ID] = &stored
+
+	return &stored, nil
+}
+
+func (repo *invitationsRepositoryFake) FindAll(ctx context.Context) ([]domain.Invitation, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	invitations := []domain.Invitation{}
+
+	for _, invitation := range repo.invitations {
+		if invitation.Accepted == nil {
+			invitations = append(invitations, *invitation)
+		}
+	}
+
+	return invitations, nil
+}
+
+func (repo *invitationsRepositoryFake) FindAllByUser(ctx context.Context,
+	userID string,
+) ([]domain.Invitation, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	invitations := []domain.Invitation{}
+
+	for id, invitation := range repo.invitations {
+		if invitation.Inviter == userID || repo.acceptedBy[id] == userID {
+			invitations = append(invitations, *invitation)
+		}
+	}
+
+	return invitations, nil
+}
+
+func (repo *invitationsRepositoryFake) FindOneByToken(ctx context.Context,
+	tokenHash []byte,
+) (*domain.Invitation, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	invitation, err := repo.pending(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	found := *invitation
+
+	return &found, nil
+}
+
+func (repo *invitationsRepositoryFake) Accept(ctx context.Context, tokenHash, password []byte) (*domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	invitation, err := repo.pending(tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	domainUser, err := repo.usersRepo.CreateActivated(ctx, invitation.Email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if repo.acceptedBy == nil {
+		repo.acceptedBy = map[string]string{}
+	}
+
+	accepted := time.Now()
+	invitation.Accepted = &accepted
+	repo.acceptedBy[invitation.ID] = domainUser.ID
+	domainUser.Role = invitation.Role
+
+	return domainUser, nil
+}
+
+func (repo *invitationsRepositoryFake) Delete(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if invitation, ok := repo.invitations[id]; !ok || invitation.Accepted != nil {
+		return repository.ErrResourceNotFound
+	}
+
+	delete(repo.invitations, id)
+
+	return nil
+}
+
+// pending returns pending invitation by token hash. Caller has to hold the lock.
+func (repo *invitationsRepositoryFake) pending(tokenHash []byte) (*domain.Invitation, error) {
+	for _, invitation := range repo.invitations {
+		if !bytes.Equal(invitation.TokenHash, tokenHash) || invitation.Accepted != nil {
+			continue
+		}
+
+		if invitation.IsExpired(time.Now()) {
+			return nil, repository.ErrTokenExpired
+		}
+
+		return invitation, nil
+	}
+
+	return nil, repository.ErrResourceNotFound
+}
+
+func (repo *invitationsRepositoryFake) expire() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, invitation := range repo.invitations {
+		invitation.Expires = time.Now().Add(-time.Second)
+	}
+}
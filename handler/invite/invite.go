@@ -0,0 +1,96 @@
+// Package invite implements invitations of new users, shared by invitations endpoints and bulk import.
+package invite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/arc/send"
+)
+
+// CLIInviter is recorded as inviter of users invited by command line. Such invitations may grant any role, since
+// whoever runs the command has access to the database anyway, so that the first administrator can be invited.
+const CLIInviter = "cli"
+
+// Errors.
+var (
+	ErrAlreadyRegistered = errors.New("already registered")
+	ErrRoleNotAllowed    = errors.New("role not allowed")
+)
+
+// Inviter invites new users.
+type Inviter struct {
+	invitationsRepo        repository.Invitations
+	usersRepo              repository.Users
+	sender                 send.Sender
+	externalURL            string
+	frontendInvitationPath string
+	invitationExp          time.Duration
+}
+
+// NewInviter creates inviter. Invitations contain link to the frontend invitation page and expire after
+// invitationExp.
+func NewInviter(ir repository.Invitations, ur repository.Users, sender send.Sender,
+	externalURL, frontendInvitationPath string, invitationExp time.Duration,
+) *Inviter {
+	return &Inviter{
+		invitationsRepo:        ir,
+		usersRepo:              ur,
+		sender:                 sender,
+		externalURL:            externalURL,
+		frontendInvitationPath: frontendInvitationPath,
+		invitationExp:          invitationExp,
+	}
+}
+
+// Invite stores the invitation, replacing pending invitation of the same email address, and sends link containing
+// invitation token to the invitee. Role may be granted just by inviter having the same role, so that nobody can
+// invite users with more privileges than own. Subjects without role, like administrators defined by policy or
+// clients, may invite users just without role.
+func (i *Inviter) Invite(ctx context.Context, email, role, inviter string) (*domain.Invitation, error) {
+	if _, err := i.usersRepo.FindOneByEmail(ctx, email); err == nil {
+		return nil, ErrAlreadyRegistered
+	} else if !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, fmt.Errorf("find user by email: %w", err)
+	}
+
+	if role != "" && inviter != CLIInviter {
+		inviterUser, err := i.usersRepo.FindOne(ctx, inviter)
+		if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+			return nil, fmt.Errorf("find inviter: %w", err)
+		}
+
+		if err != nil || inviterUser.Role != role {
+			return nil, ErrRoleNotAllowed
+		}
+	}
+
+	invitationToken, err := token.NewSecret()
+	if err != nil {
+		return nil, fmt.Errorf("invitation token: %w", err)
+	}
+
+	invitation, err := i.invitationsRepo.Create(ctx, &domain.Invitation{ //nolint:exhaustruct
+		Email:     email,
+		Role:      role,
+		Inviter:   inviter,
+		TokenHash: token.Hash(invitationToken),
+		Expires:   time.Now().Add(i.invitationExp),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create invitation: %w", err)
+	}
+
+	message := fmt.Sprintf("%s/%s/%s", i.externalURL, i.frontendInvitationPath, invitationToken)
+
+	if err := i.sender.Send(email, "Invitation", message); err != nil {
+		return nil, fmt.Errorf("send: %w", err)
+	}
+
+	return invitation, nil
+}
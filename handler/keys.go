@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/lax"
+)
+
+// KeysHandler contains signing keys related http handlers.
+type KeysHandler struct {
+	keyring *token.Keyring
+	log     lax.Logger
+}
+
+// NewKeysHandler creates keys handler.
+func NewKeysHandler(keyring *token.Keyring, log lax.Logger) *KeysHandler {
+	return &KeysHandler{
+		keyring: keyring,
+		log:     log,
+	}
+}
+
+// Rotate generates new signing key and retires the current one.
+//
+// @Tags admin
+// @Produce json
+// @Router /admin/keys/rotate [post]
+// @Security BearerAuth
+// @Success 201 {object} response.Key
+// @Failure 401
+// @Failure 403
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Rotate signing key.
+func (h *KeysHandler) Rotate(res http.ResponseWriter, req *http.Request) {
+	key, err := h.keyring.Rotate()
+	if err != nil {
+		if errors.Is(err, token.ErrRotationUnsupported) {
+			response.RenderErrorStatus(res, http.StatusConflict, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("rotate key", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	h.log.Info("signing key rotated", lax.String("kid", key.ID()))
+
+	response.Render(res, http.StatusCreated, &response.Key{ID: key.ID(), Algorithm: key.Algorithm()}, h.log)
+}
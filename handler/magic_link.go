@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// RequestMagicLink sends single-use login link to the email address of the active user. Previously sent link is
+// invalidated, but new link is not sent within a minute from the previous one. Response is the same whether such
+// user exists or not.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/magic-link [post]
+// @Param email body request.Email true "E-mail address"
+// @Success 202
+// @Failure 400 {object} response.Error
+// @Failure 500
+// @Summary Request magic login link.
+func (h *UsersHandler) RequestMagicLink(res http.ResponseWriter, req *http.Request) {
+	email, err := request.EmailFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOneByEmail(req.Context(), email.Email)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.Render(res, http.StatusAccepted, nil, h.log)
+
+			return
+		}
+
+		h.log.Warn("find user by email", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !domainUser.IsActive() {
+		response.Render(res, http.StatusAccepted, nil, h.log)
+
+		return
+	}
+
+	loginToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("magic link token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.magicLinksRepo.Create(req.Context(), &domain.MagicLink{
+		UserID:    domainUser.ID,
+		TokenHash: token.Hash(loginToken),
+		Expires:   time.Now().Add(h.magicLinkExp),
+		Created:   nil,
+	}, time.Now().Add(-magicLinkResendInterval)); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.Render(res, http.StatusAccepted, nil, h.log)
+
+			return
+		}
+
+		h.log.Warn("create magic link", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message := fmt.Sprintf("%s/%s/%s", h.externalURL, h.frontendMagicLinkPath, loginToken)
+
+	if err := h.sender.Send(domainUser.Email, "Login link", message); err != nil {
+		h.log.Warn("send magic link", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusAccepted, nil, h.log)
+}
+
+// LoginMagicLink exchanges token from the magic link for tokens. Users with second factor enabled receive MFA
+// challenge instead, as by password login.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/magic-link/verify [post]
+// @Param magicLink body request.MagicLink true "Magic link token"
+// @Success 200 {object} response.User
+// @Success 202 {object} response.MFAChallenge
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary Login by magic link.
+func (h *UsersHandler) LoginMagicLink(res http.ResponseWriter, req *http.Request) {
+	magicLink, err := request.MagicLinkFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainMagicLink, err := h.magicLinksRepo.Consume(req.Context(), token.Hash(magicLink.Token))
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid or expired link", h.log)
+
+			return
+		}
+
+		h.log.Warn("consume magic link", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), domainMagicLink.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid or expired link", h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !domainUser.IsActive() {
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "account not activated", h.log)
+
+		return
+	}
+
+	if domainUser.Status.IsBlocked(time.Now()) {
+		response.RenderErrorStatus(res, http.StatusForbidden, blockedMessage(&domainUser.Status), h.log)
+
+		return
+	}
+
+	h.continueLogin(res, req, domainUser)
+}
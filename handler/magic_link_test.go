@@ -0,0 +1,218 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/arc/send"
+)
+
+func TestMagicLink(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}                       //nolint:exhaustruct
+	magicLinksRepo := &magicLinksRepositoryFake{} //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{}           //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo:      usersRepo,
+		magicLinksRepo: magicLinksRepo,
+		sender:         sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login/magic-link", usersHandler.RequestMagicLink)
+	mux.Post("/users/login/magic-link/verify", usersHandler.LoginMagicLink)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link", "", `{"email":"unknown@sixpack.com"}`,
+		http.StatusAccepted, nil)
+
+	if _, ok := sender.message("unknown@sixpack.com"); ok {
+		t.Error("RequestMagicLink() sent link to unknown user")
+	}
+
+	requestLink := func() string {
+		doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link", "", `{"email":"john.doe@sixpack.com"}`,
+			http.StatusAccepted, nil)
+
+		message, ok := sender.message("john.doe@sixpack.com")
+		if !ok || !strings.HasPrefix(message, "http://localhost:3000/frontend-magic-link-path/") {
+			t.Fatalf("RequestMagicLink() = message %q; want magic link", message)
+		}
+
+		return message[strings.LastIndex(message, "/")+1:]
+	}
+
+	verifyBody := func(loginToken string) string {
+		return fmt.Sprintf(`{"token":%q}`, loginToken)
+	}
+
+	oldToken := requestLink()
+
+	if throttledToken := requestLink(); throttledToken != oldToken {
+		t.Error("RequestMagicLink() sent new link within resend interval")
+	}
+
+	magicLinksRepo.allowResend()
+
+	newToken := requestLink()
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", verifyBody(oldToken),
+		http.StatusUnauthorized, nil)
+
+	var user response.User
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", verifyBody(newToken), http.StatusOK,
+		&user)
+
+	if user.AuthToken == "" || user.RefreshToken == "" {
+		t.Errorf("LoginMagicLink() = %+v; want tokens", user)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", verifyBody(newToken),
+		http.StatusUnauthorized, nil)
+
+	expiredToken := requestLink()
+	magicLinksRepo.expire()
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", verifyBody(expiredToken),
+		http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", `{}`, http.StatusBadRequest, nil)
+
+	magicLinksRepo.allowResend()
+
+	blockedToken := requestLink()
+
+	if _, err := usersRepo.SetStatus(context.Background(), "john", &domain.UserStatus{ //nolint:exhaustruct
+		Status: domain.StatusBanned,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/magic-link/verify", "", verifyBody(blockedToken),
+		http.StatusForbidden, nil)
+}
+
+var _ send.Sender = (*senderFake)(nil)
+
+var errSenderFake = errors.New("sender unavailable")
+
+// senderFake records the last message sent to each recipient, unless it is failing.
+type senderFake struct {
+	mu       sync.Mutex
+	messages map[string]string
+	failing  bool
+}
+
+func (s *senderFake) Send(recipient, _, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failing {
+		return errSenderFake
+	}
+
+	if s.messages == nil {
+		s.messages = map[string]string{}
+	}
+
+	s.messages[recipient] = message
+
+	return nil
+}
+
+func (s *senderFake) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failing = true
+}
+
+func (s *senderFake) message(recipient string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	message, ok := s.messages[recipient]
+
+	return message, ok
+}
+
+var _ repository.MagicLinks = (*magicLinksRepositoryFake)(nil)
+
+type magicLinksRepositoryFake struct {
+	mu         sync.Mutex
+	magicLinks map[string]*domain.MagicLink
+}
+
+func (repo *magicLinksRepositoryFake) Create(ctx context.Context, magicLink *domain.MagicLink,
+	createdBefore time.Time,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.magicLinks == nil {
+		repo.magicLinks = map[string]*domain.MagicLink{}
+	}
+
+	if previous, ok := repo.magicLinks[magicLink.UserID]; ok && !previous.Created.Before(createdBefore) {
+		return repository.ErrResourceNotFound
+	}
+
+	created := time.Now()
+	magicLink.Created = &created
+	repo.magicLinks[magicLink.UserID] = magicLink
+
+	return nil
+}
+
+func (repo *magicLinksRepositoryFake) Consume(ctx context.Context, tokenHash []byte) (*domain.MagicLink, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for userID, magicLink := range repo.magicLinks {
+		if bytes.Equal(magicLink.TokenHash, tokenHash) && time.Now().Before(magicLink.Expires) {
+			delete(repo.magicLinks, userID)
+
+			return magicLink, nil
+		}
+	}
+
+	return nil, repository.ErrResourceNotFound
+}
+
+// allowResend moves creation of all links before the resend interval.
+func (repo *magicLinksRepositoryFake) allowResend() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	created := time.Now().Add(-time.Hour)
+
+	for _, magicLink := range repo.magicLinks {
+		magicLink.Created = &created
+	}
+}
+
+func (repo *magicLinksRepositoryFake) expire() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, magicLink := range repo.magicLinks {
+		magicLink.Expires = time.Now().Add(-time.Second)
+	}
+}
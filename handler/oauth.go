@@ -0,0 +1,763 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+const tokenTypeBearer = "Bearer"
+
+// OAuthHandler contains OAuth 2.0 related http handlers.
+type OAuthHandler struct {
+	clientsRepo            repository.Clients
+	authorizationCodesRepo repository.AuthorizationCodes
+	consentsRepo           repository.Consents
+	usersRepo              repository.Users
+	refreshTokensRepo      repository.RefreshTokens
+	revocationsRepo        repository.Revocations
+	sessionsRepo           repository.Sessions
+	jwt                    *token.JWT
+	codeExp                time.Duration
+	log                    lax.Logger
+}
+
+// NewOAuthHandler creates OAuth handler.
+func NewOAuthHandler(cr repository.Clients, acr repository.AuthorizationCodes, cor repository.Consents,
+	ur repository.Users, rtr repository.RefreshTokens, rr repository.Revocations, sr repository.Sessions,
+	jwt *token.JWT, codeExp time.Duration, log lax.Logger,
+) *OAuthHandler {
+	return &OAuthHandler{
+		clientsRepo:            cr,
+		authorizationCodesRepo: acr,
+		consentsRepo:           cor,
+		usersRepo:              ur,
+		refreshTokensRepo:      rtr,
+		revocationsRepo:        rr,
+		sessionsRepo:           sr,
+		jwt:                    jwt,
+		codeExp:                codeExp,
+		log:                    log,
+	}
+}
+
+// Authorization validates authorization request of the authenticated user and tells if consent is required.
+// Frontend authorization page should call it with the query it was opened with. Tokens issued to OAuth clients are
+// not accepted, so that clients cannot authorize each other.
+//
+// @Tags oauth
+// @Produce json
+// @Router /oauth/authorize [get]
+// @Security BearerAuth
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Redirect URI"
+// @Param response_type query string true "Response type" Enums(code)
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "PKCE code challenge method" Enums(S256)
+// @Param scope query string false "Space delimited scopes"
+// @Param state query string false "State"
+// @Param nonce query string false "Nonce"
+// @Success 200 {object} response.Authorization
+// @Failure 400 {object} response.OAuthError
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary Validate authorization request.
+func (h *OAuthHandler) Authorization(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	authorization, client, err := h.authorization(req.Context(), req.URL.Query())
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	scopes := token.ParseScope(authorization.Scope)
+
+	consentRequired, err := h.isConsentRequired(req.Context(), claims.Subject, client, scopes)
+	if err != nil {
+		h.log.Warn("is consent required", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, &response.Authorization{
+		ClientID:        client.ID,
+		ClientName:      client.Name,
+		Scopes:          scopes,
+		ConsentRequired: consentRequired,
+	}, h.log)
+}
+
+// Authorize issues authorization code to the client on behalf of the authenticated user. Consent is recorded
+// if given, otherwise previously given consent is required unless the client is trusted. Returned redirect uri
+// contains either authorization code or access_denied error.
+//
+// @Tags oauth
+// @Accept json
+// @Produce json
+// @Router /oauth/authorize [post]
+// @Security BearerAuth
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Redirect URI"
+// @Param response_type query string true "Response type" Enums(code)
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "PKCE code challenge method" Enums(S256)
+// @Param scope query string false "Space delimited scopes"
+// @Param state query string false "State"
+// @Param nonce query string false "Nonce"
+// @Param consent body request.Consent true "Consent"
+// @Success 200 {object} response.Redirect
+// @Failure 400 {object} response.OAuthError
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary Authorize client.
+func (h *OAuthHandler) Authorize(res http.ResponseWriter, req *http.Request) { //nolint:funlen,cyclop
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	authorization, client, err := h.authorization(req.Context(), req.URL.Query())
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	consent, err := request.ConsentFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	scopes := token.ParseScope(authorization.Scope)
+
+	if consent.Consent {
+		if err := h.consentsRepo.Grant(req.Context(), claims.Subject, client.ID, scopes); err != nil {
+			h.log.Warn("grant consent", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+	} else {
+		consentRequired, err := h.isConsentRequired(req.Context(), claims.Subject, client, scopes)
+		if err != nil {
+			h.log.Warn("is consent required", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		if consentRequired {
+			response.Render(res, http.StatusOK, &response.Redirect{
+				RedirectURI: redirectURI(authorization.RedirectURI, url.Values{
+					"error": {request.AccessDenied},
+					"state": {authorization.State},
+				}),
+			}, h.log)
+
+			return
+		}
+	}
+
+	code, err := h.createAuthorizationCode(req.Context(), claims, client, authorization)
+	if err != nil {
+		h.log.Warn("create authorization code", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, &response.Redirect{
+		RedirectURI: redirectURI(authorization.RedirectURI, url.Values{
+			"code":  {code},
+			"state": {authorization.State},
+		}),
+	}, h.log)
+}
+
+// Token exchanges authorization code, refresh token or client credentials for tokens. Confidential clients
+// authenticate using basic authorization header or client_secret form parameter.
+//
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Router /oauth/token [post]
+// @Param grant_type formData string true "Grant type" Enums(authorization_code, refresh_token, client_credentials)
+// @Param client_id formData string false "Client ID, required unless basic authorization is used"
+// @Param client_secret formData string false "Client secret of confidential client"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space delimited scopes requested by client credentials grant"
+// @Success 200 {object} response.OAuthTokens
+// @Failure 400 {object} response.OAuthError
+// @Failure 401 {object} response.OAuthError
+// @Failure 500
+// @Summary Issue tokens.
+func (h *OAuthHandler) Token(res http.ResponseWriter, req *http.Request) {
+	tokenRequest, err := request.TokenFromRequest(req)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	client, err := h.authenticateClient(req.Context(), tokenRequest.ClientID, tokenRequest.ClientSecret)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	var tokens *response.OAuthTokens
+
+	switch tokenRequest.GrantType {
+	case request.GrantTypeAuthorizationCode:
+		tokens, err = h.exchangeAuthorizationCode(req.Context(), client, tokenRequest)
+	case request.GrantTypeRefreshToken:
+		tokens, err = h.refreshTokens(req.Context(), client, tokenRequest)
+	case request.GrantTypeClientCredentials:
+		tokens, err = h.clientCredentials(client, tokenRequest)
+	}
+
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	response.Render(res, http.StatusOK, tokens, h.log)
+}
+
+// Introspect tells if the token is active and returns its claims as described by RFC 7662. Just confidential
+// clients, like API gateways, may introspect tokens.
+//
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Router /oauth/introspect [post]
+// @Param token formData string true "Token"
+// @Param token_type_hint formData string false "Token type hint" Enums(access_token, refresh_token)
+// @Param client_id formData string false "Client ID, required unless basic authorization is used"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} response.Introspection
+// @Failure 400 {object} response.OAuthError
+// @Failure 401 {object} response.OAuthError
+// @Failure 500
+// @Summary Introspect token.
+func (h *OAuthHandler) Introspect(res http.ResponseWriter, req *http.Request) {
+	tokenReference, err := request.TokenReferenceFromRequest(req)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	client, err := h.authenticateClient(req.Context(), tokenReference.ClientID, tokenReference.ClientSecret)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	if !client.IsConfidential() {
+		response.RenderOAuthErrorStatus(res, http.StatusUnauthorized, request.InvalidClient,
+			"introspection requires confidential client", h.log)
+
+		return
+	}
+
+	introspection, err := h.introspect(req.Context(), tokenReference.Token)
+	if err != nil {
+		h.log.Warn("introspect", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	res.Header().Set("Cache-Control", "no-store")
+	response.Render(res, http.StatusOK, introspection, h.log)
+}
+
+// Revoke revokes auth token or refresh token family as described by RFC 7009. Token must have been issued to the
+// client. Invalid and expired tokens are ignored.
+//
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Router /oauth/revoke [post]
+// @Param token formData string true "Token"
+// @Param token_type_hint formData string false "Token type hint" Enums(access_token, refresh_token)
+// @Param client_id formData string false "Client ID, required unless basic authorization is used"
+// @Param client_secret formData string false "Client secret of confidential client"
+// @Success 200
+// @Failure 400 {object} response.OAuthError
+// @Failure 401 {object} response.OAuthError
+// @Failure 500
+// @Summary Revoke token.
+func (h *OAuthHandler) Revoke(res http.ResponseWriter, req *http.Request) { //nolint:cyclop
+	tokenReference, err := request.TokenReferenceFromRequest(req)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	client, err := h.authenticateClient(req.Context(), tokenReference.ClientID, tokenReference.ClientSecret)
+	if err != nil {
+		response.RenderOAuthError(res, err, h.log)
+
+		return
+	}
+
+	jwtToken, err := h.jwt.VerifySignature(tokenReference.Token)
+	if err != nil {
+		response.Render(res, http.StatusOK, nil, h.log)
+
+		return
+	}
+
+	claims, err := token.ClaimsFromToken(jwtToken)
+	if err != nil || claims.Expires.Before(time.Now()) {
+		response.Render(res, http.StatusOK, nil, h.log)
+
+		return
+	}
+
+	if claims.ClientID != client.ID {
+		response.RenderOAuthErrorStatus(res, http.StatusBadRequest, request.UnauthorizedClient,
+			"token issued to another client", h.log)
+
+		return
+	}
+
+	switch claims.Type {
+	case token.TypeAuth:
+		err = h.revocationsRepo.Revoke(req.Context(), claims.ID, claims.Subject, claims.Expires)
+	case token.TypeRefresh:
+		err = h.refreshTokensRepo.Revoke(req.Context(), claims.SessionID)
+	default:
+		response.RenderOAuthErrorStatus(res, http.StatusBadRequest, request.UnsupportedTokenType,
+			"unsupported token type", h.log)
+
+		return
+	}
+
+	if err != nil {
+		h.log.Warn("revoke", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, nil, h.log)
+}
+
+// authorization parses authorization request and checks if the client exists and redirect uri is registered.
+func (h *OAuthHandler) authorization(ctx context.Context,
+	query url.Values,
+) (*request.Authorization, *domain.Client, error) {
+	authorization, err := request.AuthorizationFromQuery(query)
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck
+	}
+
+	client, err := h.clientsRepo.FindOne(ctx, authorization.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return nil, nil, request.NewOAuthError(request.InvalidRequest, "unknown client")
+		}
+
+		h.log.Warn("find client", lax.Error(err))
+
+		return nil, nil, request.NewInternalServerError()
+	}
+
+	if !client.HasRedirectURI(authorization.RedirectURI) {
+		return nil, nil, request.NewOAuthError(request.InvalidRequest, "redirect uri not registered")
+	}
+
+	for _, scope := range token.ParseScope(authorization.Scope) {
+		if !isSupportedScope(scope) && !client.HasScope(scope) {
+			return nil, nil, request.NewOAuthError(request.InvalidScope, "scope not allowed "+scope)
+		}
+	}
+
+	return authorization, client, nil
+}
+
+// authenticateClient finds the client and verifies secret of confidential client. Public clients must not provide
+// secret.
+func (h *OAuthHandler) authenticateClient(ctx context.Context, clientID,
+	clientSecret string,
+) (*domain.Client, error) {
+	client, err := h.clientsRepo.FindOne(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return nil, request.NewOAuthError(request.InvalidClient, "unknown client")
+		}
+
+		h.log.Warn("find client", lax.Error(err))
+
+		return nil, request.NewInternalServerError()
+	}
+
+	if client.IsConfidential() {
+		if !client.IsValidSecret(clientSecret) {
+			return nil, request.NewOAuthError(request.InvalidClient, "invalid client secret")
+		}
+	} else if clientSecret != "" {
+		return nil, request.NewOAuthError(request.InvalidClient, "public client must not use secret")
+	}
+
+	return client, nil
+}
+
+func (h *OAuthHandler) isConsentRequired(ctx context.Context, userID string, client *domain.Client,
+	scopes []string,
+) (bool, error) {
+	if client.Trusted {
+		return false, nil
+	}
+
+	granted, err := h.consentsRepo.IsGranted(ctx, userID, client.ID, scopes)
+	if err != nil {
+		return false, fmt.Errorf("is consent granted: %w", err)
+	}
+
+	return !granted, nil
+}
+
+// createAuthorizationCode stores hash of the new authorization code and returns the code. Issue time of the auth
+// token is used as authentication time.
+func (h *OAuthHandler) createAuthorizationCode(ctx context.Context, claims *token.Claims, client *domain.Client,
+	authorization *request.Authorization,
+) (string, error) {
+	code, err := token.NewSecret()
+	if err != nil {
+		return "", fmt.Errorf("authorization code: %w", err)
+	}
+
+	familyID, err := token.NewID()
+	if err != nil {
+		return "", fmt.Errorf("refresh token family id: %w", err)
+	}
+
+	expires := time.Now().Add(h.codeExp)
+
+	if err := h.authorizationCodesRepo.Create(ctx, token.Hash(code), &domain.AuthorizationCode{ //nolint:exhaustruct
+		ClientID:      client.ID,
+		UserID:        claims.Subject,
+		FamilyID:      familyID,
+		RedirectURI:   authorization.RedirectURI,
+		Scope:         authorization.Scope,
+		CodeChallenge: authorization.CodeChallenge,
+		Nonce:         authorization.Nonce,
+		AuthTime:      &claims.IssuedAt,
+		Expires:       &expires,
+	}); err != nil {
+		return "", fmt.Errorf("store authorization code: %w", err)
+	}
+
+	return code, nil
+}
+
+// exchangeAuthorizationCode verifies authorization code and PKCE code verifier and starts new session. ID token is
+// issued if openid scope has been granted.
+func (h *OAuthHandler) exchangeAuthorizationCode(ctx context.Context, client *domain.Client,
+	tokenRequest *request.Token,
+) (*response.OAuthTokens, error) {
+	code, err := h.authorizationCodesRepo.Exchange(ctx, token.Hash(tokenRequest.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			return nil, request.NewOAuthError(request.InvalidGrant, "invalid authorization code")
+		case errors.Is(err, repository.ErrTokenReused):
+			h.log.Warn("authorization code reuse detected, family revoked", lax.String("client", client.ID))
+
+			return nil, request.NewOAuthError(request.InvalidGrant, "authorization code reused")
+		default:
+			h.log.Warn("exchange authorization code", lax.Error(err))
+
+			return nil, request.NewInternalServerError()
+		}
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != tokenRequest.RedirectURI {
+		return nil, request.NewOAuthError(request.InvalidGrant, "authorization code issued to another client")
+	}
+
+	if !code.IsValidVerifier(tokenRequest.CodeVerifier) {
+		return nil, request.NewOAuthError(request.InvalidGrant, "invalid code verifier")
+	}
+
+	if err := h.checkUser(ctx, code.UserID); err != nil {
+		return nil, err
+	}
+
+	tokens := h.newTokens(code.Scope)
+
+	if tokens.AccessToken, tokens.RefreshToken, err = startSession(ctx, h.jwt, h.refreshTokensRepo, code.UserID,
+		code.FamilyID, &token.Grant{ClientID: client.ID, Scope: code.Scope}); err != nil {
+		h.log.Warn("start session", lax.Error(err))
+
+		return nil, request.NewInternalServerError()
+	}
+
+	if token.HasScope(code.Scope, token.ScopeOpenID) {
+		if tokens.IDToken, err = h.idToken(ctx, code); err != nil {
+			h.log.Warn("id token", lax.Error(err))
+
+			return nil, request.NewInternalServerError()
+		}
+	}
+
+	return tokens, nil
+}
+
+// refreshTokens rotates refresh token issued to the client.
+func (h *OAuthHandler) refreshTokens(ctx context.Context, client *domain.Client,
+	tokenRequest *request.Token,
+) (*response.OAuthTokens, error) {
+	claims, err := h.jwt.ParseRefreshToken(tokenRequest.RefreshToken)
+	if err != nil {
+		h.log.Warn("parse refresh token", lax.Error(err))
+
+		return nil, request.NewOAuthError(request.InvalidGrant, "invalid refresh token")
+	}
+
+	if claims.ClientID != client.ID {
+		return nil, request.NewOAuthError(request.InvalidGrant, "refresh token issued to another client")
+	}
+
+	if err := h.checkUser(ctx, claims.Subject); err != nil {
+		return nil, err
+	}
+
+	tokens := h.newTokens(claims.Scope)
+
+	if tokens.AccessToken, tokens.RefreshToken, err = rotateTokens(ctx, h.jwt, h.refreshTokensRepo,
+		tokenRequest.RefreshToken, claims); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			return nil, request.NewOAuthError(request.InvalidGrant, "invalid refresh token")
+		case errors.Is(err, repository.ErrTokenReused):
+			h.log.Warn("refresh token reuse detected, family revoked", lax.String("user", claims.Subject))
+
+			return nil, request.NewOAuthError(request.InvalidGrant, "refresh token reused")
+		case errors.Is(err, repository.ErrTokenRevoked):
+			return nil, request.NewOAuthError(request.InvalidGrant, "refresh token revoked")
+		default:
+			h.log.Warn("rotate tokens", lax.Error(err))
+
+			return nil, request.NewInternalServerError()
+		}
+	}
+
+	return tokens, nil
+}
+
+// clientCredentials issues auth token to the confidential client acting on its own behalf. If no scope is requested,
+// all scopes allowed to the client are granted.
+func (h *OAuthHandler) clientCredentials(client *domain.Client,
+	tokenRequest *request.Token,
+) (*response.OAuthTokens, error) {
+	if !client.IsConfidential() {
+		return nil, request.NewOAuthError(request.UnauthorizedClient, "public client cannot use client credentials")
+	}
+
+	scopes := token.ParseScope(tokenRequest.Scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	for _, scope := range scopes {
+		if !client.HasScope(scope) {
+			return nil, request.NewOAuthError(request.InvalidScope, "scope not allowed "+scope)
+		}
+	}
+
+	tokens := h.newTokens(strings.Join(scopes, " "))
+
+	var err error
+
+	if tokens.AccessToken, err = h.jwt.ClientToken(client.ID, tokens.Scope); err != nil {
+		h.log.Warn("client token", lax.Error(err))
+
+		return nil, request.NewInternalServerError()
+	}
+
+	return tokens, nil
+}
+
+// introspect verifies the token and checks if it has been revoked. ID tokens are never active because they are not
+// meant to be used for authorization.
+func (h *OAuthHandler) introspect(ctx context.Context, tokenString string) (*response.Introspection, error) {
+	inactive := &response.Introspection{Active: false} //nolint:exhaustruct
+
+	jwtToken, err := h.jwt.Verify(tokenString)
+	if err != nil {
+		return inactive, nil
+	}
+
+	claims, err := token.ClaimsFromToken(jwtToken)
+	if err != nil {
+		return inactive, nil //nolint:nilerr
+	}
+
+	switch claims.Type {
+	case token.TypeAuth:
+		revoked, err := h.revocationsRepo.IsRevoked(ctx, claims.ID, claims.Subject, claims.IssuedAt)
+		if err != nil {
+			return nil, fmt.Errorf("is revoked: %w", err)
+		}
+
+		if revoked {
+			return inactive, nil
+		}
+
+		if claims.SessionID != "" {
+			active, err := h.sessionsRepo.IsActive(ctx, claims.SessionID)
+			if err != nil {
+				return nil, fmt.Errorf("is session active: %w", err)
+			}
+
+			if !active {
+				return inactive, nil
+			}
+		}
+
+		return response.IntrospectionFromClaims(claims, h.jwt.Issuer(), request.TokenTypeHintAccessToken), nil
+	case token.TypeRefresh:
+		refreshToken, err := h.refreshTokensRepo.FindOne(ctx, token.Hash(tokenString))
+		if err != nil {
+			if errors.Is(err, repository.ErrResourceNotFound) {
+				return inactive, nil
+			}
+
+			return nil, fmt.Errorf("find refresh token: %w", err)
+		}
+
+		if !refreshToken.IsActive() {
+			return inactive, nil
+		}
+
+		return response.IntrospectionFromClaims(claims, h.jwt.Issuer(), request.TokenTypeHintRefreshToken), nil
+	default:
+		return inactive, nil
+	}
+}
+
+// checkUser refuses to issue tokens to deleted, suspended or banned user.
+func (h *OAuthHandler) checkUser(ctx context.Context, userID string) error {
+	domainUser, err := h.usersRepo.FindOne(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return request.NewOAuthError(request.InvalidGrant, "unknown user")
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+
+		return request.NewInternalServerError()
+	}
+
+	if domainUser.Status.IsBlocked(time.Now()) {
+		return request.NewOAuthError(request.InvalidGrant, blockedMessage(&domainUser.Status))
+	}
+
+	return nil
+}
+
+func (h *OAuthHandler) idToken(ctx context.Context, code *domain.AuthorizationCode) (string, error) {
+	idTokenClaims := &token.IDTokenClaims{ //nolint:exhaustruct
+		Subject:  code.UserID,
+		Audience: code.ClientID,
+		Nonce:    code.Nonce,
+		AuthTime: *code.AuthTime,
+	}
+
+	if token.HasScope(code.Scope, token.ScopeEmail) {
+		domainUser, err := h.usersRepo.FindOne(ctx, code.UserID)
+		if err != nil {
+			return "", fmt.Errorf("find user: %w", err)
+		}
+
+		idTokenClaims.Email = domainUser.Email
+		idTokenClaims.EmailVerified = domainUser.IsActive()
+	}
+
+	idToken, err := h.jwt.IDToken(idTokenClaims)
+	if err != nil {
+		return "", fmt.Errorf("generate id token: %w", err)
+	}
+
+	return idToken, nil
+}
+
+func (h *OAuthHandler) newTokens(scope string) *response.OAuthTokens {
+	return &response.OAuthTokens{ //nolint:exhaustruct
+		TokenType: tokenTypeBearer,
+		ExpiresIn: int64(h.jwt.AuthTokenExp().Seconds()),
+		Scope:     scope,
+	}
+}
+
+func isSupportedScope(scope string) bool {
+	for _, s := range token.SupportedScopes() {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redirectURI appends non empty parameters to the query of the redirect uri.
+func redirectURI(uri string, params url.Values) string {
+	redirect, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := redirect.Query()
+
+	for name, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(name, value)
+			}
+		}
+	}
+
+	redirect.RawQuery = query.Encode()
+
+	return redirect.String()
+}
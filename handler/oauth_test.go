@@ -0,0 +1,644 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	testClientID      = "client"
+	testRedirectURI   = "https://app.example.com/callback"
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testServiceID     = "service"
+	testServiceSecret = "secret"
+)
+
+func TestAuthorizationCodeFlow(t *testing.T) { //nolint:funlen,cyclop
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newOAuthServer(t, jwt, &usersRepositoryFake{}) //nolint:exhaustruct
+
+	authToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {"openid email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+
+	var authorization response.Authorization
+
+	doJSON(t, http.MethodGet, server.URL+"/oauth/authorize?"+query.Encode(), authToken, "", http.StatusOK,
+		&authorization)
+
+	if !authorization.ConsentRequired {
+		t.Error("consent not required for untrusted client without consent")
+	}
+
+	var redirect response.Redirect
+
+	doJSON(t, http.MethodPost, server.URL+"/oauth/authorize?"+query.Encode(), authToken, `{"consent":false}`,
+		http.StatusOK, &redirect)
+
+	if !strings.Contains(redirect.RedirectURI, "error=access_denied") {
+		t.Errorf("redirect uri = %q; want access_denied error", redirect.RedirectURI)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/oauth/authorize?"+query.Encode(), authToken, `{"consent":true}`,
+		http.StatusOK, &redirect)
+
+	redirectURI, err := url.Parse(redirect.RedirectURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := redirectURI.Query().Get("code")
+	if code == "" || redirectURI.Query().Get("state") != "xyz" {
+		t.Fatalf("redirect uri = %q; want code and state", redirect.RedirectURI)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/oauth/authorize?"+query.Encode(), authToken, "", http.StatusOK,
+		&authorization)
+
+	if authorization.ConsentRequired {
+		t.Error("consent required although already granted")
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {testClientID},
+		"code":          {code},
+		"redirect_uri":  {testRedirectURI},
+		"code_verifier": {testCodeVerifier},
+	}
+
+	var tokens response.OAuthTokens
+
+	postForm(t, server.URL+"/oauth/token", form, http.StatusOK, &tokens)
+
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.IDToken == "" ||
+		tokens.TokenType != "Bearer" || tokens.Scope != "openid email" {
+		t.Errorf("tokens = %+v; want access, refresh and id token", tokens)
+	}
+
+	idToken, err := jwt.Verify(tokens.IDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aud, nonce := idToken.Audience(), idToken.PrivateClaims()["nonce"]
+	if len(aud) != 1 || aud[0] != testClientID || nonce != "n-0S6_WzA2Mj" {
+		t.Errorf("id token aud = %v, nonce = %v; want client audience and nonce", aud, nonce)
+	}
+
+	var oauthErr response.OAuthError
+
+	postForm(t, server.URL+"/oauth/token", form, http.StatusBadRequest, &oauthErr)
+
+	if oauthErr.Error != "invalid_grant" || oauthErr.ErrorDescription != "authorization code reused" {
+		t.Errorf("reused code = %+v; want invalid_grant", oauthErr)
+	}
+}
+
+func TestToken(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newOAuthServer(t, jwt, &usersRepositoryFake{}) //nolint:exhaustruct
+
+	authToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+
+	newCode := func() string {
+		var redirect response.Redirect
+
+		doJSON(t, http.MethodPost, server.URL+"/oauth/authorize?"+query.Encode(), authToken, `{"consent":true}`,
+			http.StatusOK, &redirect)
+
+		redirectURI, err := url.Parse(redirect.RedirectURI)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return redirectURI.Query().Get("code")
+	}
+
+	tests := map[string]struct {
+		in         url.Values
+		wantStatus int
+		wantError  string
+	}{
+		"unsupported grant type": {
+			url.Values{"grant_type": {"password"}, "client_id": {testClientID}},
+			http.StatusBadRequest, "unsupported_grant_type",
+		},
+		"unknown client": {
+			url.Values{"grant_type": {"refresh_token"}, "client_id": {"unknown"}, "refresh_token": {"token"}},
+			http.StatusUnauthorized, "invalid_client",
+		},
+		"unknown code": {
+			url.Values{
+				"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {"unknown"},
+				"redirect_uri": {testRedirectURI}, "code_verifier": {testCodeVerifier},
+			},
+			http.StatusBadRequest, "invalid_grant",
+		},
+		"redirect uri mismatch": {
+			url.Values{
+				"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {newCode()},
+				"redirect_uri": {"https://evil.example.com"}, "code_verifier": {testCodeVerifier},
+			},
+			http.StatusBadRequest, "invalid_grant",
+		},
+		"invalid code verifier": {
+			url.Values{
+				"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {newCode()},
+				"redirect_uri": {testRedirectURI}, "code_verifier": {strings.Repeat("a", 43)},
+			},
+			http.StatusBadRequest, "invalid_grant",
+		},
+		"ok": {
+			url.Values{
+				"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {newCode()},
+				"redirect_uri": {testRedirectURI}, "code_verifier": {testCodeVerifier},
+			},
+			http.StatusOK, "",
+		},
+		"public client credentials": {
+			url.Values{"grant_type": {"client_credentials"}, "client_id": {testClientID}, "client_secret": {"x"}},
+			http.StatusUnauthorized, "invalid_client",
+		},
+		"invalid client secret": {
+			url.Values{"grant_type": {"client_credentials"}, "client_id": {testServiceID}, "client_secret": {"x"}},
+			http.StatusUnauthorized, "invalid_client",
+		},
+		"scope not allowed": {
+			url.Values{
+				"grant_type": {"client_credentials"}, "client_id": {testServiceID},
+				"client_secret": {testServiceSecret}, "scope": {"jobs:read admin"},
+			},
+			http.StatusBadRequest, "invalid_scope",
+		},
+		"client credentials": {
+			url.Values{
+				"grant_type": {"client_credentials"}, "client_id": {testServiceID},
+				"client_secret": {testServiceSecret}, "scope": {"jobs:read"},
+			},
+			http.StatusOK, "",
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			var oauthErr response.OAuthError
+
+			postForm(t, server.URL+"/oauth/token", test.in, test.wantStatus, &oauthErr)
+
+			if oauthErr.Error != test.wantError {
+				t.Errorf("error = %q; want %q", oauthErr.Error, test.wantError)
+			}
+		})
+	}
+}
+
+func TestTokenBlockedUser(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	// Refresh tokens are valid once auth tokens expire, so issued auth tokens expire immediately.
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+	server := newOAuthServer(t, newJWT(t, 0), usersRepo)
+
+	authToken, _, err := newJWT(t, time.Hour).Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {testClientID},
+		"redirect_uri":          {testRedirectURI},
+		"code_challenge":        {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+		"code_challenge_method": {"S256"},
+	}
+
+	codeForm := func() url.Values {
+		var redirect response.Redirect
+
+		doJSON(t, http.MethodPost, server.URL+"/oauth/authorize?"+query.Encode(), authToken, `{"consent":true}`,
+			http.StatusOK, &redirect)
+
+		redirectURI, err := url.Parse(redirect.RedirectURI)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return url.Values{
+			"grant_type": {"authorization_code"}, "client_id": {testClientID}, "code": {redirectURI.Query().Get("code")},
+			"redirect_uri": {testRedirectURI}, "code_verifier": {testCodeVerifier},
+		}
+	}
+
+	var tokens response.OAuthTokens
+
+	postForm(t, server.URL+"/oauth/token", codeForm(), http.StatusOK, &tokens)
+
+	form := codeForm()
+
+	if _, err := usersRepo.SetStatus(context.Background(), "john", &domain.UserStatus{ //nolint:exhaustruct
+		Status: domain.StatusBanned,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var oauthErr response.OAuthError
+
+	postForm(t, server.URL+"/oauth/token", form, http.StatusBadRequest, &oauthErr)
+
+	if oauthErr.Error != "invalid_grant" || oauthErr.ErrorDescription != "account banned" {
+		t.Errorf("exchange code = %+v; want invalid_grant of banned account", oauthErr)
+	}
+
+	oauthErr = response.OAuthError{} //nolint:exhaustruct
+
+	postForm(t, server.URL+"/oauth/token", url.Values{
+		"grant_type": {"refresh_token"}, "client_id": {testClientID}, "refresh_token": {tokens.RefreshToken},
+	}, http.StatusBadRequest, &oauthErr)
+
+	if oauthErr.Error != "invalid_grant" || oauthErr.ErrorDescription != "account banned" {
+		t.Errorf("refresh = %+v; want invalid_grant of banned account", oauthErr)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newOAuthServer(t, jwt, &usersRepositoryFake{}) //nolint:exhaustruct
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL+"/oauth/token",
+		strings.NewReader("grant_type=client_credentials"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(testServiceID, testServiceSecret)
+
+	var tokens response.OAuthTokens
+
+	do(t, req, http.StatusOK, &tokens)
+
+	if tokens.RefreshToken != "" || tokens.Scope != "jobs:read jobs:write" {
+		t.Errorf("tokens = %+v; want all allowed scopes and no refresh token", tokens)
+	}
+
+	authToken, err := jwt.Verify(tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authToken.Subject() != testServiceID {
+		t.Errorf("sub = %q; want %q", authToken.Subject(), testServiceID)
+	}
+}
+
+func TestIntrospectAndRevoke(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newOAuthServer(t, jwt, &usersRepositoryFake{}) //nolint:exhaustruct
+
+	serviceToken, err := jwt.ClientToken(testServiceID, "jobs:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userToken, _, err := jwt.Tokens("user", "session", &token.Grant{ClientID: testClientID, Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	credentials := url.Values{"client_id": {testServiceID}, "client_secret": {testServiceSecret}}
+
+	introspect := func(tokenString string) response.Introspection {
+		form := url.Values{"token": {tokenString}}
+		for name, values := range credentials {
+			form[name] = values
+		}
+
+		var introspection response.Introspection
+
+		postForm(t, server.URL+"/oauth/introspect", form, http.StatusOK, &introspection)
+
+		return introspection
+	}
+
+	if got := introspect(serviceToken); !got.Active || got.Subject != testServiceID || got.ClientID != testServiceID ||
+		got.Scope != "jobs:read" || got.TokenType != "access_token" {
+		t.Errorf("introspect(service token) = %+v; want active with claims", got)
+	}
+
+	if got := introspect(userToken); !got.Active || got.Subject != "user" || got.ClientID != testClientID {
+		t.Errorf("introspect(user token) = %+v; want active with claims", got)
+	}
+
+	if got := introspect("invalid"); got.Active || got.Subject != "" {
+		t.Errorf("introspect(invalid) = %+v; want inactive", got)
+	}
+
+	var oauthErr response.OAuthError
+
+	postForm(t, server.URL+"/oauth/introspect", url.Values{"token": {serviceToken}, "client_id": {testClientID}},
+		http.StatusUnauthorized, &oauthErr)
+
+	postForm(t, server.URL+"/oauth/revoke", url.Values{"token": {userToken}, "client_id": {testServiceID},
+		"client_secret": {testServiceSecret}}, http.StatusBadRequest, &oauthErr)
+
+	if oauthErr.Error != "unauthorized_client" {
+		t.Errorf("revoke token of another client = %+v; want unauthorized_client", oauthErr)
+	}
+
+	postForm(t, server.URL+"/oauth/revoke", url.Values{"token": {"invalid"}, "client_id": {testClientID}},
+		http.StatusOK, nil)
+	postForm(t, server.URL+"/oauth/revoke", url.Values{"token": {userToken}, "client_id": {testClientID}},
+		http.StatusOK, nil)
+
+	if got := introspect(userToken); got.Active {
+		t.Errorf("introspect(revoked token) = %+v; want inactive", got)
+	}
+}
+
+func newOAuthServer(t *testing.T, jwt *token.JWT, usersRepo *usersRepositoryFake) *httptest.Server {
+	t.Helper()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	oauthHandler := handler.NewOAuthHandler(
+		&clientsRepositoryFake{clients: map[string]*domain.Client{
+			testClientID: {ID: testClientID, Name: "App", RedirectURIs: []string{testRedirectURI}}, //nolint:exhaustruct
+			testServiceID: { //nolint:exhaustruct
+				ID: testServiceID, Name: "Service", SecretHash: token.Hash(testServiceSecret),
+				Scopes: []string{"jobs:read", "jobs:write"},
+			},
+		}},
+		&authorizationCodesRepositoryFake{codes: map[string]*domain.AuthorizationCode{}}, //nolint:exhaustruct
+		&consentsRepositoryFake{consents: map[string][]string{}},                         //nolint:exhaustruct
+		usersRepo,
+		&refreshTokensRepositoryFake{tokens: map[string]bool{}}, //nolint:exhaustruct
+		&revocationsRepositoryFake{},                            //nolint:exhaustruct
+		&sessionsRepositoryFake{},                               //nolint:exhaustruct
+		jwt, time.Minute, log)
+
+	mux := chi.NewRouter()
+	mux.Post("/oauth/token", oauthHandler.Token)
+	mux.Post("/oauth/introspect", oauthHandler.Introspect)
+	mux.Post("/oauth/revoke", oauthHandler.Revoke)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/oauth/authorize", oauthHandler.Authorization)
+		r.Post("/oauth/authorize", oauthHandler.Authorize)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func doJSON(t *testing.T, method, url, authToken, body string, wantStatus int, out interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+
+	do(t, req, wantStatus, out)
+}
+
+func doJSONStepUp(t *testing.T, method, url, authToken, stepUpToken, body string, wantStatus int, out interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+authToken)
+	req.Header.Set(mw.StepUpHeader, stepUpToken)
+
+	do(t, req, wantStatus, out)
+}
+
+func postForm(t *testing.T, url string, form url.Values, wantStatus int, out interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url,
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	do(t, req, wantStatus, out)
+}
+
+func do(t *testing.T, req *http.Request, wantStatus int, out interface{}) {
+	t.Helper()
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if res.StatusCode != wantStatus {
+		t.Fatalf("%s %s = status %d; want status %d", req.Method, req.URL.Path, res.StatusCode, wantStatus)
+	}
+
+	if out != nil && res.ContentLength != 0 {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var _ repository.Clients = (*clientsRepositoryFake)(nil)
+
+type clientsRepositoryFake struct {
+	clients map[string]*domain.Client
+}
+
+func (repo *clientsRepositoryFake) Create(ctx context.Context, client *domain.Client) (*domain.Client, error) {
+	panic("unimplemented")
+}
+
+func (repo *clientsRepositoryFake) FindOne(ctx context.Context, id string) (*domain.Client, error) {
+	client, ok := repo.clients[id]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	return client, nil
+}
+
+func (repo *clientsRepositoryFake) FindAll(ctx context.Context) ([]domain.Client, error) {
+	panic("unimplemented")
+}
+
+func (repo *clientsRepositoryFake) SetSecret(ctx context.Context, id string, secretHash []byte) error {
+	client, ok := repo.clients[id]
+	if !ok {
+		return repository.ErrResourceNotFound
+	}
+
+	client.SecretHash = secretHash
+
+	return nil
+}
+
+func (repo *clientsRepositoryFake) Delete(ctx context.Context, id string) error {
+	panic("unimplemented")
+}
+
+var _ repository.AuthorizationCodes = (*authorizationCodesRepositoryFake)(nil)
+
+type authorizationCodesRepositoryFake struct {
+	mu    sync.Mutex
+	codes map[string]*domain.AuthorizationCode
+}
+
+func (repo *authorizationCodesRepositoryFake) Create(ctx context.Context, codeHash []byte,
+	code *domain.AuthorizationCode,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.codes[string(codeHash)] = code
+
+	return nil
+}
+
+func (repo *authorizationCodesRepositoryFake) Exchange(ctx context.Context,
+	codeHash []byte,
+) (*domain.AuthorizationCode, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	code, ok := repo.codes[string(codeHash)]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	if code.Used != nil {
+		return nil, repository.ErrTokenReused
+	}
+
+	now := time.Now()
+	code.Used = &now
+
+	return code, nil
+}
+
+var _ repository.Consents = (*consentsRepositoryFake)(nil)
+
+type consentsRepositoryFake struct {
+	mu       sync.Mutex
+	consents map[string][]string
+}
+
+func (repo *consentsRepositoryFake) Grant(ctx context.Context, userID, clientID string, scopes []string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	repo.consents[userID+" "+clientID] = append(repo.consents[userID+" "+clientID], scopes...)
+
+	return nil
+}
+
+func (repo *consentsRepositoryFake) IsGranted(ctx context.Context, userID, clientID string,
+	scopes []string,
+) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	granted, ok := repo.consents[userID+" "+clientID]
+	if !ok {
+		return false, nil
+	}
+
+	for _, scope := range scopes {
+		if !token.HasScope(strings.Join(granted, " "), scope) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (repo *consentsRepositoryFake) FindAll(ctx context.Context, userID string) ([]domain.Consent, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	consents := []domain.Consent{}
+
+	for key, scopes := range repo.consents {
+		if strings.HasPrefix(key, userID+" ") {
+			consents = append(consents, domain.Consent{ //nolint:exhaustruct
+				UserID: userID, ClientID: strings.TrimPrefix(key, userID+" "), Scopes: scopes,
+			})
+		}
+	}
+
+	return consents, nil
+}
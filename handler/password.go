@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// ChangePassword sets new password of the current user once the current one is confirmed. Step-up token is
+// required as well, so stolen auth token and known password are not enough. All other sessions of the user are
+// revoked and user is notified per email.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/password [patch]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Param changePassword body request.ChangePassword true "Current and new password"
+// @Success 204
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Change password of the current user.
+func (h *UsersHandler) ChangePassword(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	changePassword, err := request.ChangePasswordFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if !domainUser.IsValidPassword(changePassword.CurrentPassword) {
+		response.RenderErrorStatus(res, http.StatusForbidden, "invalid current password", h.log)
+
+		return
+	}
+
+	if err := h.usersRepo.ChangePassword(req.Context(), claims.Subject, changePassword.HashedPassword); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("change password", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.revokeOtherSessions(req.Context(), claims.Subject, claims.SessionID); err != nil {
+		h.log.Warn("revoke other sessions", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	message := "Your password was changed. If you didn't change it, reset your password immediately."
+
+	if err := h.sender.Send(domainUser.Email, "Password changed", message); err != nil {
+		h.log.Warn("send password changed notification", lax.Error(err))
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// revokeOtherSessions revokes all refresh token families of the user except the one of the current session. Since
+// session is identified by its refresh token family, this revokes other sessions as well as tokens issued to OAuth
+// clients.
+func (h *UsersHandler) revokeOtherSessions(ctx context.Context, userID, currentSessionID string) error {
+	if err := h.refreshTokensRepo.RevokeAllExcept(ctx, userID, currentSessionID); err != nil {
+		return fmt.Errorf("revoke other refresh token families: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestChangePassword(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	sender := &senderFake{}                                                       //nolint:exhaustruct
+	revocationsRepo := &revocationsRepositoryFake{}                               //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{sessions: map[string]*domain.Session{ //nolint:exhaustruct
+		"current": {ID: "current", UserID: "john", UserAgent: "Firefox", IP: "192.0.2.1"}, //nolint:exhaustruct
+		"phone":   {ID: "phone", UserID: "john", UserAgent: "Safari", IP: "192.0.2.2"},    //nolint:exhaustruct
+		"foreign": {ID: "foreign", UserID: "jane", UserAgent: "Edge", IP: "192.0.2.3"},    //nolint:exhaustruct
+	}}
+
+	refreshTokensRepo := &refreshTokensRepositoryFake{ //nolint:exhaustruct
+		tokens:       map[string]bool{},
+		sessionsRepo: sessionsRepo,
+	}
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		refreshTokensRepo: refreshTokensRepo,
+		revocationsRepo:   revocationsRepo,
+		sessionsRepo:      sessionsRepo,
+		sender:            sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login", usersHandler.Login)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Get("/users/me", usersHandler.Profile)
+		r.With(mw.StepUp(jwt, log)).Patch("/users/me/password", usersHandler.ChangePassword)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authToken, _, err := jwt.Tokens("john", "current", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	phoneToken, _, err := jwt.Tokens("john", "phone", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	janeToken, _, err := jwt.Tokens("jane", "foreign", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	refreshTokenHashes := map[string][]byte{}
+
+	for familyID, userID := range map[string]string{"current": "john", "oauth": "john", "foreign-oauth": "jane"} {
+		refreshTokenHashes[familyID] = token.Hash(familyID + "-refresh-token")
+
+		if _, err := refreshTokensRepo.Create(ctx, familyID, userID, "", refreshTokenHashes[familyID],
+			time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stepUpToken, err := jwt.StepUpToken("john", "current")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newPassword := "correct-Horse-battery-staple-42"
+	changeBody := func(currentPassword, password string) string {
+		return fmt.Sprintf(`{"currentPassword":%q,"password":%q}`, currentPassword, password)
+	}
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/me/password", authToken, changeBody(testPassword, newPassword),
+		http.StatusForbidden, nil)
+	doJSONStepUp(t, http.MethodPatch, server.URL+"/users/me/password", authToken, stepUpToken,
+		changeBody(testPassword, "pass"), http.StatusBadRequest, nil)
+	doJSONStepUp(t, http.MethodPatch, server.URL+"/users/me/password", authToken, stepUpToken,
+		changeBody("wrong", newPassword), http.StatusForbidden, nil)
+
+	if _, ok := sender.message("john.doe@sixpack.com"); ok {
+		t.Fatal("notification sent; want none before password is changed")
+	}
+
+	doJSONStepUp(t, http.MethodPatch, server.URL+"/users/me/password", authToken, stepUpToken,
+		changeBody(testPassword, newPassword), http.StatusNoContent, nil)
+
+	if _, ok := sender.message("john.doe@sixpack.com"); !ok {
+		t.Error("no notification sent; want password changed notification")
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me", authToken, "", http.StatusOK, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me", phoneToken, "", http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me", janeToken, "", http.StatusOK, nil)
+
+	for familyID, wantErr := range map[string]error{
+		"current": nil, "oauth": repository.ErrTokenRevoked, "foreign-oauth": nil,
+	} {
+		if _, err := refreshTokensRepo.Rotate(ctx, refreshTokenHashes[familyID], token.Hash(familyID+"-rotated"),
+			time.Now().Add(time.Hour)); !errors.Is(err, wantErr) {
+			t.Errorf("Rotate(%s) = %v; want %v", familyID, err, wantErr)
+		}
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"),
+		http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "",
+		fmt.Sprintf(`{"email":"john.doe@sixpack.com","password":%q}`, newPassword), http.StatusOK, nil)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// Profile renders the current user.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me [get]
+// @Security BearerAuth
+// @Success 200 {object} response.User
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Get the current user.
+func (h *UsersHandler) Profile(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.FromDomainUser(domainUser), h.log)
+}
+
+// UpdateProfile updates display name, locale, timezone and metadata of the current user. Omitted fields are left
+// unchanged.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me [patch]
+// @Security BearerAuth
+// @Param profile body request.ProfileUpdate true "Profile fields"
+// @Success 200 {object} response.User
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Update the current user.
+func (h *UsersHandler) UpdateProfile(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	profileUpdate, err := request.ProfileUpdateFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	profile := domainUser.Profile
+	profileUpdate.Apply(&profile)
+
+	domainUser, err = h.usersRepo.UpdateProfile(req.Context(), claims.Subject, &profile)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("update profile", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.FromDomainUser(domainUser), h.log)
+}
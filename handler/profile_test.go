@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestProfile(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}       //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+	})
+
+	mux := chi.NewRouter()
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Use(mw.FirstParty(log))
+		r.Get("/users/me", usersHandler.Profile)
+		r.Patch("/users/me", usersHandler.UpdateProfile)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientToken, _, err := jwt.Tokens("john", "session", &token.Grant{ClientID: "client", Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var user response.User
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me", "", "", http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me", clientToken, "", http.StatusForbidden, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me", authToken, "", http.StatusOK, &user)
+
+	if user.ID != "john" || user.Email != "john.doe@sixpack.com" || user.DisplayName != "" {
+		t.Errorf("Profile() = %+v; want john without display name", user)
+	}
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/me", authToken, `{"timezone":"Mars/Olympus"}`,
+		http.StatusBadRequest, nil)
+	doJSON(t, http.MethodPatch, server.URL+"/users/me", authToken,
+		`{"displayName":"John Doe","locale":"en-us","timezone":"Europe/Berlin","metadata":{"theme":"dark"}}`,
+		http.StatusOK, nil)
+
+	user = response.User{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/me", authToken, `{"displayName":"Johnny"}`, http.StatusOK, &user)
+
+	if user.DisplayName != "Johnny" || user.Locale != "en-US" || user.Timezone != "Europe/Berlin" ||
+		string(user.Metadata) != `{"theme":"dark"}` {
+		t.Errorf("UpdateProfile() = %+v; want updated display name and other fields unchanged", user)
+	}
+
+	user = response.User{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/me", authToken, `{"locale":"","metadata":null}`, http.StatusOK,
+		nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me", authToken, "", http.StatusOK, &user)
+
+	if user.Locale != "" || string(user.Metadata) != `{}` {
+		t.Errorf("Profile() = %+v; want cleared locale and metadata", user)
+	}
+}
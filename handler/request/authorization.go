@@ -0,0 +1,86 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+
+	"go.ectobit.com/lax"
+)
+
+// CodeChallengeMethodS256 is the only supported PKCE code challenge method.
+const CodeChallengeMethodS256 = "S256"
+
+// ResponseTypeCode is the only supported OAuth response type.
+const ResponseTypeCode = "code"
+
+var codeChallengeRegex = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+
+// Authorization contains OAuth authorization request parameters.
+type Authorization struct {
+	ResponseType  string
+	ClientID      string
+	RedirectURI   string
+	Scope         string
+	State         string
+	CodeChallenge string
+	Nonce         string
+}
+
+// AuthorizationFromQuery parses OAuth authorization request from query parameters.
+func AuthorizationFromQuery(query url.Values) (*Authorization, error) {
+	authorization := &Authorization{
+		ResponseType:  query.Get("response_type"),
+		ClientID:      query.Get("client_id"),
+		RedirectURI:   query.Get("redirect_uri"),
+		Scope:         query.Get("scope"),
+		State:         query.Get("state"),
+		CodeChallenge: query.Get("code_challenge"),
+		Nonce:         query.Get("nonce"),
+	}
+
+	if authorization.ClientID == "" {
+		return nil, NewOAuthError(InvalidRequest, "empty client id")
+	}
+
+	if authorization.RedirectURI == "" {
+		return nil, NewOAuthError(InvalidRequest, "empty redirect uri")
+	}
+
+	if !isValidRedirectURI(authorization.RedirectURI) {
+		return nil, NewOAuthError(InvalidRequest, "invalid redirect uri")
+	}
+
+	if authorization.ResponseType != ResponseTypeCode {
+		return nil, NewOAuthError(UnsupportedResponseType, "unsupported response type")
+	}
+
+	if query.Get("code_challenge_method") != CodeChallengeMethodS256 {
+		return nil, NewOAuthError(InvalidRequest, "code challenge method must be S256")
+	}
+
+	if !codeChallengeRegex.MatchString(authorization.CodeChallenge) {
+		return nil, NewOAuthError(InvalidRequest, "invalid code challenge")
+	}
+
+	return authorization, nil
+}
+
+// Consent contains user's decision on OAuth authorization request.
+type Consent struct {
+	Consent bool `json:"consent"`
+}
+
+// ConsentFromJSON parses consent from request body.
+func ConsentFromJSON(body io.Reader, log lax.Logger) (*Consent, error) {
+	var consent Consent
+
+	if err := json.NewDecoder(body).Decode(&consent); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	return &consent, nil
+}
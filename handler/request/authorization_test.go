@@ -0,0 +1,70 @@
+package request_test
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+)
+
+func TestAuthorizationFromQuery(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	valid := "response_type=code&client_id=client&redirect_uri=https%3A%2F%2Fapp.example.com%2Fcallback" +
+		"&code_challenge=E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM&code_challenge_method=S256"
+
+	tests := map[string]struct {
+		in       string
+		wantCode string
+		wantErr  string
+	}{
+		"empty":                      {"", request.InvalidRequest, "empty client id"},
+		"empty redirect uri":         {"client_id=client", request.InvalidRequest, "empty redirect uri"},
+		"relative redirect uri":      {"client_id=client&redirect_uri=/callback", request.InvalidRequest, "invalid redirect uri"},                                                                                                                 //nolint:lll
+		"redirect uri with fragment": {"client_id=client&redirect_uri=https%3A%2F%2Fapp.example.com%23a", request.InvalidRequest, "invalid redirect uri"},                                                                                         //nolint:lll
+		"token response type":        {"client_id=client&redirect_uri=https%3A%2F%2Fapp.example.com&response_type=token", request.UnsupportedResponseType, "unsupported response type"},                                                           //nolint:lll
+		"plain challenge method":     {strings.Replace(valid, "S256", "plain", 1), request.InvalidRequest, "code challenge method must be S256"},                                                                                                  //nolint:lll
+		"missing challenge method":   {"response_type=code&client_id=client&redirect_uri=https%3A%2F%2Fapp.example.com&code_challenge=E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", request.InvalidRequest, "code challenge method must be S256"}, //nolint:lll
+		"invalid challenge":          {"response_type=code&client_id=client&redirect_uri=https%3A%2F%2Fapp.example.com&code_challenge=short&code_challenge_method=S256", request.InvalidRequest, "invalid code challenge"},                        //nolint:lll
+		"ok":                         {valid + "&scope=openid+email&state=xyz", "", ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := url.ParseQuery(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, gotErr := request.AuthorizationFromQuery(query)
+			if test.wantErr != "" {
+				oauthErr := &request.OAuthError{} //nolint:exhaustruct
+
+				if !errors.As(gotErr, &oauthErr) {
+					t.Fatalf("AuthorizationFromQuery(%q) = error %v; want oauth error", test.in, gotErr)
+				}
+
+				if oauthErr.Code != test.wantCode || oauthErr.Description != test.wantErr {
+					t.Fatalf("AuthorizationFromQuery(%q) = error %s %q; want error %s %q", test.in, oauthErr.Code,
+						oauthErr.Description, test.wantCode, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("AuthorizationFromQuery(%q) = error %v; want nil", test.in, gotErr)
+			}
+
+			if got.ClientID != "client" || got.RedirectURI != "https://app.example.com/callback" {
+				t.Errorf("AuthorizationFromQuery(%q) = %+v", test.in, got)
+			}
+		})
+	}
+}
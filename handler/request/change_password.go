@@ -0,0 +1,50 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/lax"
+)
+
+// ChangePassword contains user's current password and new password to be set.
+type ChangePassword struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+	HashedPassword  []byte `json:"-"`
+}
+
+// ChangePasswordFromJSON parses ChangePassword from request body.
+func ChangePasswordFromJSON(body io.Reader, log lax.Logger) (*ChangePassword, error) {
+	var changePassword ChangePassword
+
+	var err error
+
+	if err := json.NewDecoder(body).Decode(&changePassword); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if changePassword.CurrentPassword == "" {
+		return nil, NewBadRequestError("empty current password")
+	}
+
+	if changePassword.Password == "" {
+		return nil, NewBadRequestError("empty password")
+	}
+
+	if isWeakPassword(changePassword.Password) {
+		return nil, NewBadRequestError("weak password")
+	}
+
+	changePassword.HashedPassword, err = domain.HashPassword(changePassword.Password)
+	if err != nil {
+		log.Warn("hash password", lax.Error(err))
+
+		return nil, NewInternalServerError()
+	}
+
+	return &changePassword, nil
+}
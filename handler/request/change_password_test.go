@@ -0,0 +1,68 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestChangePasswordFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		want    *request.ChangePassword
+		wantErr string
+	}{
+		"invalid json body": {``, nil, "invalid json body"},
+		"empty body":        {`{}`, nil, "empty current password"},
+		"all empty":         {`{"currentPassword":"","password":""}`, nil, "empty current password"},
+		"empty password":    {`{"currentPassword":"test","password":""}`, nil, "empty password"},
+		"weak password":     {`{"currentPassword":"test","password":"pass"}`, nil, "weak password"},
+		"ok": {
+			`{"currentPassword":"test","password":"h+z67{GxLSL~]Cl(I88AqV7w"}`,
+			&request.ChangePassword{CurrentPassword: "test", Password: "h+z67{GxLSL~]Cl(I88AqV7w"}, "", //nolint:exhaustruct
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.ChangePasswordFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("ChangePasswordFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("ChangePasswordFromJSON(%q) = error %q; want error %q", test.in, test.wantErr, gotErr)
+				}
+
+				return
+			}
+
+			if got.CurrentPassword != test.want.CurrentPassword || got.Password != test.want.Password {
+				t.Errorf("ChangePasswordFromJSON(%q) = %v; want %v", test.in, got, test.want)
+			}
+
+			domainUser := &domain.User{ //nolint:exhaustruct
+				Password: got.HashedPassword,
+			}
+
+			if !domainUser.IsValidPassword(test.want.Password) {
+				t.Errorf("IsValidPassword(%q) = true; got false", test.want.Password)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"go.ectobit.com/lax"
+)
+
+// Client contains OAuth client registration data to receive.
+type Client struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+	Trusted      bool     `json:"trusted"`
+}
+
+// ClientFromJSON parses OAuth client registration data from request body. Redirect uris are required unless client
+// is confidential, because such client may use just client credentials grant.
+func ClientFromJSON(body io.Reader, log lax.Logger) (*Client, error) {
+	var client Client
+
+	if err := json.NewDecoder(body).Decode(&client); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if client.Name == "" {
+		return nil, NewBadRequestError("empty name")
+	}
+
+	if len(client.RedirectURIs) == 0 && !client.Confidential {
+		return nil, NewBadRequestError("empty redirect uris")
+	}
+
+	for _, redirectURI := range client.RedirectURIs {
+		if !isValidRedirectURI(redirectURI) {
+			return nil, NewBadRequestError("invalid redirect uri " + redirectURI)
+		}
+	}
+
+	for _, scope := range client.Scopes {
+		if scope == "" || strings.ContainsAny(scope, " \t\n\"\\") {
+			return nil, NewBadRequestError("invalid scope " + scope)
+		}
+	}
+
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+
+	if client.Scopes == nil {
+		client.Scopes = []string{}
+	}
+
+	return &client, nil
+}
+
+// isValidRedirectURI checks if redirect uri is absolute and without fragment, as required by OAuth 2.0.
+func isValidRedirectURI(redirectURI string) bool {
+	uri, err := url.Parse(redirectURI)
+
+	return err == nil && uri.IsAbs() && uri.Fragment == ""
+}
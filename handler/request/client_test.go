@@ -0,0 +1,60 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestClientFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body":     {``, "invalid json body"},
+		"empty body":            {`{}`, "empty name"},
+		"empty redirect uris":   {`{"name":"app","redirectUris":[]}`, "empty redirect uris"},
+		"relative redirect uri": {`{"name":"app","redirectUris":["/callback"]}`, "invalid redirect uri /callback"},
+		"invalid scope":         {`{"name":"app","confidential":true,"scopes":["jobs write"]}`, "invalid scope jobs write"},
+		"confidential":          {`{"name":"app","confidential":true,"scopes":["jobs:write"]}`, ""},
+		"ok":                    {`{"name":"app","redirectUris":["https://app.example.com/callback"]}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.ClientFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("ClientFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("ClientFromJSON(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("ClientFromJSON(%q) = error %v; want nil", test.in, gotErr)
+			}
+
+			if got.Name != "app" || got.RedirectURIs == nil || got.Scopes == nil {
+				t.Errorf("ClientFromJSON(%q) = %+v", test.in, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+
+	"go.ectobit.com/lax"
+)
+
+// EmailChange contains new email address of the user and the current password.
+type EmailChange struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// EmailChangeFromJSON parses EmailChange from request body.
+func EmailChangeFromJSON(body io.Reader, log lax.Logger) (*EmailChange, error) {
+	var emailChange EmailChange
+
+	if err := json.NewDecoder(body).Decode(&emailChange); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if emailChange.Email == "" {
+		return nil, NewBadRequestError("empty email")
+	}
+
+	if !isValidEmail(emailChange.Email) {
+		return nil, NewBadRequestError("invalid email")
+	}
+
+	if emailChange.Password == "" {
+		return nil, NewBadRequestError("empty password")
+	}
+
+	return &emailChange, nil
+}
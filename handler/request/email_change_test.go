@@ -0,0 +1,58 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestEmailChangeFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		want    *request.EmailChange
+		wantErr string
+	}{
+		"invalid json body": {``, nil, "invalid json body"},
+		"empty body":        {`{}`, nil, "empty email"},
+		"invalid email":     {`{"email":"a","password":"test"}`, nil, "invalid email"},
+		"empty password":    {`{"email":"john.doe@sixpack.com"}`, nil, "empty password"},
+		"ok": {
+			`{"email":"john.doe@sixpack.com","password":"test"}`,
+			&request.EmailChange{Email: "john.doe@sixpack.com", Password: "test"}, "",
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.EmailChangeFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("EmailChangeFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("EmailChangeFromJSON(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if *got != *test.want {
+				t.Errorf("EmailChangeFromJSON(%q) = %v; want %v", test.in, got, test.want)
+			}
+		})
+	}
+}
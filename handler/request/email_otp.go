@@ -0,0 +1,56 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+
+	"go.ectobit.com/lax"
+)
+
+// EmailOTPLogin contains MFA challenge token of the user requesting login code per email.
+type EmailOTPLogin struct {
+	MFAToken string `json:"mfaToken"`
+}
+
+// EmailOTPLoginFromJSON parses email OTP login request from request body.
+func EmailOTPLoginFromJSON(body io.Reader, log lax.Logger) (*EmailOTPLogin, error) {
+	var emailOTPLogin EmailOTPLogin
+
+	if err := json.NewDecoder(body).Decode(&emailOTPLogin); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if emailOTPLogin.MFAToken == "" {
+		return nil, NewBadRequestError("empty mfa token")
+	}
+
+	return &emailOTPLogin, nil
+}
+
+// StepUp contains code sent per email to re-authenticate the user.
+type StepUp struct {
+	Code string `json:"code"`
+}
+
+// StepUpFromJSON parses step-up request from request body.
+func StepUpFromJSON(body io.Reader, log lax.Logger) (*StepUp, error) {
+	var stepUp StepUp
+
+	if err := json.NewDecoder(body).Decode(&stepUp); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if stepUp.Code == "" {
+		return nil, NewBadRequestError("empty code")
+	}
+
+	if !codeRegex.MatchString(stepUp.Code) {
+		return nil, NewBadRequestError("invalid code")
+	}
+
+	return &stepUp, nil
+}
@@ -0,0 +1,83 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestEmailOTPLoginFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty body":        {`{}`, "empty mfa token"},
+		"ok":                {`{"mfaToken":"token"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.EmailOTPLoginFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("EmailOTPLoginFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("EmailOTPLoginFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestStepUpFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty body":        {`{}`, "empty code"},
+		"invalid code":      {`{"code":"1234567"}`, "invalid code"},
+		"ok":                {`{"code":"123456"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.StepUpFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("StepUpFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("StepUpFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
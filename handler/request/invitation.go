@@ -0,0 +1,82 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/lax"
+)
+
+const maxRoleLength = 64
+
+var roleRegexp = regexp.MustCompile(`^[A-Za-z0-9_.:-]*$`)
+
+// Invitation contains email address of the invitee and intended role. Empty role means no role.
+type Invitation struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// InvitationFromJSON parses Invitation from request body.
+func InvitationFromJSON(body io.Reader, log lax.Logger) (*Invitation, error) {
+	var invitation Invitation
+
+	if err := json.NewDecoder(body).Decode(&invitation); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if err := ValidateEmail(invitation.Email); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateRole(invitation.Role); err != nil {
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// ValidateRole checks if role is a valid Casbin subject. Empty role is valid.
+func ValidateRole(role string) error {
+	if len(role) > maxRoleLength || !roleRegexp.MatchString(role) {
+		return NewBadRequestError("invalid role")
+	}
+
+	return nil
+}
+
+// InvitationAcceptance contains password chosen by the invitee.
+type InvitationAcceptance struct {
+	Password       string `json:"password"`
+	HashedPassword []byte `json:"-"`
+}
+
+// InvitationAcceptanceFromJSON parses InvitationAcceptance from request body.
+func InvitationAcceptanceFromJSON(body io.Reader, log lax.Logger) (*InvitationAcceptance, error) {
+	var acceptance InvitationAcceptance
+
+	var err error
+
+	if err := json.NewDecoder(body).Decode(&acceptance); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if err := ValidatePassword(acceptance.Password); err != nil {
+		return nil, err
+	}
+
+	acceptance.HashedPassword, err = domain.HashPassword(acceptance.Password)
+	if err != nil {
+		log.Warn("hash password", lax.Error(err))
+
+		return nil, NewInternalServerError()
+	}
+
+	return &acceptance, nil
+}
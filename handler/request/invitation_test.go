@@ -0,0 +1,115 @@
+package request_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestInvitationFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		want    *request.Invitation
+		wantErr string
+	}{
+		"invalid json body": {``, nil, "invalid json body"},
+		"empty body":        {`{}`, nil, "empty email"},
+		"invalid email":     {`{"email":"john"}`, nil, "invalid email"},
+		"invalid role":      {`{"email":"john.doe@sixpack.com","role":"a b"}`, nil, "invalid role"},
+		"role too long": {
+			`{"email":"john.doe@sixpack.com","role":"` + strings.Repeat("a", 65) + `"}`, nil, "invalid role",
+		},
+		"no role": {`{"email":"john.doe@sixpack.com"}`, &request.Invitation{Email: "john.doe@sixpack.com", Role: ""}, ""},
+		"role": {
+			`{"email":"john.doe@sixpack.com","role":"owner"}`,
+			&request.Invitation{Email: "john.doe@sixpack.com", Role: "owner"}, "",
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.InvitationFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("InvitationFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("InvitationFromJSON(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("InvitationFromJSON(%q) = error %q; want no error", test.in, gotErr)
+			}
+
+			if *got != *test.want {
+				t.Errorf("InvitationFromJSON(%q) = %v; want %v", test.in, got, test.want)
+			}
+		})
+	}
+}
+
+func TestInvitationAcceptanceFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty body":        {`{}`, "empty password"},
+		"weak password":     {`{"password":"pass"}`, "weak password"},
+		"ok":                {`{"password":"h+z67{GxLSL~]Cl(I88AqV7w"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.InvitationAcceptanceFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("InvitationAcceptanceFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("InvitationAcceptanceFromJSON(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			domainUser := &domain.User{ //nolint:exhaustruct
+				Password: got.HashedPassword,
+			}
+
+			if !domainUser.IsValidPassword(got.Password) {
+				t.Errorf("InvitationAcceptanceFromJSON(%q) = invalid hashed password", test.in)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+
+	"go.ectobit.com/lax"
+)
+
+// MagicLink contains login token from the magic link.
+type MagicLink struct {
+	Token string `json:"token"`
+}
+
+// MagicLinkFromJSON parses magic link token from request body.
+func MagicLinkFromJSON(body io.Reader, log lax.Logger) (*MagicLink, error) {
+	var magicLink MagicLink
+
+	if err := json.NewDecoder(body).Decode(&magicLink); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if magicLink.Token == "" {
+		return nil, NewBadRequestError("empty token")
+	}
+
+	return &magicLink, nil
+}
@@ -0,0 +1,46 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestMagicLinkFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty body":        {`{}`, "empty token"},
+		"ok":                {`{"token":"token"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.MagicLinkFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("MagicLinkFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("MagicLinkFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+
+	"go.ectobit.com/lax"
+)
+
+var codeRegex = regexp.MustCompile(`^\d{6}$`)
+
+// TOTPCode contains code generated by authenticator app.
+type TOTPCode struct {
+	Code string `json:"code"`
+}
+
+// TOTPCodeFromJSON parses TOTP code from request body.
+func TOTPCodeFromJSON(body io.Reader, log lax.Logger) (*TOTPCode, error) {
+	var totpCode TOTPCode
+
+	if err := json.NewDecoder(body).Decode(&totpCode); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if totpCode.Code == "" {
+		return nil, NewBadRequestError("empty code")
+	}
+
+	if !codeRegex.MatchString(totpCode.Code) {
+		return nil, NewBadRequestError("invalid code")
+	}
+
+	return &totpCode, nil
+}
+
+// MFALogin contains MFA challenge token and just one of TOTP code, recovery code or code sent per email.
+type MFALogin struct {
+	MFAToken     string `json:"mfaToken"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recoveryCode,omitempty"`
+	EmailCode    string `json:"emailCode,omitempty"`
+}
+
+// MFALoginFromJSON parses MFA login from request body.
+func MFALoginFromJSON(body io.Reader, log lax.Logger) (*MFALogin, error) {
+	var mfaLogin MFALogin
+
+	if err := json.NewDecoder(body).Decode(&mfaLogin); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if mfaLogin.MFAToken == "" {
+		return nil, NewBadRequestError("empty mfa token")
+	}
+
+	codes := 0
+
+	for _, code := range []string{mfaLogin.Code, mfaLogin.RecoveryCode, mfaLogin.EmailCode} {
+		if code != "" {
+			codes++
+		}
+	}
+
+	if codes == 0 {
+		return nil, NewBadRequestError("empty code")
+	}
+
+	if codes > 1 {
+		return nil, NewBadRequestError("just one code expected")
+	}
+
+	if mfaLogin.Code != "" && !codeRegex.MatchString(mfaLogin.Code) {
+		return nil, NewBadRequestError("invalid code")
+	}
+
+	if mfaLogin.EmailCode != "" && !codeRegex.MatchString(mfaLogin.EmailCode) {
+		return nil, NewBadRequestError("invalid code")
+	}
+
+	return &mfaLogin, nil
+}
@@ -0,0 +1,91 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestTOTPCodeFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty body":        {`{}`, "empty code"},
+		"too short":         {`{"code":"12345"}`, "invalid code"},
+		"not digits":        {`{"code":"12345a"}`, "invalid code"},
+		"ok":                {`{"code":"123456"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.TOTPCodeFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("TOTPCodeFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("TOTPCodeFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestMFALoginFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body":  {``, "invalid json body"},
+		"empty body":         {`{}`, "empty mfa token"},
+		"empty code":         {`{"mfaToken":"token"}`, "empty code"},
+		"both codes":         {`{"mfaToken":"token","code":"123456","recoveryCode":"abcd"}`, "just one code expected"}, //nolint:lll
+		"email and totp":     {`{"mfaToken":"token","code":"123456","emailCode":"123456"}`, "just one code expected"},  //nolint:lll
+		"invalid email code": {`{"mfaToken":"token","emailCode":"12345a"}`, "invalid code"},
+		"invalid code":       {`{"mfaToken":"token","code":"1234"}`, "invalid code"},
+		"code":               {`{"mfaToken":"token","code":"123456"}`, ""},
+		"recovery code":      {`{"mfaToken":"token","recoveryCode":"abcd-efgh"}`, ""},
+		"email code":         {`{"mfaToken":"token","emailCode":"123456"}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.MFALoginFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("MFALoginFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("MFALoginFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package request
+
+// OAuth 2.0 error codes.
+const (
+	InvalidRequest          = "invalid_request"
+	InvalidClient           = "invalid_client"
+	InvalidGrant            = "invalid_grant"
+	InvalidScope            = "invalid_scope"
+	UnauthorizedClient      = "unauthorized_client"
+	UnsupportedGrantType    = "unsupported_grant_type"
+	UnsupportedResponseType = "unsupported_response_type"
+	UnsupportedTokenType    = "unsupported_token_type"
+	AccessDenied            = "access_denied"
+)
+
+// OAuthError contains OAuth 2.0 error code and description to be returned to the client.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+// NewOAuthError creates OAuth 2.0 error.
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+	}
+}
+
+// Error implements error interface.
+func (e *OAuthError) Error() string {
+	return e.Description
+}
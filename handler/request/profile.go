@@ -0,0 +1,95 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/lax"
+	"golang.org/x/text/language"
+)
+
+const (
+	maxDisplayNameLength = 100
+	maxMetadataSize      = 16 << 10
+)
+
+// ProfileUpdate contains profile fields to be updated. Omitted fields are left unchanged, empty strings clear them
+// and null metadata resets it to empty object.
+type ProfileUpdate struct {
+	DisplayName *string         `json:"displayName,omitempty"`
+	Locale      *string         `json:"locale,omitempty" example:"en-US"`
+	Timezone    *string         `json:"timezone,omitempty" example:"Europe/Berlin"`
+	Metadata    json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+}
+
+// ProfileUpdateFromJSON parses profile update from request body. Locale is canonicalized.
+func ProfileUpdateFromJSON(body io.Reader, log lax.Logger) (*ProfileUpdate, error) { //nolint:cyclop
+	var profileUpdate ProfileUpdate
+
+	if err := json.NewDecoder(body).Decode(&profileUpdate); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	if profileUpdate.DisplayName != nil && utf8.RuneCountInString(*profileUpdate.DisplayName) > maxDisplayNameLength {
+		return nil, NewBadRequestError("display name too long")
+	}
+
+	if profileUpdate.Locale != nil && *profileUpdate.Locale != "" {
+		tag, err := language.Parse(*profileUpdate.Locale)
+		if err != nil {
+			return nil, NewBadRequestError("invalid locale")
+		}
+
+		locale := tag.String()
+		profileUpdate.Locale = &locale
+	}
+
+	if profileUpdate.Timezone != nil && *profileUpdate.Timezone != "" {
+		if _, err := time.LoadLocation(*profileUpdate.Timezone); err != nil || *profileUpdate.Timezone == "Local" {
+			return nil, NewBadRequestError("invalid timezone")
+		}
+	}
+
+	if len(profileUpdate.Metadata) > maxMetadataSize {
+		return nil, NewBadRequestError("metadata too large")
+	}
+
+	if len(profileUpdate.Metadata) != 0 && !bytes.Equal(profileUpdate.Metadata, []byte("null")) &&
+		profileUpdate.Metadata[0] != '{' {
+		return nil, NewBadRequestError("metadata has to be an object")
+	}
+
+	return &profileUpdate, nil
+}
+
+// Apply applies updated fields to the profile.
+func (u *ProfileUpdate) Apply(profile *domain.Profile) {
+	if u.DisplayName != nil {
+		profile.DisplayName = *u.DisplayName
+	}
+
+	if u.Locale != nil {
+		profile.Locale = *u.Locale
+	}
+
+	if u.Timezone != nil {
+		profile.Timezone = *u.Timezone
+	}
+
+	switch {
+	case bytes.Equal(u.Metadata, []byte("null")):
+		profile.Metadata = json.RawMessage("{}")
+	case len(u.Metadata) != 0:
+		profile.Metadata = u.Metadata
+	}
+
+	if len(profile.Metadata) == 0 {
+		profile.Metadata = json.RawMessage("{}")
+	}
+}
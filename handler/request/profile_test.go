@@ -0,0 +1,101 @@
+package request_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestProfileUpdateFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body":  {``, "invalid json body"},
+		"empty body":         {`{}`, ""},
+		"long display name":  {`{"displayName":"` + strings.Repeat("ž", 101) + `"}`, "display name too long"},
+		"invalid locale":     {`{"locale":"not a locale"}`, "invalid locale"},
+		"invalid timezone":   {`{"timezone":"Mars/Olympus"}`, "invalid timezone"},
+		"local timezone":     {`{"timezone":"Local"}`, "invalid timezone"},
+		"metadata array":     {`{"metadata":[1,2]}`, "metadata has to be an object"},
+		"metadata string":    {`{"metadata":"data"}`, "metadata has to be an object"},
+		"metadata too large": {`{"metadata":{"data":"` + strings.Repeat("a", 16<<10) + `"}}`, "metadata too large"},
+		"ok": {
+			`{"displayName":"John Doe","locale":"en-us","timezone":"Europe/Berlin","metadata":{"theme":"dark"}}`,
+			"",
+		},
+		"cleared": {`{"displayName":"","locale":"","timezone":"","metadata":null}`, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.ProfileUpdateFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("ProfileUpdateFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("ProfileUpdateFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestProfileUpdateApply(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in   string
+		want domain.Profile
+	}{
+		"unchanged": {`{}`, domain.Profile{
+			DisplayName: "John", Locale: "de", Timezone: "UTC", Metadata: []byte(`{"a":1}`),
+		}},
+		"updated": {`{"displayName":"Jane","locale":"en-gb","metadata":{"b":2}}`, domain.Profile{
+			DisplayName: "Jane", Locale: "en-GB", Timezone: "UTC", Metadata: []byte(`{"b":2}`),
+		}},
+		"cleared": {`{"displayName":"","locale":"","timezone":"","metadata":null}`, domain.Profile{
+			DisplayName: "", Locale: "", Timezone: "", Metadata: []byte(`{}`),
+		}},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			profileUpdate, err := request.ProfileUpdateFromJSON(bytes.NewBufferString(test.in), log)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := domain.Profile{DisplayName: "John", Locale: "de", Timezone: "UTC", Metadata: []byte(`{"a":1}`)}
+			profileUpdate.Apply(&got)
+
+			if got.DisplayName != test.want.DisplayName || got.Locale != test.want.Locale ||
+				got.Timezone != test.want.Timezone || !bytes.Equal(got.Metadata, test.want.Metadata) {
+				t.Errorf("Apply(%q) = %+v; want %+v", test.in, got, test.want)
+			}
+		})
+	}
+}
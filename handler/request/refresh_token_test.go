@@ -0,0 +1,54 @@
+package request_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRefreshTokenFromBody(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		want    *request.RefreshToken
+		wantErr string
+	}{
+		"invalid json body":   {``, nil, "invalid json body"},
+		"empty body":          {`{}`, nil, "empty refresh token"},
+		"empty refresh token": {`{"refreshToken":""}`, nil, "empty refresh token"},
+		"ok":                  {`{"refreshToken":"test"}`, &request.RefreshToken{RefreshToken: "test"}, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.RefreshTokenFromBody(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("RefreshTokenFromBody(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("RefreshTokenFromBody(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if got.RefreshToken != test.want.RefreshToken {
+				t.Errorf("RefreshTokenFromBody(%q) = %v; want %v", test.in, got, test.want)
+			}
+		})
+	}
+}
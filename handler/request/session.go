@@ -0,0 +1,45 @@
+package request
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+const maxUserAgentLength = 512
+
+// UserAgent returns user agent of the client, truncated to reasonable length.
+func UserAgent(req *http.Request) string {
+	userAgent := req.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		return userAgent[:maxUserAgentLength]
+	}
+
+	return userAgent
+}
+
+// ClientIP returns IP address of the client. Each of trusted reverse proxies appends address of its peer to
+// X-Forwarded-For header, so the address appended by the outermost one is taken, counting from the right, while
+// entries on its left are set by the client and ignored. Remote address of the connection is used if there are no
+// trusted proxies or the header contains no valid address.
+func ClientIP(req *http.Request, trustedProxies uint) string {
+	if forwardedFor := req.Header.Get("X-Forwarded-For"); forwardedFor != "" && trustedProxies > 0 {
+		entries := strings.Split(forwardedFor, ",")
+		entry := strings.TrimSpace(entries[max(len(entries)-int(trustedProxies), 0)])
+
+		if ip := net.ParseIP(entry); ip != nil {
+			return ip.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+
+	return ""
+}
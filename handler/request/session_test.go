@@ -0,0 +1,63 @@
+package request_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		remoteAddr     string
+		forwardedFor   string
+		trustedProxies uint
+		want           string
+	}{
+		"remote address":       {"192.0.2.1:1234", "", 1, "192.0.2.1"},
+		"remote ipv6":          {"[2001:db8::1]:1234", "", 1, "2001:db8::1"},
+		"forwarded for":        {"10.0.0.1:1234", "203.0.113.7", 1, "203.0.113.7"},
+		"spoofed forwarded":    {"10.0.0.1:1234", "198.51.100.1, 203.0.113.7", 1, "203.0.113.7"},
+		"two proxies":          {"10.0.0.1:1234", "198.51.100.1, 203.0.113.7, 10.0.0.2", 2, "203.0.113.7"},
+		"fewer entries":        {"10.0.0.1:1234", "203.0.113.7", 2, "203.0.113.7"},
+		"no trusted proxies":   {"10.0.0.1:1234", "203.0.113.7", 0, "10.0.0.1"},
+		"invalid forwarded":    {"10.0.0.1:1234", "unknown", 1, "10.0.0.1"},
+		"invalid remote":       {"invalid", "", 1, ""},
+		"remote without port":  {"192.0.2.1", "", 1, "192.0.2.1"},
+		"forwarded whitespace": {"10.0.0.1:1234", "  203.0.113.7  ", 1, "203.0.113.7"},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/users/login", nil)
+			req.RemoteAddr = test.remoteAddr
+
+			if test.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", test.forwardedFor)
+			}
+
+			if got := request.ClientIP(req, test.trustedProxies); got != test.want {
+				t.Errorf("ClientIP() = %q; want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/login", nil)
+	req.Header.Set("User-Agent", strings.Repeat("a", 1000))
+
+	if got := request.UserAgent(req); len(got) != 512 {
+		t.Errorf("UserAgent() = length %d; want length 512", len(got))
+	}
+}
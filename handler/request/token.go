@@ -0,0 +1,102 @@
+package request
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// Supported OAuth grant types.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+var codeVerifierRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// Token contains OAuth token request parameters.
+type Token struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// TokenFromRequest parses OAuth token request from form parameters. Client credentials are taken from basic
+// authorization header if present, otherwise from form parameters.
+func TokenFromRequest(req *http.Request) (*Token, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, NewOAuthError(InvalidRequest, "invalid form body")
+	}
+
+	tokenRequest := &Token{
+		GrantType:    req.PostForm.Get("grant_type"),
+		ClientID:     req.PostForm.Get("client_id"),
+		ClientSecret: req.PostForm.Get("client_secret"),
+		Code:         req.PostForm.Get("code"),
+		RedirectURI:  req.PostForm.Get("redirect_uri"),
+		CodeVerifier: req.PostForm.Get("code_verifier"),
+		RefreshToken: req.PostForm.Get("refresh_token"),
+		Scope:        req.PostForm.Get("scope"),
+	}
+
+	if err := clientCredentials(req, &tokenRequest.ClientID, &tokenRequest.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	switch tokenRequest.GrantType {
+	case GrantTypeAuthorizationCode:
+		if tokenRequest.Code == "" {
+			return nil, NewOAuthError(InvalidRequest, "empty code")
+		}
+
+		if tokenRequest.RedirectURI == "" {
+			return nil, NewOAuthError(InvalidRequest, "empty redirect uri")
+		}
+
+		if !codeVerifierRegex.MatchString(tokenRequest.CodeVerifier) {
+			return nil, NewOAuthError(InvalidRequest, "invalid code verifier")
+		}
+	case GrantTypeRefreshToken:
+		if tokenRequest.RefreshToken == "" {
+			return nil, NewOAuthError(InvalidRequest, "empty refresh token")
+		}
+	case GrantTypeClientCredentials:
+		if tokenRequest.ClientSecret == "" {
+			return nil, NewOAuthError(InvalidClient, "empty client secret")
+		}
+	case "":
+		return nil, NewOAuthError(InvalidRequest, "empty grant type")
+	default:
+		return nil, NewOAuthError(UnsupportedGrantType, "unsupported grant type")
+	}
+
+	return tokenRequest, nil
+}
+
+// clientCredentials overrides client id and secret by basic authorization header credentials, which are form
+// encoded as required by OAuth 2.0, and checks that client id is present.
+func clientCredentials(req *http.Request, clientID, clientSecret *string) error {
+	if username, password, ok := req.BasicAuth(); ok {
+		var err error
+
+		if *clientID, err = url.QueryUnescape(username); err != nil {
+			return NewOAuthError(InvalidClient, "invalid client id")
+		}
+
+		if *clientSecret, err = url.QueryUnescape(password); err != nil {
+			return NewOAuthError(InvalidClient, "invalid client secret")
+		}
+	}
+
+	if *clientID == "" {
+		return NewOAuthError(InvalidRequest, "empty client id")
+	}
+
+	return nil
+}
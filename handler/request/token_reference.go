@@ -0,0 +1,42 @@
+package request
+
+import "net/http"
+
+// Token type hints.
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// TokenReference contains token introspection or revocation request parameters.
+type TokenReference struct {
+	Token         string
+	TokenTypeHint string
+	ClientID      string
+	ClientSecret  string
+}
+
+// TokenReferenceFromRequest parses token introspection or revocation request from form parameters. Client
+// credentials are taken from basic authorization header if present, otherwise from form parameters.
+func TokenReferenceFromRequest(req *http.Request) (*TokenReference, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, NewOAuthError(InvalidRequest, "invalid form body")
+	}
+
+	tokenReference := &TokenReference{
+		Token:         req.PostForm.Get("token"),
+		TokenTypeHint: req.PostForm.Get("token_type_hint"),
+		ClientID:      req.PostForm.Get("client_id"),
+		ClientSecret:  req.PostForm.Get("client_secret"),
+	}
+
+	if err := clientCredentials(req, &tokenReference.ClientID, &tokenReference.ClientSecret); err != nil {
+		return nil, err
+	}
+
+	if tokenReference.Token == "" {
+		return nil, NewOAuthError(InvalidRequest, "empty token")
+	}
+
+	return tokenReference, nil
+}
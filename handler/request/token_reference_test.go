@@ -0,0 +1,67 @@
+package request_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+)
+
+func TestTokenReferenceFromRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		in       string
+		basic    []string
+		wantCode string
+		wantErr  string
+	}{
+		"empty":        {"", nil, request.InvalidRequest, "empty client id"},
+		"empty token":  {"client_id=client", nil, request.InvalidRequest, "empty token"},
+		"client id":    {"client_id=client&token=token&token_type_hint=access_token", nil, "", ""},
+		"basic":        {"token=token", []string{"client", "secret"}, "", ""},
+		"basic escape": {"token=token", []string{"client", "%zz"}, request.InvalidClient, "invalid client secret"},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(test.in))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if test.basic != nil {
+				req.SetBasicAuth(test.basic[0], test.basic[1])
+			}
+
+			got, gotErr := request.TokenReferenceFromRequest(req)
+			if test.wantErr != "" {
+				oauthErr := &request.OAuthError{} //nolint:exhaustruct
+
+				if !errors.As(gotErr, &oauthErr) {
+					t.Fatalf("TokenReferenceFromRequest(%q) = error %v; want oauth error", test.in, gotErr)
+				}
+
+				if oauthErr.Code != test.wantCode || oauthErr.Description != test.wantErr {
+					t.Fatalf("TokenReferenceFromRequest(%q) = error %s %q; want error %s %q", test.in,
+						oauthErr.Code, oauthErr.Description, test.wantCode, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("TokenReferenceFromRequest(%q) = error %v; want nil", test.in, gotErr)
+			}
+
+			if got.ClientID != "client" || got.Token != "token" {
+				t.Errorf("TokenReferenceFromRequest(%q) = %+v", test.in, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package request_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+)
+
+func TestTokenFromRequest(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+	tests := map[string]struct {
+		in       string
+		basic    []string
+		wantCode string
+		wantErr  string
+	}{
+		"empty":                  {"", nil, request.InvalidRequest, "empty client id"},
+		"empty grant type":       {"client_id=client", nil, request.InvalidRequest, "empty grant type"},
+		"unsupported grant type": {"client_id=client&grant_type=password", nil, request.UnsupportedGrantType, "unsupported grant type"},                                                   //nolint:lll
+		"empty code":             {"client_id=client&grant_type=authorization_code", nil, request.InvalidRequest, "empty code"},                                                           //nolint:lll
+		"empty redirect uri":     {"client_id=client&grant_type=authorization_code&code=code", nil, request.InvalidRequest, "empty redirect uri"},                                         //nolint:lll
+		"short code verifier":    {"client_id=client&grant_type=authorization_code&code=code&redirect_uri=uri&code_verifier=short", nil, request.InvalidRequest, "invalid code verifier"}, //nolint:lll
+		"empty refresh token":    {"client_id=client&grant_type=refresh_token", nil, request.InvalidRequest, "empty refresh token"},                                                       //nolint:lll
+		"authorization code":     {"client_id=client&grant_type=authorization_code&code=code&redirect_uri=uri&code_verifier=" + verifier, nil, "", ""},                                    //nolint:lll
+		"refresh token":          {"client_id=client&grant_type=refresh_token&refresh_token=token", nil, "", ""},
+		"empty client secret":    {"client_id=client&grant_type=client_credentials", nil, request.InvalidClient, "empty client secret"}, //nolint:lll
+		"client secret post":     {"client_id=client&client_secret=secret&grant_type=client_credentials", nil, "", ""},
+		"client secret basic":    {"grant_type=client_credentials", []string{"client", "secret"}, "", ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(test.in))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			if test.basic != nil {
+				req.SetBasicAuth(test.basic[0], test.basic[1])
+			}
+
+			got, gotErr := request.TokenFromRequest(req)
+			if test.wantErr != "" {
+				oauthErr := &request.OAuthError{} //nolint:exhaustruct
+
+				if !errors.As(gotErr, &oauthErr) {
+					t.Fatalf("TokenFromRequest(%q) = error %v; want oauth error", test.in, gotErr)
+				}
+
+				if oauthErr.Code != test.wantCode || oauthErr.Description != test.wantErr {
+					t.Fatalf("TokenFromRequest(%q) = error %s %q; want error %s %q", test.in, oauthErr.Code,
+						oauthErr.Description, test.wantCode, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("TokenFromRequest(%q) = error %v; want nil", test.in, gotErr)
+			}
+
+			if got.ClientID != "client" {
+				t.Errorf("TokenFromRequest(%q) = %+v", test.in, got)
+			}
+		})
+	}
+}
@@ -27,20 +27,12 @@ func UserRegistrationFromJSON(body io.Reader, log lax.Logger) (*UserRegistration
 		return nil, NewBadRequestError("invalid json body")
 	}
 
-	if userRegistration.Email == "" {
-		return nil, NewBadRequestError("empty email")
+	if err := ValidateEmail(userRegistration.Email); err != nil {
+		return nil, err
 	}
 
-	if !isValidEmail(userRegistration.Email) {
-		return nil, NewBadRequestError("invalid email")
-	}
-
-	if userRegistration.Password == "" {
-		return nil, NewBadRequestError("empty password")
-	}
-
-	if isWeakPassword(userRegistration.Password) {
-		return nil, NewBadRequestError("weak password")
+	if err := ValidatePassword(userRegistration.Password); err != nil {
+		return nil, err
 	}
 
 	userRegistration.HashedPassword, err = domain.HashPassword(userRegistration.Password)
@@ -52,3 +44,29 @@ func UserRegistrationFromJSON(body io.Reader, log lax.Logger) (*UserRegistration
 
 	return &userRegistration, nil
 }
+
+// ValidateEmail checks if email address of the new user is present and valid.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return NewBadRequestError("empty email")
+	}
+
+	if !isValidEmail(email) {
+		return NewBadRequestError("invalid email")
+	}
+
+	return nil
+}
+
+// ValidatePassword checks if password of the new user is present and strong enough.
+func ValidatePassword(password string) error {
+	if password == "" {
+		return NewBadRequestError("empty password")
+	}
+
+	if isWeakPassword(password) {
+		return NewBadRequestError("weak password")
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package request
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/lax"
+)
+
+const maxStatusReasonLength = 500
+
+// UserStatus contains new status of the user. Until is end of suspension, omitted for suspension lasting until
+// lifted. Reason is required unless user is reactivated.
+type UserStatus struct {
+	Status string     `json:"status" enums:"active,suspended,banned"`
+	Until  *time.Time `json:"until,omitempty"`
+	Reason string     `json:"reason"`
+}
+
+// UserStatusFromJSON parses UserStatus from request body.
+func UserStatusFromJSON(body io.Reader, log lax.Logger) (*UserStatus, error) {
+	var userStatus UserStatus
+
+	if err := json.NewDecoder(body).Decode(&userStatus); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	userStatus.Reason = strings.TrimSpace(userStatus.Reason)
+
+	switch userStatus.Status {
+	case domain.StatusActive, domain.StatusBanned:
+		if userStatus.Until != nil {
+			return nil, NewBadRequestError("until allowed just for suspension")
+		}
+	case domain.StatusSuspended:
+		if userStatus.Until != nil && !userStatus.Until.After(time.Now()) {
+			return nil, NewBadRequestError("until in the past")
+		}
+	default:
+		return nil, NewBadRequestError("invalid status")
+	}
+
+	if userStatus.Reason == "" && userStatus.Status != domain.StatusActive {
+		return nil, NewBadRequestError("empty reason")
+	}
+
+	if utf8.RuneCountInString(userStatus.Reason) > maxStatusReasonLength {
+		return nil, NewBadRequestError("reason too long")
+	}
+
+	return &userStatus, nil
+}
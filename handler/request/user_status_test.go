@@ -0,0 +1,70 @@
+package request_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestUserStatusFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in         string
+		wantStatus string
+		wantErr    string
+	}{
+		"invalid json body": {``, "", "invalid json body"},
+		"empty body":        {`{}`, "", "invalid status"},
+		"invalid status":    {`{"status":"disabled","reason":"spam"}`, "", "invalid status"},
+		"empty reason":      {`{"status":"banned","reason":"  "}`, "", "empty reason"},
+		"reason too long":   {`{"status":"banned","reason":"` + strings.Repeat("a", 501) + `"}`, "", "reason too long"},
+		"until for ban": {
+			`{"status":"banned","reason":"spam","until":"2999-01-01T00:00:00Z"}`, "", "until allowed just for suspension",
+		},
+		"until in the past": {
+			`{"status":"suspended","reason":"spam","until":"2001-01-01T00:00:00Z"}`, "", "until in the past",
+		},
+		"suspension":          {`{"status":"suspended","reason":"spam","until":"2999-01-01T00:00:00Z"}`, "suspended", ""},
+		"indefinite":          {`{"status":"suspended","reason":"spam"}`, "suspended", ""},
+		"ban":                 {`{"status":"banned","reason":"fraud"}`, "banned", ""},
+		"active without note": {`{"status":"active"}`, "active", ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			got, gotErr := request.UserStatusFromJSON(buf, log)
+			if test.wantErr != "" {
+				if gotErr == nil {
+					t.Fatalf("UserStatusFromJSON(%q) = error nil; want error %q", test.in, test.wantErr)
+				}
+
+				if gotErr.Error() != test.wantErr {
+					t.Fatalf("UserStatusFromJSON(%q) = error %q; want error %q", test.in, gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatalf("UserStatusFromJSON(%q) = error %q; want no error", test.in, gotErr)
+			}
+
+			if got.Status != test.wantStatus {
+				t.Errorf("UserStatusFromJSON(%q) = status %q; want %q", test.in, got.Status, test.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package request
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/repository"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 100
+)
+
+// UsersQueryFromURL parses users listing query from URL query parameters email (prefix), active, createdFrom,
+// createdTo (RFC 3339), sort (created, email, optionally prefixed by minus for descending order), limit and cursor.
+// Cursor of the next page determines sort order if sort is omitted.
+func UsersQueryFromURL(values url.Values) (*repository.UsersQuery, error) { //nolint:cyclop
+	query := &repository.UsersQuery{ //nolint:exhaustruct
+		EmailPrefix: strings.TrimSpace(values.Get("email")),
+		Sort:        repository.UsersByCreated,
+		Limit:       defaultUsersLimit,
+	}
+
+	if active := values.Get("active"); active != "" {
+		isActive, err := strconv.ParseBool(active)
+		if err != nil {
+			return nil, NewBadRequestError("invalid active")
+		}
+
+		query.Active = &isActive
+	}
+
+	var err error
+
+	if query.CreatedFrom, err = parseTime(values.Get("createdFrom")); err != nil {
+		return nil, NewBadRequestError("invalid createdFrom")
+	}
+
+	if query.CreatedTo, err = parseTime(values.Get("createdTo")); err != nil {
+		return nil, NewBadRequestError("invalid createdTo")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 || query.Limit > maxUsersLimit {
+			return nil, NewBadRequestError("invalid limit")
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		if query.After, err = repository.ParseCursor(cursor); err != nil {
+			return nil, NewBadRequestError("invalid cursor")
+		}
+	}
+
+	sort := values.Get("sort")
+	if sort == "" && query.After != nil {
+		sort = query.After.Sort
+	}
+
+	if sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.Sort = strings.TrimPrefix(sort, "-")
+
+		if query.Sort != repository.UsersByCreated && query.Sort != repository.UsersByEmail {
+			return nil, NewBadRequestError("invalid sort")
+		}
+	}
+
+	if query.After != nil && query.After.Sort != query.SortOrder() {
+		return nil, NewBadRequestError("cursor does not match sort")
+	}
+
+	if query.After != nil && query.Sort == repository.UsersByCreated {
+		if _, err := time.Parse(time.RFC3339Nano, query.After.Value); err != nil {
+			return nil, NewBadRequestError("invalid cursor")
+		}
+	}
+
+	return query, nil
+}
+
+// parseTime parses optional RFC 3339 time.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err //nolint:wrapcheck
+	}
+
+	return &parsed, nil
+}
@@ -0,0 +1,71 @@
+package request_test
+
+import (
+	"net/url"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/repository"
+)
+
+func TestUsersQueryFromURL(t *testing.T) {
+	t.Parallel()
+
+	emailCursor := (&repository.Cursor{Sort: "-email", Value: "john.doe@sixpack.com", ID: "john"}).String()
+	createdCursor := (&repository.Cursor{Sort: "created", Value: "yesterday", ID: "john"}).String()
+
+	tests := map[string]struct {
+		in             string
+		wantSort       string
+		wantLimit      int
+		wantErrMessage string
+	}{
+		"defaults":              {"", "created", 50, ""},
+		"all filters":           {"email=jo&active=true&createdFrom=2021-10-01T00:00:00Z&limit=10", "created", 10, ""},
+		"descending email":      {"sort=-email", "-email", 50, ""},
+		"sort from cursor":      {"cursor=" + emailCursor, "-email", 50, ""},
+		"cursor matching sort":  {"sort=-email&cursor=" + emailCursor, "-email", 50, ""},
+		"cursor not matching":   {"sort=email&cursor=" + emailCursor, "", 0, "cursor does not match sort"},
+		"invalid cursor":        {"cursor=abc", "", 0, "invalid cursor"},
+		"invalid cursor value":  {"cursor=" + createdCursor, "", 0, "invalid cursor"},
+		"invalid sort":          {"sort=password", "", 0, "invalid sort"},
+		"invalid active":        {"active=maybe", "", 0, "invalid active"},
+		"invalid created from":  {"createdFrom=2021-10-01", "", 0, "invalid createdFrom"},
+		"invalid created to":    {"createdTo=tomorrow", "", 0, "invalid createdTo"},
+		"zero limit":            {"limit=0", "", 0, "invalid limit"},
+		"limit too large":       {"limit=101", "", 0, "invalid limit"},
+		"non numeric limit":     {"limit=ten", "", 0, "invalid limit"},
+		"whitespace only email": {"email=%20", "created", 50, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			values, err := url.ParseQuery(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := request.UsersQueryFromURL(values)
+			if test.wantErrMessage != "" {
+				if err == nil || err.Error() != test.wantErrMessage {
+					t.Errorf("UsersQueryFromURL(%q) error = %v; want %s", test.in, err, test.wantErrMessage)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("UsersQueryFromURL(%q) error = %v", test.in, err)
+			}
+
+			if got.SortOrder() != test.wantSort || got.Limit != test.wantLimit {
+				t.Errorf("UsersQueryFromURL(%q) = %+v; want sort %s and limit %d", test.in, got, test.wantSort,
+					test.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package request
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"go.ectobit.com/lax"
+)
+
+const (
+	defaultCredentialName = "Passkey"
+	maxCredentialNameLen  = 64
+)
+
+// WebAuthnRegistration contains authenticator response to the registration ceremony. Binary fields are base64url
+// encoded.
+type WebAuthnRegistration struct {
+	Name              string `json:"name,omitempty"`
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AttestationObject string `json:"attestationObject"`
+	RawID             []byte `json:"-"`
+	RawClientDataJSON []byte `json:"-"`
+	RawAttestation    []byte `json:"-"`
+}
+
+// WebAuthnRegistrationFromJSON parses WebAuthn registration from request body. Empty credential name defaults to
+// "Passkey".
+func WebAuthnRegistrationFromJSON(body io.Reader, log lax.Logger) (*WebAuthnRegistration, error) {
+	var registration WebAuthnRegistration
+
+	if err := json.NewDecoder(body).Decode(&registration); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	registration.Name = strings.TrimSpace(registration.Name)
+	if registration.Name == "" {
+		registration.Name = defaultCredentialName
+	}
+
+	if utf8.RuneCountInString(registration.Name) > maxCredentialNameLen {
+		return nil, NewBadRequestError("too long name")
+	}
+
+	var ok bool
+
+	if registration.RawID, ok = decodeBase64URL(registration.ID); !ok {
+		return nil, NewBadRequestError("invalid credential id")
+	}
+
+	if registration.RawClientDataJSON, ok = decodeBase64URL(registration.ClientDataJSON); !ok {
+		return nil, NewBadRequestError("invalid client data")
+	}
+
+	if registration.RawAttestation, ok = decodeBase64URL(registration.AttestationObject); !ok {
+		return nil, NewBadRequestError("invalid attestation object")
+	}
+
+	return &registration, nil
+}
+
+// WebAuthnLoginBegin contains optional MFA challenge token. WebAuthn is used as the second factor if token is
+// provided, otherwise as passwordless login.
+type WebAuthnLoginBegin struct {
+	MFAToken string `json:"mfaToken,omitempty"`
+}
+
+// WebAuthnLoginBeginFromJSON parses WebAuthn login begin from request body, which may be empty.
+func WebAuthnLoginBeginFromJSON(body io.Reader, log lax.Logger) (*WebAuthnLoginBegin, error) {
+	var loginBegin WebAuthnLoginBegin
+
+	if err := json.NewDecoder(body).Decode(&loginBegin); err != nil && !errors.Is(err, io.EOF) {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	return &loginBegin, nil
+}
+
+// WebAuthnAssertion contains authenticator response to the authentication ceremony. Binary fields are base64url
+// encoded. User handle is returned by authenticators for discoverable credentials and is required for passwordless
+// login.
+type WebAuthnAssertion struct {
+	ID                   string `json:"id"`
+	ClientDataJSON       string `json:"clientDataJSON"`
+	AuthenticatorData    string `json:"authenticatorData"`
+	Signature            string `json:"signature"`
+	UserHandle           string `json:"userHandle,omitempty"`
+	RawID                []byte `json:"-"`
+	RawClientDataJSON    []byte `json:"-"`
+	RawAuthenticatorData []byte `json:"-"`
+	RawSignature         []byte `json:"-"`
+	RawUserHandle        []byte `json:"-"`
+}
+
+// WebAuthnAssertionFromJSON parses WebAuthn assertion from request body.
+func WebAuthnAssertionFromJSON(body io.Reader, log lax.Logger) (*WebAuthnAssertion, error) {
+	var assertion WebAuthnAssertion
+
+	if err := json.NewDecoder(body).Decode(&assertion); err != nil {
+		log.Warn("decode json: %w", lax.Error(err))
+
+		return nil, NewBadRequestError("invalid json body")
+	}
+
+	var ok bool
+
+	if assertion.RawID, ok = decodeBase64URL(assertion.ID); !ok {
+		return nil, NewBadRequestError("invalid credential id")
+	}
+
+	if assertion.RawClientDataJSON, ok = decodeBase64URL(assertion.ClientDataJSON); !ok {
+		return nil, NewBadRequestError("invalid client data")
+	}
+
+	if assertion.RawAuthenticatorData, ok = decodeBase64URL(assertion.AuthenticatorData); !ok {
+		return nil, NewBadRequestError("invalid authenticator data")
+	}
+
+	if assertion.RawSignature, ok = decodeBase64URL(assertion.Signature); !ok {
+		return nil, NewBadRequestError("invalid signature")
+	}
+
+	if assertion.UserHandle != "" {
+		if assertion.RawUserHandle, ok = decodeBase64URL(assertion.UserHandle); !ok {
+			return nil, NewBadRequestError("invalid user handle")
+		}
+	}
+
+	return &assertion, nil
+}
+
+// CredentialID decodes base64url encoded WebAuthn credential id.
+func CredentialID(encoded string) ([]byte, bool) {
+	return decodeBase64URL(encoded)
+}
+
+// decodeBase64URL decodes non-empty base64url encoded data with or without padding.
+func decodeBase64URL(encoded string) ([]byte, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil || len(data) == 0 {
+		return nil, false
+	}
+
+	return data, true
+}
@@ -0,0 +1,150 @@
+package request_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWebAuthnRegistrationFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in       string
+		wantName string
+		wantErr  string
+	}{
+		"invalid json body":   {``, "", "invalid json body"},
+		"empty id":            {`{"clientDataJSON":"YQ","attestationObject":"YQ"}`, "", "invalid credential id"},
+		"empty client data":   {`{"id":"YQ","attestationObject":"YQ"}`, "", "invalid client data"},
+		"invalid client data": {`{"id":"YQ","clientDataJSON":"a+b","attestationObject":"YQ"}`, "", "invalid client data"},
+		"empty attestation":   {`{"id":"YQ","clientDataJSON":"YQ"}`, "", "invalid attestation object"},
+		"too long name": {
+			`{"name":"` + strings.Repeat("a", 65) + `","id":"YQ","clientDataJSON":"YQ","attestationObject":"YQ"}`, "",
+			"too long name",
+		},
+		"default name": {`{"id":"YQ","clientDataJSON":"YQ","attestationObject":"YQ"}`, "Passkey", ""},
+		"padded":       {`{"name":" Key ","id":"YQ==","clientDataJSON":"YQ==","attestationObject":"YQ"}`, "Key", ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			got, gotErr := request.WebAuthnRegistrationFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("WebAuthnRegistrationFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				if got.Name != test.wantName || string(got.RawClientDataJSON) != "a" {
+					t.Errorf("WebAuthnRegistrationFromJSON(%q) = %+v; want name %q", test.in, got, test.wantName)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("WebAuthnRegistrationFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebAuthnLoginBeginFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in           string
+		wantMFAToken string
+		wantErr      string
+	}{
+		"empty body":        {``, "", ""},
+		"passwordless":      {`{}`, "", ""},
+		"second factor":     {`{"mfaToken":"token"}`, "token", ""},
+		"invalid json body": {`{`, "", "invalid json body"},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			got, gotErr := request.WebAuthnLoginBeginFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("WebAuthnLoginBeginFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				if got.MFAToken != test.wantMFAToken {
+					t.Errorf("WebAuthnLoginBeginFromJSON(%q) = %q; want %q", test.in, got.MFAToken, test.wantMFAToken)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("WebAuthnLoginBeginFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebAuthnAssertionFromJSON(t *testing.T) {
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+
+	tests := map[string]struct {
+		in      string
+		wantErr string
+	}{
+		"invalid json body": {``, "invalid json body"},
+		"empty id":          {`{"clientDataJSON":"YQ","authenticatorData":"YQ","signature":"YQ"}`, "invalid credential id"},
+		"empty client data": {`{"id":"YQ","authenticatorData":"YQ","signature":"YQ"}`, "invalid client data"},
+		"empty auth data":   {`{"id":"YQ","clientDataJSON":"YQ","signature":"YQ"}`, "invalid authenticator data"},
+		"invalid signature": {
+			`{"id":"YQ","clientDataJSON":"YQ","authenticatorData":"YQ","signature":"!"}`, "invalid signature",
+		},
+		"invalid user handle": {
+			`{"id":"YQ","clientDataJSON":"YQ","authenticatorData":"YQ","signature":"YQ","userHandle":"!"}`,
+			"invalid user handle",
+		},
+		"ok": {`{"id":"YQ","clientDataJSON":"YQ","authenticatorData":"YQ","signature":"YQ"}`, ""},
+		"user handle": {
+			`{"id":"YQ","clientDataJSON":"YQ","authenticatorData":"YQ","signature":"YQ","userHandle":"YQ"}`, "",
+		},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			_, gotErr := request.WebAuthnAssertionFromJSON(bytes.NewBufferString(test.in), log)
+			if test.wantErr == "" {
+				if gotErr != nil {
+					t.Fatalf("WebAuthnAssertionFromJSON(%q) = error %q; want nil", test.in, gotErr)
+				}
+
+				return
+			}
+
+			if gotErr == nil || gotErr.Error() != test.wantErr {
+				t.Fatalf("WebAuthnAssertionFromJSON(%q) = error %v; want error %q", test.in, gotErr, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package response
+
+import (
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Client contains OAuth client data to send out. Secret is sent just once, when it is generated.
+type Client struct {
+	ID           string     `json:"id" format:"uuid"`
+	Name         string     `json:"name"`
+	Secret       string     `json:"secret,omitempty"`
+	RedirectURIs []string   `json:"redirectUris"`
+	Scopes       []string   `json:"scopes"`
+	Confidential bool       `json:"confidential"`
+	Trusted      bool       `json:"trusted"`
+	Created      *time.Time `json:"created"`
+}
+
+// FromDomainClient converts domain client to public client.
+func FromDomainClient(client *domain.Client) *Client {
+	return &Client{ //nolint:exhaustruct
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       client.Scopes,
+		Confidential: client.IsConfidential(),
+		Trusted:      client.Trusted,
+		Created:      client.Created,
+	}
+}
+
+// ClientSecret contains newly generated OAuth client secret.
+type ClientSecret struct {
+	Secret string `json:"secret"`
+}
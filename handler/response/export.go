@@ -0,0 +1,54 @@
+package response
+
+import (
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Export contains all data stored about the user. Password, token and code hashes are left out.
+type Export struct {
+	User                *User                 `json:"user"`
+	MFAMethods          []string              `json:"mfaMethods"`
+	Sessions            []*Session            `json:"sessions"`
+	WebAuthnCredentials []*WebAuthnCredential `json:"webauthnCredentials"`
+	Consents            []*Consent            `json:"consents"`
+	StatusHistory       []*UserStatus         `json:"statusHistory"`
+	Invitations         []*Invitation         `json:"invitations"`
+	EmailChanges        []*EmailChange        `json:"emailChanges"`
+	Exported            time.Time             `json:"exported"`
+}
+
+// Consent contains scopes the user has allowed the OAuth client to access.
+type Consent struct {
+	ClientID string     `json:"clientId" format:"uuid"`
+	Scopes   []string   `json:"scopes"`
+	Created  *time.Time `json:"created"`
+	Updated  *time.Time `json:"updated,omitempty"`
+}
+
+// FromDomainConsent converts domain consent to public consent.
+func FromDomainConsent(consent *domain.Consent) *Consent {
+	return &Consent{
+		ClientID: consent.ClientID,
+		Scopes:   consent.Scopes,
+		Created:  consent.Created,
+		Updated:  consent.Updated,
+	}
+}
+
+// EmailChange contains pending change of the user's email address.
+type EmailChange struct {
+	NewEmail string     `json:"newEmail"`
+	Expires  time.Time  `json:"expires"`
+	Created  *time.Time `json:"created"`
+}
+
+// FromDomainEmailChange converts domain email change to public email change.
+func FromDomainEmailChange(emailChange *domain.EmailChange) *EmailChange {
+	return &EmailChange{
+		NewEmail: emailChange.NewEmail,
+		Expires:  emailChange.Expires,
+		Created:  emailChange.Created,
+	}
+}
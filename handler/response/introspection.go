@@ -0,0 +1,31 @@
+package response
+
+import "go.ectobit.com/arc/handler/token"
+
+// Introspection contains RFC 7662 token introspection response body. Inactive tokens contain just active field.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Expires   int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+	ID        string `json:"jti,omitempty"`
+}
+
+// IntrospectionFromClaims converts claims of the active token to introspection response.
+func IntrospectionFromClaims(claims *token.Claims, issuer, tokenType string) *Introspection {
+	return &Introspection{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		Subject:   claims.Subject,
+		TokenType: tokenType,
+		Expires:   claims.Expires.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+		Issuer:    issuer,
+		ID:        claims.ID,
+	}
+}
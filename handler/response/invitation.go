@@ -0,0 +1,40 @@
+package response
+
+import (
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Invitation contains invitation data to send out. ID, inviter and creation time are sent just to administrators.
+type Invitation struct {
+	ID       string     `json:"id,omitempty" format:"uuid"`
+	Email    string     `json:"email"`
+	Role     string     `json:"role,omitempty"`
+	Inviter  string     `json:"inviter,omitempty"`
+	Expires  time.Time  `json:"expires"`
+	Accepted *time.Time `json:"accepted,omitempty"`
+	Created  *time.Time `json:"created,omitempty"`
+}
+
+// FromDomainInvitation converts domain invitation to invitation data for administrators.
+func FromDomainInvitation(invitation *domain.Invitation) *Invitation {
+	return &Invitation{
+		ID:       invitation.ID,
+		Email:    invitation.Email,
+		Role:     invitation.Role,
+		Inviter:  invitation.Inviter,
+		Expires:  invitation.Expires,
+		Accepted: invitation.Accepted,
+		Created:  invitation.Created,
+	}
+}
+
+// FromDomainInvitationPublic converts domain invitation to invitation data for the invitee.
+func FromDomainInvitationPublic(invitation *domain.Invitation) *Invitation {
+	return &Invitation{ //nolint:exhaustruct
+		Email:   invitation.Email,
+		Role:    invitation.Role,
+		Expires: invitation.Expires,
+	}
+}
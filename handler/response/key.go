@@ -0,0 +1,7 @@
+package response
+
+// Key contains signing key metadata.
+type Key struct {
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+}
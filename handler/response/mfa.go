@@ -0,0 +1,26 @@
+package response
+
+// MFAChallenge is sent instead of tokens when user has to complete login by the second factor. Methods lists second
+// factors available to the user.
+type MFAChallenge struct {
+	MFAToken string   `json:"mfaToken"`
+	Methods  []string `json:"methods"`
+}
+
+// TOTPEnrollment contains TOTP secret and otpauth URI to be rendered as QR code.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes contains one-time recovery codes which are shown just once.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// StepUp contains short-lived token allowing sensitive operations within the same session. It has to be sent in
+// X-Step-Up-Token header.
+type StepUp struct {
+	StepUpToken string `json:"stepUpToken"`
+	ExpiresIn   int64  `json:"expiresIn"`
+}
@@ -0,0 +1,63 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/lax"
+)
+
+// OAuthError contains OAuth 2.0 error response body.
+type OAuthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthTokens contains OAuth 2.0 token response body.
+type OAuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Authorization contains OAuth authorization request details to be shown on consent screen.
+type Authorization struct {
+	ClientID        string   `json:"clientId" format:"uuid"`
+	ClientName      string   `json:"clientName"`
+	Scopes          []string `json:"scopes"`
+	ConsentRequired bool     `json:"consentRequired"`
+}
+
+// Redirect contains url the user agent should be redirected to.
+type Redirect struct {
+	RedirectURI string `json:"redirectUri"`
+}
+
+// RenderOAuthError renders OAuth 2.0 error response by provided error. Client authentication failures are rendered
+// with status unauthorized.
+func RenderOAuthError(res http.ResponseWriter, err error, log lax.Logger) {
+	oauthErr := &request.OAuthError{} //nolint:exhaustruct
+
+	if errors.As(err, &oauthErr) {
+		statusCode := http.StatusBadRequest
+		if oauthErr.Code == request.InvalidClient {
+			statusCode = http.StatusUnauthorized
+		}
+
+		RenderOAuthErrorStatus(res, statusCode, oauthErr.Code, oauthErr.Description, log)
+
+		return
+	}
+
+	RenderError(res, err, log)
+}
+
+// RenderOAuthErrorStatus renders OAuth 2.0 error response by provided status code, error code and description.
+func RenderOAuthErrorStatus(res http.ResponseWriter, statusCode int, code, description string, log lax.Logger) {
+	res.Header().Set("Cache-Control", "no-store")
+	Render(res, statusCode, &OAuthError{Error: code, ErrorDescription: description}, log)
+}
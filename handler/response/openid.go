@@ -0,0 +1,48 @@
+package response
+
+import "go.ectobit.com/arc/domain"
+
+// OpenIDConfiguration contains OpenID Connect discovery metadata.
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                     string   `json:"token_endpoint,omitempty"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint,omitempty"`
+	RevocationEndpoint                string   `json:"revocation_endpoint,omitempty"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfo contains OpenID Connect claims about the authenticated user.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	UpdatedAt     int64  `json:"updated_at,omitempty"`
+}
+
+// UserInfoFromDomainUser converts domain user to OpenID Connect user info. Email claims are omitted unless
+// withEmail is set.
+func UserInfoFromDomainUser(user *domain.User, withEmail bool) *UserInfo {
+	userInfo := &UserInfo{Subject: user.ID} //nolint:exhaustruct
+
+	if withEmail {
+		emailVerified := user.IsActive()
+		userInfo.Email = user.Email
+		userInfo.EmailVerified = &emailVerified
+	}
+
+	if user.Updated != nil {
+		userInfo.UpdatedAt = user.Updated.Unix()
+	}
+
+	return userInfo
+}
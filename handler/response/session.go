@@ -0,0 +1,29 @@
+package response
+
+import (
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Session contains data of the device user is logged in from.
+type Session struct {
+	ID        string     `json:"id" format:"uuid"`
+	UserAgent string     `json:"userAgent"`
+	IP        string     `json:"ip"`
+	Created   *time.Time `json:"created"`
+	LastSeen  *time.Time `json:"lastSeen"`
+	Current   bool       `json:"current"`
+}
+
+// FromDomainSession converts domain session to public session. Current marks the session of the requester.
+func FromDomainSession(session *domain.Session, currentSessionID string) *Session {
+	return &Session{
+		ID:        session.ID,
+		UserAgent: session.UserAgent,
+		IP:        session.IP,
+		Created:   session.Created,
+		LastSeen:  session.LastSeen,
+		Current:   session.ID == currentSessionID,
+	}
+}
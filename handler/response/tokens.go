@@ -0,0 +1,7 @@
+package response
+
+// Tokens contains authentication and refresh tokens.
+type Tokens struct {
+	AuthToken    string `json:"authToken"`
+	RefreshToken string `json:"refreshToken"`
+}
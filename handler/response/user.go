@@ -1,6 +1,7 @@
 package response
 
 import (
+	"encoding/json"
 	"time"
 
 	"go.ectobit.com/arc/domain"
@@ -8,20 +9,86 @@ import (
 
 // User contains user data to send out.
 type User struct {
-	ID           string     `json:"id,omitempty" format:"uuid"`
-	Email        string     `json:"email"`
-	Created      *time.Time `json:"created"`
-	Updated      *time.Time `json:"updated,omitempty"`
-	AuthToken    string     `json:"authToken,omitempty"`
-	RefreshToken string     `json:"refreshToken,omitempty"`
+	ID                string          `json:"id,omitempty" format:"uuid"`
+	Email             string          `json:"email"`
+	DisplayName       string          `json:"displayName,omitempty"`
+	Locale            string          `json:"locale,omitempty"`
+	Timezone          string          `json:"timezone,omitempty"`
+	Metadata          json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	Created           *time.Time      `json:"created"`
+	Updated           *time.Time      `json:"updated,omitempty"`
+	DeletionScheduled *time.Time      `json:"deletionScheduled,omitempty"`
+	AuthToken         string          `json:"authToken,omitempty"`
+	RefreshToken      string          `json:"refreshToken,omitempty"`
+	IDToken           string          `json:"idToken,omitempty"`
 }
 
 // FromDomainUser converts domain user to public user.
 func FromDomainUser(user *domain.User) *User {
 	return &User{ //nolint:exhaustruct
-		ID:      user.ID,
-		Email:   user.Email,
-		Created: user.Created,
-		Updated: user.Updated,
+		ID:                user.ID,
+		Email:             user.Email,
+		DisplayName:       user.DisplayName,
+		Locale:            user.Locale,
+		Timezone:          user.Timezone,
+		Metadata:          user.Metadata,
+		Created:           user.Created,
+		Updated:           user.Updated,
+		DeletionScheduled: user.DeletionScheduled,
 	}
 }
+
+// AdminUser contains user data to send out to administrators.
+type AdminUser struct {
+	User
+	Active    bool        `json:"active"`
+	Activated *time.Time  `json:"activated,omitempty"`
+	Status    *UserStatus `json:"status"`
+	Role      string      `json:"role,omitempty"`
+}
+
+// FromDomainUserAdmin converts domain user to user data for administrators.
+func FromDomainUserAdmin(user *domain.User) *AdminUser {
+	return &AdminUser{
+		User:      *FromDomainUser(user),
+		Active:    user.Active != nil && *user.Active,
+		Activated: user.Activated,
+		Status:    FromDomainUserStatus(&user.Status),
+		Role:      user.Role,
+	}
+}
+
+// UserStatus contains status of the user account set by administrator.
+type UserStatus struct {
+	Status  string     `json:"status" enums:"active,suspended,banned"`
+	Until   *time.Time `json:"until,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+	Actor   string     `json:"actor,omitempty"`
+	Changed *time.Time `json:"changed,omitempty"`
+}
+
+// FromDomainUserStatus converts domain user status to user status to send out. Users whose status has never been set
+// are active.
+func FromDomainUserStatus(status *domain.UserStatus) *UserStatus {
+	userStatus := &UserStatus{
+		Status:  status.Status,
+		Until:   status.Until,
+		Reason:  status.Reason,
+		Actor:   status.Actor,
+		Changed: status.Changed,
+	}
+
+	if userStatus.Status == "" {
+		userStatus.Status = domain.StatusActive
+	}
+
+	return userStatus
+}
+
+// UsersPage contains one page of users listing for administrators. Next is the cursor of the next page, omitted on
+// the last one.
+type UsersPage struct {
+	Users []*AdminUser `json:"users"`
+	Total int64        `json:"total"`
+	Next  string       `json:"next,omitempty"`
+}
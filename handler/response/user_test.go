@@ -1,6 +1,7 @@
 package response_test
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -16,24 +17,37 @@ func TestFromDomainUser(t *testing.T) {
 	now := time.Now()
 
 	domainUser := &domain.User{
-		ID:              "926c7bed-18a7-4c0f-97fd-f5901b2c52ba",
-		Email:           "john.doe@sixpack.com",
-		Password:        []byte{},
-		Activated:       nil,
-		Created:         &now,
-		Updated:         &now,
-		ActivationToken: "",
-		RecoveryToken:   "",
-		Active:          &active,
+		ID:                    "926c7bed-18a7-4c0f-97fd-f5901b2c52ba",
+		Email:                 "john.doe@sixpack.com",
+		Password:              []byte{},
+		Activated:             nil,
+		Created:               &now,
+		Updated:               &now,
+		ActivationTokenIssued: &now,
+		RecoveryTokenIssued:   nil,
+		Active:                &active,
+		DeletionScheduled:     &now,
+		Profile: domain.Profile{
+			DisplayName: "John Doe",
+			Locale:      "en-US",
+			Timezone:    "Europe/Berlin",
+			Metadata:    json.RawMessage(`{"theme":"dark"}`),
+		},
 	}
 
 	wantPublicUser := &response.User{
-		ID:           domainUser.ID,
-		Email:        domainUser.Email,
-		Created:      &now,
-		Updated:      &now,
-		AuthToken:    "",
-		RefreshToken: "",
+		ID:                domainUser.ID,
+		Email:             domainUser.Email,
+		DisplayName:       "John Doe",
+		Locale:            "en-US",
+		Timezone:          "Europe/Berlin",
+		Metadata:          json.RawMessage(`{"theme":"dark"}`),
+		Created:           &now,
+		Updated:           &now,
+		DeletionScheduled: &now,
+		AuthToken:         "",
+		RefreshToken:      "",
+		IDToken:           "",
 	}
 
 	gotPublicUser := response.FromDomainUser(domainUser)
@@ -0,0 +1,135 @@
+package response
+
+import (
+	"encoding/base64"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"go.ectobit.com/arc/domain"
+)
+
+// WebAuthnRP identifies relying party.
+type WebAuthnRP struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUser identifies user account the credential is created for. ID is base64url encoded.
+type WebAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// WebAuthnCredentialParameters contains credential type and COSE algorithm.
+type WebAuthnCredentialParameters struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor identifies credential by base64url encoded ID.
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// WebAuthnAuthenticatorSelection contains requirements for the authenticator.
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions contains options of the registration ceremony to be passed to
+// navigator.credentials.create after decoding base64url encoded fields.
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RP                     WebAuthnRP                     `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	PubKeyCredParams       []WebAuthnCredentialParameters `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+// NewWebAuthnCreationOptions converts options of the registration ceremony.
+func NewWebAuthnCreationOptions(options *protocol.PublicKeyCredentialCreationOptions) *WebAuthnCreationOptions {
+	params := make([]WebAuthnCredentialParameters, 0, len(options.Parameters))
+
+	for _, param := range options.Parameters {
+		params = append(params, WebAuthnCredentialParameters{Type: string(param.Type), Alg: int(param.Algorithm)})
+	}
+
+	userID, _ := options.User.ID.(protocol.URLEncodedBase64)
+
+	return &WebAuthnCreationOptions{
+		Challenge: options.Challenge.String(),
+		RP:        WebAuthnRP{ID: options.RelyingParty.ID, Name: options.RelyingParty.Name},
+		User: WebAuthnUser{
+			ID:          userID.String(),
+			Name:        options.User.Name,
+			DisplayName: options.User.DisplayName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            int64(options.Timeout),
+		ExcludeCredentials: credentialDescriptors(options.CredentialExcludeList),
+		AuthenticatorSelection: WebAuthnAuthenticatorSelection{
+			ResidentKey:      string(options.AuthenticatorSelection.ResidentKey),
+			UserVerification: string(options.AuthenticatorSelection.UserVerification),
+		},
+		Attestation: string(options.Attestation),
+	}
+}
+
+// WebAuthnRequestOptions contains options of the authentication ceremony to be passed to navigator.credentials.get
+// after decoding base64url encoded fields. Allowed credentials are empty for passwordless login, so that the
+// authenticator offers discoverable credentials.
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	RPID             string                         `json:"rpId"`
+	Timeout          int64                          `json:"timeout"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// NewWebAuthnRequestOptions converts options of the authentication ceremony.
+func NewWebAuthnRequestOptions(options *protocol.PublicKeyCredentialRequestOptions) *WebAuthnRequestOptions {
+	return &WebAuthnRequestOptions{
+		Challenge:        options.Challenge.String(),
+		RPID:             options.RelyingPartyID,
+		Timeout:          int64(options.Timeout),
+		AllowCredentials: credentialDescriptors(options.AllowedCredentials),
+		UserVerification: string(options.UserVerification),
+	}
+}
+
+// WebAuthnCredential contains registered credential data. ID is base64url encoded.
+type WebAuthnCredential struct {
+	ID       string     `json:"id"`
+	Name     string     `json:"name"`
+	Created  *time.Time `json:"created"`
+	LastUsed *time.Time `json:"lastUsed"`
+}
+
+// FromDomainWebAuthnCredential converts domain WebAuthn credential to public WebAuthn credential.
+func FromDomainWebAuthnCredential(credential *domain.WebAuthnCredential) *WebAuthnCredential {
+	return &WebAuthnCredential{
+		ID:       base64.RawURLEncoding.EncodeToString(credential.ID),
+		Name:     credential.Name,
+		Created:  credential.Created,
+		LastUsed: credential.LastUsed,
+	}
+}
+
+func credentialDescriptors(credentials []protocol.CredentialDescriptor) []WebAuthnCredentialDescriptor {
+	descriptors := make([]WebAuthnCredentialDescriptor, 0, len(credentials))
+
+	for _, credential := range credentials {
+		descriptors = append(descriptors, WebAuthnCredentialDescriptor{
+			Type: string(credential.Type),
+			ID:   credential.CredentialID.String(),
+		})
+	}
+
+	return descriptors
+}
@@ -0,0 +1,25 @@
+package token
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const emailOTPModulo = 1_000_000
+
+// NewEmailOTPCode generates uniformly distributed random 6-digit code to be sent per email.
+func NewEmailOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(emailOTPModulo))
+	if err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// HashEmailOTPCode hashes code using sha256 binding it to the user and purpose, so that the same code of another
+// challenge produces different hash.
+func HashEmailOTPCode(userID, purpose, code string) []byte {
+	return Hash(userID + ":" + purpose + ":" + code)
+}
@@ -0,0 +1,40 @@
+package token_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestNewEmailOTPCode(t *testing.T) {
+	t.Parallel()
+
+	code, err := token.NewEmailOTPCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`^\d{6}$`).MatchString(code) {
+		t.Errorf("NewEmailOTPCode() = %q; want 6 digits", code)
+	}
+}
+
+func TestHashEmailOTPCode(t *testing.T) {
+	t.Parallel()
+
+	want := token.HashEmailOTPCode("user", "login", "123456")
+
+	if got := token.HashEmailOTPCode("user", "login", "123456"); !bytes.Equal(got, want) {
+		t.Errorf("HashEmailOTPCode() = %x; want %x", got, want)
+	}
+
+	if got := token.HashEmailOTPCode("user", "step_up", "123456"); bytes.Equal(got, want) {
+		t.Errorf("HashEmailOTPCode() of another purpose = %x; want different hash", got)
+	}
+
+	if got := token.HashEmailOTPCode("other", "login", "123456"); bytes.Equal(got, want) {
+		t.Errorf("HashEmailOTPCode() of another user = %x; want different hash", got)
+	}
+}
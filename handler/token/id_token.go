@@ -0,0 +1,57 @@
+package token
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypeID is set in type claim of OpenID Connect ID tokens.
+const TypeID = "id"
+
+// IDTokenClaims contains claims of the OpenID Connect ID token.
+type IDTokenClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Audience      string
+	Nonce         string
+	AuthTime      time.Time
+}
+
+// IDToken generates OpenID Connect ID token. ID token expires at the same time as auth token. Email claims are
+// omitted if email is empty.
+func (j *JWT) IDToken(idTokenClaims *IDTokenClaims) (string, error) {
+	now := time.Now()
+
+	tokenID, err := NewID()
+	if err != nil {
+		return "", fmt.Errorf("id token id: %w", err)
+	}
+
+	claims := map[string]interface{}{
+		"iss":       j.issuer,
+		"sub":       idTokenClaims.Subject,
+		"aud":       idTokenClaims.Audience,
+		"exp":       now.Add(j.authTokenExp).Unix(),
+		"iat":       now.Unix(),
+		"jti":       tokenID,
+		"auth_time": idTokenClaims.AuthTime.Unix(),
+		ClaimType:   TypeID,
+	}
+
+	if idTokenClaims.Email != "" {
+		claims["email"] = idTokenClaims.Email
+		claims["email_verified"] = idTokenClaims.EmailVerified
+	}
+
+	if idTokenClaims.Nonce != "" {
+		claims["nonce"] = idTokenClaims.Nonce
+	}
+
+	idToken, err := j.encode(claims)
+	if err != nil {
+		return "", fmt.Errorf("encode id token: %w", err)
+	}
+
+	return idToken, nil
+}
@@ -0,0 +1,56 @@
+package token_test
+
+import (
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestIDToken(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	idToken, err := jwt.IDToken(&token.IDTokenClaims{
+		Subject:       "user",
+		Email:         "john.doe@example.com",
+		EmailVerified: true,
+		Audience:      "client",
+		Nonce:         "nonce",
+		AuthTime:      time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedIDToken, err := jwt.Verify(idToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := token.ClaimsFromToken(verifiedIDToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Type != token.TypeID || claims.Subject != "user" {
+		t.Errorf("ClaimsFromToken() = %+v; want type %q and subject user", claims, token.TypeID)
+	}
+
+	if aud := verifiedIDToken.Audience(); len(aud) != 1 || aud[0] != "client" {
+		t.Errorf("aud = %v; want [client]", aud)
+	}
+
+	privateClaims := verifiedIDToken.PrivateClaims()
+
+	if privateClaims["email"] != "john.doe@example.com" || privateClaims["email_verified"] != true ||
+		privateClaims["nonce"] != "nonce" {
+		t.Errorf("private claims = %v; want email, email_verified and nonce", privateClaims)
+	}
+}
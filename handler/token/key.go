@@ -0,0 +1,209 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = string(jwa.HS256)
+	RS256 = string(jwa.RS256)
+	ES256 = string(jwa.ES256)
+	EdDSA = string(jwa.EdDSA)
+)
+
+const minRSAKeyBits = 2048
+
+// Key errors.
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrKeyMismatch          = errors.New("private key does not match algorithm")
+	ErrEmptyPrivateKey      = errors.New("empty private key path")
+)
+
+// Key contains signing key and, for asymmetric algorithms, its public counterpart.
+type Key struct {
+	algorithm string
+	private   jwk.Key
+	public    jwk.Key
+}
+
+// NewKey creates HS256 key from the shared secret or loads asymmetric key from the PEM file.
+func NewKey(algorithm, secret, privateKeyPath string) (*Key, error) {
+	if algorithm == HS256 {
+		return NewSymmetricKey(secret)
+	}
+
+	return LoadPrivateKey(algorithm, privateKeyPath)
+}
+
+// NewSymmetricKey creates HS256 key from the shared secret.
+func NewSymmetricKey(secret string) (*Key, error) {
+	if secret == "" {
+		return nil, ErrEmptySecret
+	}
+
+	private, err := jwk.FromRaw([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("symmetric key: %w", err)
+	}
+
+	key := &Key{algorithm: HS256, private: private, public: nil}
+
+	if err := key.setHeaders(private); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// LoadPrivateKey loads PEM encoded private key for the asymmetric algorithm.
+func LoadPrivateKey(algorithm, path string) (*Key, error) {
+	if path == "" {
+		return nil, ErrEmptyPrivateKey
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+
+	return ParsePrivateKey(algorithm, data)
+}
+
+// ParsePrivateKey parses PEM encoded private key for the asymmetric algorithm.
+func ParsePrivateKey(algorithm string, data []byte) (*Key, error) {
+	private, err := jwk.ParseKey(data, jwk.WithPEM(true))
+	if err != nil {
+		return nil, fmt.Errorf("parse private key: %w", err)
+	}
+
+	var raw interface{}
+
+	if err := private.Raw(&raw); err != nil {
+		return nil, fmt.Errorf("raw private key: %w", err)
+	}
+
+	if err := checkKeyAlgorithm(algorithm, raw); err != nil {
+		return nil, err
+	}
+
+	public, err := private.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("public key: %w", err)
+	}
+
+	key := &Key{algorithm: algorithm, private: private, public: public}
+
+	if err := key.setHeaders(private); err != nil {
+		return nil, err
+	}
+
+	if err := key.setHeaders(public); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// GenerateKey generates new private key for the asymmetric algorithm and returns it together with its PEM encoding.
+func GenerateKey(algorithm string) (*Key, []byte, error) {
+	var (
+		private crypto.PrivateKey
+		err     error
+	)
+
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	case ES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate private key: %w", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal private key: %w", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}) //nolint:exhaustruct
+
+	key, err := ParsePrivateKey(algorithm, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, data, nil
+}
+
+// ID returns key id.
+func (k *Key) ID() string {
+	return k.private.KeyID()
+}
+
+// Algorithm returns signing algorithm.
+func (k *Key) Algorithm() string {
+	return k.algorithm
+}
+
+// IsSymmetric checks if key uses shared secret.
+func (k *Key) IsSymmetric() bool {
+	return k.public == nil
+}
+
+func (k *Key) setHeaders(jwkKey jwk.Key) error {
+	if err := jwk.AssignKeyID(jwkKey); err != nil {
+		return fmt.Errorf("assign key id: %w", err)
+	}
+
+	if err := jwkKey.Set(jwk.AlgorithmKey, jwa.SignatureAlgorithm(k.algorithm)); err != nil {
+		return fmt.Errorf("set key algorithm: %w", err)
+	}
+
+	if err := jwkKey.Set(jwk.KeyUsageKey, jwk.ForSignature); err != nil {
+		return fmt.Errorf("set key usage: %w", err)
+	}
+
+	return nil
+}
+
+func checkKeyAlgorithm(algorithm string, raw interface{}) error {
+	switch algorithm {
+	case RS256:
+		if key, ok := raw.(*rsa.PrivateKey); ok && key.N.BitLen() >= minRSAKeyBits {
+			return nil
+		}
+	case ES256:
+		if key, ok := raw.(*ecdsa.PrivateKey); ok && key.Curve == elliptic.P256() {
+			return nil
+		}
+	case EdDSA:
+		if _, ok := raw.(ed25519.PrivateKey); ok {
+			return nil
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	return fmt.Errorf("%w: %s", ErrKeyMismatch, algorithm)
+}
@@ -0,0 +1,139 @@
+package token_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestParsePrivateKey(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		algorithm  string
+		privateKey crypto.PrivateKey
+		wantErr    error
+	}{
+		"rs256":          {token.RS256, rsaKey, nil},
+		"es256":          {token.ES256, ecdsaKey, nil},
+		"eddsa":          {token.EdDSA, ed25519Key, nil},
+		"key mismatch":   {token.ES256, rsaKey, token.ErrKeyMismatch},
+		"unsupported":    {"PS512", rsaKey, token.ErrUnsupportedAlgorithm},
+		"symmetric alg.": {token.HS256, rsaKey, token.ErrUnsupportedAlgorithm},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			der, err := x509.MarshalPKCS8PrivateKey(test.privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			key, gotErr := token.ParsePrivateKey(test.algorithm,
+				pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})) //nolint:exhaustruct
+			if test.wantErr != nil {
+				if !errors.Is(gotErr, test.wantErr) {
+					t.Fatalf("ParsePrivateKey() = error %v; want error %v", gotErr, test.wantErr)
+				}
+
+				return
+			}
+
+			if gotErr != nil {
+				t.Fatal(gotErr)
+			}
+
+			jwt := token.NewJWT("test", token.NewStaticKeyring(key), 0, time.Hour)
+
+			authToken, refreshToken, err := jwt.Tokens("user", "session", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := jwt.ParseRefreshToken(refreshToken); err != nil {
+				t.Errorf("ParseRefreshToken() = error %v; want nil", err)
+			}
+
+			message, err := jws.Parse([]byte(authToken))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if kid := message.Signatures()[0].ProtectedHeaders().KeyID(); kid == "" || kid != key.ID() {
+				t.Errorf("kid = %q; want %q", kid, key.ID())
+			}
+
+			set, err := jwt.JWKS()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if set.Len() != 1 {
+				t.Fatalf("JWKS() = %d keys; want 1", set.Len())
+			}
+
+			data, err := json.Marshal(set)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if strings.Contains(string(data), `"d":`) {
+				t.Errorf("JWKS() = %s; contains private key", data)
+			}
+		})
+	}
+}
+
+func TestNewSymmetricKey(t *testing.T) {
+	t.Parallel()
+
+	if _, err := token.NewSymmetricKey(""); !errors.Is(err, token.ErrEmptySecret) {
+		t.Errorf(`NewSymmetricKey("") = error %v; want %v`, err, token.ErrEmptySecret)
+	}
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour).JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if set.Len() != 0 {
+		t.Errorf("JWKS() = %d keys; want 0", set.Len())
+	}
+}
@@ -0,0 +1,331 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+const (
+	keyFileExt        = ".pem"
+	keyFileTimeLayout = "20060102150405.000000000"
+	keyFilePerm       = 0o600
+
+	// unknownKeyReloadInterval is minimal interval between reloads caused by tokens signed by unknown keys.
+	unknownKeyReloadInterval = 10 * time.Second
+)
+
+// Keyring errors.
+var (
+	ErrRotationUnsupported = errors.New("key rotation requires keys directory")
+	ErrNoKeys              = errors.New("no keys")
+	ErrSymmetricKeysDir    = errors.New("keys directory requires asymmetric algorithm")
+)
+
+// Keyring contains current signing key and previous keys which are still valid for token verification.
+// Keys are stored in directory as PEM files named by their creation time, so that the newest key is used to
+// sign new tokens and previous ones are retired at the creation time of their successor. Retired keys remain
+// valid for verification and are exposed in JWKS during the retention period, which should be at least as long
+// as the lifetime of the longest living token.
+type Keyring struct {
+	mu        sync.RWMutex
+	dir       string
+	algorithm string
+	retention time.Duration
+	current   *Key
+	keys      []*keyringEntry
+	// unknownKeyReloaded is time of the last reload caused by token signed by unknown key.
+	unknownKeyReloaded time.Time
+}
+
+type keyringEntry struct {
+	key     *Key
+	path    string
+	created time.Time
+	retired time.Time
+}
+
+// NewStaticKeyring creates keyring containing just the provided key. Such keyring cannot be rotated.
+func NewStaticKeyring(key *Key) *Keyring {
+	return &Keyring{ //nolint:exhaustruct
+		algorithm: key.algorithm,
+		current:   key,
+		keys:      []*keyringEntry{{key: key}}, //nolint:exhaustruct
+	}
+}
+
+// NewKeyring creates keyring for keys of the asymmetric algorithm stored in directory. Keys are not loaded until
+// Reload or Rotate is called.
+func NewKeyring(dir, algorithm string, retention time.Duration) *Keyring {
+	return &Keyring{ //nolint:exhaustruct
+		dir:       dir,
+		algorithm: algorithm,
+		retention: retention,
+	}
+}
+
+// LoadKeyring creates keyring and loads keys from directory. If there are no keys, the first one is generated.
+// Symmetric keys cannot be stored in directory, so HS256 algorithm is refused.
+func LoadKeyring(dir, algorithm string, retention time.Duration) (*Keyring, error) {
+	if algorithm == HS256 {
+		return nil, fmt.Errorf("%w: %s", ErrSymmetricKeysDir, algorithm)
+	}
+
+	keyring := NewKeyring(dir, algorithm, retention)
+
+	if err := keyring.Reload(); err != nil {
+		if !errors.Is(err, ErrNoKeys) {
+			return nil, err
+		}
+
+		if _, err := keyring.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return keyring, nil
+}
+
+// Reload reloads keys from directory.
+func (k *Keyring) Reload() error {
+	if k.dir == "" {
+		return nil
+	}
+
+	entries, err := loadKeyringEntries(k.dir, k.algorithm)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	keys := make([]*keyringEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.isExpired(now, k.retention) {
+			continue
+		}
+
+		keys = append(keys, entry)
+	}
+
+	if len(keys) == 0 {
+		return ErrNoKeys
+	}
+
+	k.mu.Lock()
+	k.current = keys[len(keys)-1].key
+	k.keys = keys
+	k.mu.Unlock()
+
+	return nil
+}
+
+// Rotate generates new signing key and retires the current one. Keys retired longer than the retention period
+// are removed from directory.
+func (k *Keyring) Rotate() (*Key, error) {
+	if k.dir == "" {
+		return nil, ErrRotationUnsupported
+	}
+
+	if k.algorithm == HS256 {
+		return nil, fmt.Errorf("%w: %s", ErrSymmetricKeysDir, k.algorithm)
+	}
+
+	key, data, err := GenerateKey(k.algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(k.dir, time.Now().UTC().Format(keyFileTimeLayout)+keyFileExt)
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, keyFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("create key file: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("write key file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("close key file: %w", err)
+	}
+
+	if err := k.prune(); err != nil {
+		return nil, err
+	}
+
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// PublicKeys returns set of public keys of the current and retired keys. Symmetric keys are never exposed.
+func (k *Keyring) PublicKeys() (jwk.Set, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := jwk.NewSet()
+
+	for _, entry := range k.keys {
+		if entry.key.IsSymmetric() {
+			continue
+		}
+
+		if err := set.AddKey(entry.key.public); err != nil {
+			return nil, fmt.Errorf("add key: %w", err)
+		}
+	}
+
+	return set, nil
+}
+
+// reloadIfUnknown reloads keys from directory if keyring does not contain key identified by kid, which may have been
+// rotated by another instance since the last periodic reload. Such reloads are throttled, so that tokens with forged
+// kid cannot enforce reading the directory on each request.
+func (k *Keyring) reloadIfUnknown(kid string) error {
+	if k.dir == "" || kid == "" {
+		return nil
+	}
+
+	k.mu.Lock()
+
+	if k.contains(kid) || time.Since(k.unknownKeyReloaded) < unknownKeyReloadInterval {
+		k.mu.Unlock()
+
+		return nil
+	}
+
+	k.unknownKeyReloaded = time.Now()
+	k.mu.Unlock()
+
+	return k.Reload()
+}
+
+// contains checks if keyring contains key identified by kid. Caller has to hold the lock.
+func (k *Keyring) contains(kid string) bool {
+	for _, entry := range k.keys {
+		if entry.key.ID() == kid {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (k *Keyring) signingKey() *Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current
+}
+
+func (k *Keyring) verificationKeys() (jwk.Set, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := jwk.NewSet()
+	now := time.Now()
+
+	for _, entry := range k.keys {
+		if entry.isExpired(now, k.retention) {
+			continue
+		}
+
+		key := entry.key.public
+		if entry.key.IsSymmetric() {
+			key = entry.key.private
+		}
+
+		if err := set.AddKey(key); err != nil {
+			return nil, fmt.Errorf("add key: %w", err)
+		}
+	}
+
+	return set, nil
+}
+
+func (k *Keyring) prune() error {
+	entries, err := loadKeyringEntries(k.dir, k.algorithm)
+	if err != nil && !errors.Is(err, ErrNoKeys) {
+		return err
+	}
+
+	now := time.Now()
+
+	for _, entry := range entries {
+		if !entry.isExpired(now, k.retention) {
+			continue
+		}
+
+		if err := os.Remove(entry.path); err != nil {
+			return fmt.Errorf("remove expired key: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// keyID returns kid header of the token, or empty string if token cannot be parsed.
+func keyID(tokenString string) string {
+	message, err := jws.ParseString(tokenString)
+	if err != nil || len(message.Signatures()) == 0 {
+		return ""
+	}
+
+	return message.Signatures()[0].ProtectedHeaders().KeyID()
+}
+
+func (e *keyringEntry) isExpired(now time.Time, retention time.Duration) bool {
+	return !e.retired.IsZero() && now.After(e.retired.Add(retention))
+}
+
+// loadKeyringEntries loads keys from directory sorted by creation time.
+func loadKeyringEntries(dir, algorithm string) ([]*keyringEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+keyFileExt))
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+
+	entries := make([]*keyringEntry, 0, len(paths))
+
+	for _, path := range paths {
+		created, err := time.Parse(keyFileTimeLayout, strings.TrimSuffix(filepath.Base(path), keyFileExt))
+		if err != nil {
+			continue
+		}
+
+		key, err := LoadPrivateKey(algorithm, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		entries = append(entries, &keyringEntry{key: key, path: path, created: created}) //nolint:exhaustruct
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].created.Before(entries[j].created)
+	})
+
+	for i := 0; i < len(entries)-1; i++ {
+		entries[i].retired = entries[i+1].created
+	}
+
+	return entries, nil
+}
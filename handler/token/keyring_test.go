@@ -0,0 +1,141 @@
+package token_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestKeyringRotate(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	keyring, err := token.LoadKeyring(dir, token.ES256, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", keyring, time.Hour, time.Hour)
+
+	oldAuthToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := keyring.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newAuthToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, authToken := range map[string]string{"old": oldAuthToken, "new": newAuthToken} {
+		if _, err := jwt.Verify(authToken); err != nil {
+			t.Errorf("Verify(%s token) = error %v; want nil", name, err)
+		}
+	}
+
+	set, err := jwt.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if set.Len() != 2 {
+		t.Errorf("JWKS() = %d keys; want 2", set.Len())
+	}
+
+	if _, ok := set.LookupKeyID(newKey.ID()); !ok {
+		t.Errorf("JWKS() does not contain new key %s", newKey.ID())
+	}
+
+	expiringKeyring := token.NewKeyring(dir, token.ES256, 0)
+
+	if _, err := expiringKeyring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := token.NewJWT("test", expiringKeyring, time.Hour, time.Hour).Verify(oldAuthToken); err == nil {
+		t.Error("Verify(old token) after retention = error nil; want error")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 1 {
+		t.Errorf("keys directory contains %d keys; want 1 after pruning", len(files))
+	}
+}
+
+func TestKeyringReloadUnknownKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	keyring, err := token.LoadKeyring(dir, token.ES256, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKeyring, err := token.LoadKeyring(dir, token.ES256, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := otherKeyring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	authToken, _, err := token.NewJWT("test", otherKeyring, time.Hour, time.Hour).Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := token.NewJWT("test", keyring, time.Hour, time.Hour).Verify(authToken); err != nil {
+		t.Errorf("Verify(token signed by rotated key) = error %v; want nil", err)
+	}
+}
+
+func TestLoadKeyringSymmetric(t *testing.T) {
+	t.Parallel()
+
+	if _, err := token.LoadKeyring(t.TempDir(), token.HS256, time.Hour); !errors.Is(err, token.ErrSymmetricKeysDir) {
+		t.Errorf("LoadKeyring() = error %v; want %v", err, token.ErrSymmetricKeysDir)
+	}
+}
+
+func TestStaticKeyringRotate(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := token.NewStaticKeyring(key).Rotate(); !errors.Is(err, token.ErrRotationUnsupported) {
+		t.Errorf("Rotate() = error %v; want %v", err, token.ErrRotationUnsupported)
+	}
+}
+
+func TestLoadKeyringInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "20220101000000.000000000.pem"), []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := token.LoadKeyring(dir, token.ES256, time.Hour); err == nil {
+		t.Error("LoadKeyring() = error nil; want error")
+	}
+}
@@ -0,0 +1,56 @@
+package token
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypeMFA is set in type claim of MFA challenge tokens.
+const TypeMFA = "mfa"
+
+// MFATokenExp is expiration of MFA challenge token. User has to complete the second factor within this period.
+const MFATokenExp = 5 * time.Minute
+
+// MFAToken generates MFA challenge token proving that the user has already been authenticated by the first factor.
+// It may be exchanged for auth and refresh tokens only together with the valid second factor.
+func (j *JWT) MFAToken(userID string) (string, error) {
+	now := time.Now()
+
+	tokenID, err := NewID()
+	if err != nil {
+		return "", fmt.Errorf("mfa token id: %w", err)
+	}
+
+	mfaToken, err := j.encode(map[string]interface{}{
+		"iss":     j.issuer,
+		"exp":     now.Add(MFATokenExp).Unix(),
+		"iat":     now.Unix(),
+		"jti":     tokenID,
+		"sub":     userID,
+		ClaimType: TypeMFA,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode mfa token: %w", err)
+	}
+
+	return mfaToken, nil
+}
+
+// ParseMFAToken validates MFA challenge token and returns its claims.
+func (j *JWT) ParseMFAToken(mfaToken string) (*Claims, error) {
+	token, err := j.Verify(mfaToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify mfa token: %w", err)
+	}
+
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != TypeMFA {
+		return nil, ErrInvalidTokenType
+	}
+
+	return claims, nil
+}
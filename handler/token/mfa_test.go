@@ -0,0 +1,77 @@
+package token_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestMFAToken(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	mfaToken, err := jwt.MFAToken("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := jwt.ParseMFAToken(mfaToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "user" || claims.Type != token.TypeMFA {
+		t.Errorf("ParseMFAToken() = %+v; want subject user and type mfa", claims)
+	}
+
+	authToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwt.ParseMFAToken(authToken); !errors.Is(err, token.ErrInvalidTokenType) {
+		t.Errorf("ParseMFAToken(authToken) = error %v; want %v", err, token.ErrInvalidTokenType)
+	}
+}
+
+func TestStepUpToken(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	stepUpToken, err := jwt.StepUpToken("user", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := jwt.ParseStepUpToken(stepUpToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "user" || claims.SessionID != "session" || claims.Type != token.TypeStepUp {
+		t.Errorf("ParseStepUpToken() = %+v; want subject user, session and type step_up", claims)
+	}
+
+	mfaToken, err := jwt.MFAToken("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwt.ParseStepUpToken(mfaToken); !errors.Is(err, token.ErrInvalidTokenType) {
+		t.Errorf("ParseStepUpToken(mfaToken) = error %v; want %v", err, token.ErrInvalidTokenType)
+	}
+}
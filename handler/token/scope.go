@@ -0,0 +1,43 @@
+package token
+
+import "strings"
+
+// Supported OpenID Connect scopes.
+const (
+	ScopeOpenID = "openid"
+	ScopeEmail  = "email"
+)
+
+// SupportedScopes returns scopes which may be requested by OAuth clients.
+func SupportedScopes() []string {
+	return []string{ScopeOpenID, ScopeEmail}
+}
+
+// ParseScope splits space delimited scope into distinct scopes.
+func ParseScope(scope string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+
+	for _, s := range strings.Fields(scope) {
+		if seen[s] {
+			continue
+		}
+
+		seen[s] = true
+
+		scopes = append(scopes, s)
+	}
+
+	return scopes
+}
+
+// HasScope checks if space delimited scope contains provided scope.
+func HasScope(scope, s string) bool {
+	for _, field := range strings.Fields(scope) {
+		if field == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package token
+
+import (
+	"fmt"
+	"time"
+)
+
+// TypeStepUp is set in type claim of step-up tokens.
+const TypeStepUp = "step_up"
+
+// StepUpTokenExp is expiration of step-up token. Sensitive operations are allowed within this period after the user
+// has re-authenticated.
+const StepUpTokenExp = 5 * time.Minute
+
+// StepUpToken generates step-up token proving that the user has recently re-authenticated within the session.
+func (j *JWT) StepUpToken(userID, sessionID string) (string, error) {
+	now := time.Now()
+
+	tokenID, err := NewID()
+	if err != nil {
+		return "", fmt.Errorf("step-up token id: %w", err)
+	}
+
+	stepUpToken, err := j.encode(map[string]interface{}{
+		"iss":          j.issuer,
+		"exp":          now.Add(StepUpTokenExp).Unix(),
+		"iat":          now.Unix(),
+		"jti":          tokenID,
+		"sub":          userID,
+		ClaimType:      TypeStepUp,
+		ClaimSessionID: sessionID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode step-up token: %w", err)
+	}
+
+	return stepUpToken, nil
+}
+
+// ParseStepUpToken validates step-up token and returns its claims.
+func (j *JWT) ParseStepUpToken(stepUpToken string) (*Claims, error) {
+	token, err := j.Verify(stepUpToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify step-up token: %w", err)
+	}
+
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != TypeStepUp {
+		return nil, ErrInvalidTokenType
+	}
+
+	return claims, nil
+}
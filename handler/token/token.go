@@ -2,70 +2,334 @@
 package token
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/go-chi/jwtauth/v5"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// Token types set in type claim.
+const (
+	TypeAuth    = "auth"
+	TypeRefresh = "refresh"
+)
+
+// Private claim names.
+const (
+	ClaimType      = "type"
+	ClaimSessionID = "sid"
+	ClaimClientID  = "client_id"
+	ClaimScope     = "scope"
 )
 
 // Errors.
 var (
-	ErrEmptySecret = errors.New("empty secret")
+	ErrEmptySecret      = errors.New("empty secret")
+	ErrInvalidToken     = errors.New("invalid token")
+	ErrInvalidTokenType = errors.New("invalid token type")
+	ErrEmptySubject     = errors.New("empty subject")
+	ErrEmptySessionID   = errors.New("empty session id")
 )
 
 // JWT is used to generate jwt tokens.
 type JWT struct {
-	jwtauth         *jwtauth.JWTAuth
+	keyring         *Keyring
 	issuer          string
 	authTokenExp    time.Duration
 	refreshTokenExp time.Duration
 }
 
-// NewJWT creates new jwt.
-func NewJWT(issuer, secret string, authTokenExp, refreshTokenExp time.Duration) (*JWT, error) {
-	if secret == "" {
-		return nil, ErrEmptySecret
-	}
-
+// NewJWT creates new jwt signing tokens with the current key of the provided keyring.
+func NewJWT(issuer string, keyring *Keyring, authTokenExp, refreshTokenExp time.Duration) *JWT {
 	return &JWT{
-		jwtauth:         jwtauth.New("HS256", []byte(secret), nil),
+		keyring:         keyring,
 		issuer:          issuer,
 		authTokenExp:    authTokenExp,
 		refreshTokenExp: refreshTokenExp,
-	}, nil
+	}
 }
 
-// Tokens generates auth and refresh jwt tokens.
-func (j *JWT) Tokens(userID, requestID string) (authToken, refreshToken string, err error) { //nolint:nonamedreturns
+// Grant contains authorization granted to the OAuth client. Tokens issued to first-party applications have no grant.
+type Grant struct {
+	ClientID string
+	Scope    string
+}
+
+// Claims contains claims of the verified token.
+type Claims struct {
+	ID        string
+	Type      string
+	Subject   string
+	SessionID string
+	ClientID  string
+	Scope     string
+	IssuedAt  time.Time
+	Expires   time.Time
+}
+
+// ClaimsFromToken extracts claims from the verified jwt token.
+func ClaimsFromToken(token jwt.Token) (*Claims, error) {
+	if token == nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{ //nolint:exhaustruct
+		ID:       token.JwtID(),
+		Subject:  token.Subject(),
+		IssuedAt: token.IssuedAt(),
+		Expires:  token.Expiration(),
+	}
+
+	if claims.Subject == "" {
+		return nil, ErrEmptySubject
+	}
+
+	claims.Type, _ = token.PrivateClaims()[ClaimType].(string)
+	claims.SessionID, _ = token.PrivateClaims()[ClaimSessionID].(string)
+	claims.ClientID, _ = token.PrivateClaims()[ClaimClientID].(string)
+	claims.Scope, _ = token.PrivateClaims()[ClaimScope].(string)
+
+	return claims, nil
+}
+
+// Grant returns authorization granted to the OAuth client the token was issued to or nil.
+func (c *Claims) Grant() *Grant {
+	if c.ClientID == "" {
+		return nil
+	}
+
+	return &Grant{ClientID: c.ClientID, Scope: c.Scope}
+}
+
+// Tokens generates auth and refresh jwt tokens belonging to the same session. If grant is provided, tokens are
+// bound to the OAuth client and scope.
+func (j *JWT) Tokens(userID, sessionID string, //nolint:nonamedreturns
+	grant *Grant,
+) (authToken, refreshToken string, err error) {
 	now := time.Now()
 
-	if _, authToken, err = j.jwtauth.Encode(jwt.MapClaims{
-		"iss": j.issuer,
-		"exp": now.Add(j.authTokenExp).Unix(),
-		"iat": now.Unix(),
-		"jti": requestID,
-		"sub": userID,
-	}); err != nil {
+	authTokenID, err := NewID()
+	if err != nil {
+		return "", "", fmt.Errorf("auth token id: %w", err)
+	}
+
+	if authToken, err = j.encode(withGrant(map[string]interface{}{
+		"iss":          j.issuer,
+		"exp":          now.Add(j.authTokenExp).Unix(),
+		"iat":          now.Unix(),
+		"jti":          authTokenID,
+		"sub":          userID,
+		ClaimType:      TypeAuth,
+		ClaimSessionID: sessionID,
+	}, grant)); err != nil {
 		return "", "", fmt.Errorf("encode auth token: %w", err)
 	}
 
-	if _, refreshToken, err = j.jwtauth.Encode(jwt.MapClaims{
-		"iss": j.issuer,
-		"exp": now.Add(j.refreshTokenExp).Unix(),
-		"iat": now.Unix(),
-		"nbf": now.Add(j.authTokenExp).Unix(),
-		"jti": requestID,
-		"sub": userID,
-	}); err != nil {
+	refreshTokenID, err := NewID()
+	if err != nil {
+		return "", "", fmt.Errorf("refresh token id: %w", err)
+	}
+
+	if refreshToken, err = j.encode(withGrant(map[string]interface{}{
+		"iss":          j.issuer,
+		"exp":          now.Add(j.refreshTokenExp).Unix(),
+		"iat":          now.Unix(),
+		"nbf":          now.Add(j.authTokenExp).Unix(),
+		"jti":          refreshTokenID,
+		"sub":          userID,
+		ClaimType:      TypeRefresh,
+		ClaimSessionID: sessionID,
+	}, grant)); err != nil {
 		return "", "", fmt.Errorf("encode refresh token: %w", err)
 	}
 
 	return authToken, refreshToken, nil
 }
 
-// JWTAuth returns jwt auth.
-func (j *JWT) JWTAuth() *jwtauth.JWTAuth {
-	return j.jwtauth
+// ClientToken generates auth jwt token for the OAuth client acting on its own behalf. Subject of such token is the
+// client itself and there is no refresh token.
+func (j *JWT) ClientToken(clientID, scope string) (string, error) {
+	now := time.Now()
+
+	authTokenID, err := NewID()
+	if err != nil {
+		return "", fmt.Errorf("auth token id: %w", err)
+	}
+
+	authToken, err := j.encode(withGrant(map[string]interface{}{
+		"iss":     j.issuer,
+		"exp":     now.Add(j.authTokenExp).Unix(),
+		"iat":     now.Unix(),
+		"jti":     authTokenID,
+		"sub":     clientID,
+		ClaimType: TypeAuth,
+	}, &Grant{ClientID: clientID, Scope: scope}))
+	if err != nil {
+		return "", fmt.Errorf("encode auth token: %w", err)
+	}
+
+	return authToken, nil
+}
+
+// Verify verifies token signature using keys of the keyring selected by kid and validates its claims.
+func (j *JWT) Verify(tokenString string) (jwt.Token, error) {
+	keys, err := j.verificationKeys(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseString(tokenString, jwt.WithKeySet(keys), jwt.WithValidate(true),
+		jwt.WithIssuer(j.issuer))
+	if err != nil {
+		return nil, fmt.Errorf("parse token: %w", err)
+	}
+
+	return token, nil
+}
+
+// VerifySignature verifies token signature and issuer without validating its time claims, so that expired or not
+// yet valid tokens may be revoked.
+func (j *JWT) VerifySignature(tokenString string) (jwt.Token, error) {
+	keys, err := j.verificationKeys(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseString(tokenString, jwt.WithKeySet(keys), jwt.WithValidate(false))
+	if err != nil {
+		return nil, fmt.Errorf("parse token: %w", err)
+	}
+
+	if token.Issuer() != j.issuer {
+		return nil, ErrInvalidToken
+	}
+
+	return token, nil
+}
+
+// verificationKeys returns keys for verification of the token. Keyring is reloaded first if the token is signed by
+// an unknown key, so that tokens signed by a key just rotated by another instance are accepted.
+func (j *JWT) verificationKeys(tokenString string) (jwk.Set, error) {
+	if err := j.keyring.reloadIfUnknown(keyID(tokenString)); err != nil {
+		return nil, fmt.Errorf("reload keys: %w", err)
+	}
+
+	return j.keyring.verificationKeys()
+}
+
+// ParseRefreshToken validates refresh token and returns its claims.
+func (j *JWT) ParseRefreshToken(refreshToken string) (*Claims, error) {
+	token, err := j.Verify(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify refresh token: %w", err)
+	}
+
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Type != TypeRefresh {
+		return nil, ErrInvalidTokenType
+	}
+
+	if claims.SessionID == "" {
+		return nil, ErrEmptySessionID
+	}
+
+	return claims, nil
+}
+
+// Issuer returns tokens issuer.
+func (j *JWT) Issuer() string {
+	return j.issuer
+}
+
+// Algorithm returns signing algorithm of the current key.
+func (j *JWT) Algorithm() string {
+	return j.keyring.signingKey().algorithm
+}
+
+// AuthTokenExp returns auth token expiration duration.
+func (j *JWT) AuthTokenExp() time.Duration {
+	return j.authTokenExp
+}
+
+// RefreshTokenExp returns refresh token expiration duration.
+func (j *JWT) RefreshTokenExp() time.Duration {
+	return j.refreshTokenExp
+}
+
+// JWKS returns set of public keys which may be used to verify tokens. Symmetric keys are never exposed.
+func (j *JWT) JWKS() (jwk.Set, error) {
+	return j.keyring.PublicKeys()
+}
+
+// Keyring returns keyring.
+func (j *JWT) Keyring() *Keyring {
+	return j.keyring
+}
+
+func (j *JWT) encode(claims map[string]interface{}) (string, error) {
+	token := jwt.New()
+
+	for name, value := range claims {
+		if err := token.Set(name, value); err != nil {
+			return "", fmt.Errorf("set claim %s: %w", name, err)
+		}
+	}
+
+	key := j.keyring.signingKey()
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.SignatureAlgorithm(key.algorithm), key.private))
+	if err != nil {
+		return "", fmt.Errorf("sign: %w", err)
+	}
+
+	return string(signed), nil
+}
+
+func withGrant(claims map[string]interface{}, grant *Grant) map[string]interface{} {
+	if grant != nil {
+		claims[ClaimClientID] = grant.ClientID
+		claims[ClaimScope] = grant.Scope
+	}
+
+	return claims
+}
+
+// Hash hashes token using sha256 so that it can be stored at rest.
+func Hash(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+
+	return hash[:]
+}
+
+// NewID generates random identifier in UUID format.
+func NewID() (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	return id.String(), nil
+}
+
+// NewSecret generates random URL safe string with 256 bits of entropy, used for opaque tokens like authorization
+// codes.
+func NewSecret() (string, error) {
+	var secret [32]byte
+
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret[:]), nil
 }
@@ -0,0 +1,194 @@
+package token_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	authToken, _, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedAuthToken, err := jwt.Verify(authToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authClaims, err := token.ClaimsFromToken(verifiedAuthToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authClaims.Type != token.TypeAuth {
+		t.Errorf("auth token type = %q; want %q", authClaims.Type, token.TypeAuth)
+	}
+
+	if _, err := jwt.ParseRefreshToken(authToken); !errors.Is(err, token.ErrInvalidTokenType) {
+		t.Errorf("ParseRefreshToken(authToken) = error %v; want %v", err, token.ErrInvalidTokenType)
+	}
+
+	jwt = token.NewJWT("test", token.NewStaticKeyring(key), 0, time.Hour)
+
+	_, refreshToken, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshClaims, err := jwt.ParseRefreshToken(refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshClaims.Subject != "user" || refreshClaims.SessionID != "session" {
+		t.Errorf("ParseRefreshToken() = %+v; want subject user and session id session", refreshClaims)
+	}
+
+	if authClaims.ID == "" || authClaims.ID == refreshClaims.ID {
+		t.Errorf("jti %q and %q should be unique and not empty", authClaims.ID, refreshClaims.ID)
+	}
+
+	if _, err := token.NewJWT("other", token.NewStaticKeyring(key), time.Hour, time.Hour).Verify(authToken); err == nil {
+		t.Error("Verify() with different issuer = error nil; want error")
+	}
+}
+
+func TestNewID(t *testing.T) {
+	t.Parallel()
+
+	id, err := token.NewID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(id) != 36 || id[14] != '4' {
+		t.Errorf("NewID() = %q; want version 4 uuid", id)
+	}
+}
+
+func TestNewSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := token.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := token.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(secret) != 43 || secret == other {
+		t.Errorf("NewSecret() = %q, %q; want unique 43 characters long secrets", secret, other)
+	}
+}
+
+func TestTokensWithGrant(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	authToken, _, err := jwt.Tokens("user", "session", &token.Grant{ClientID: "client", Scope: "openid email"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedAuthToken, err := jwt.Verify(authToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := token.ClaimsFromToken(verifiedAuthToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grant := claims.Grant()
+	if grant == nil || grant.ClientID != "client" || grant.Scope != "openid email" {
+		t.Errorf("Grant() = %+v; want client id client and scope openid email", grant)
+	}
+
+	if !token.HasScope(claims.Scope, token.ScopeEmail) || token.HasScope(claims.Scope, "admin") {
+		t.Errorf("HasScope(%q) reports wrong scopes", claims.Scope)
+	}
+}
+
+func TestClientToken(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	authToken, err := jwt.ClientToken("client", "jobs:write")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifiedAuthToken, err := jwt.Verify(authToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := token.ClaimsFromToken(verifiedAuthToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Type != token.TypeAuth || claims.Subject != "client" || claims.ClientID != "client" ||
+		claims.Scope != "jobs:write" || claims.SessionID != "" {
+		t.Errorf("ClaimsFromToken() = %+v; want client subject and scope without session", claims)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwt := token.NewJWT("test", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	_, refreshToken, err := jwt.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := jwt.Verify(refreshToken); err == nil {
+		t.Error("Verify() of not yet valid refresh token = error nil; want error")
+	}
+
+	if _, err := jwt.VerifySignature(refreshToken); err != nil {
+		t.Errorf("VerifySignature() = error %v; want nil", err)
+	}
+
+	other := token.NewJWT("other", token.NewStaticKeyring(key), time.Hour, time.Hour)
+
+	if _, err := other.VerifySignature(refreshToken); !errors.Is(err, token.ErrInvalidToken) {
+		t.Errorf("VerifySignature() with different issuer = error %v; want %v", err, token.ErrInvalidToken)
+	}
+}
@@ -0,0 +1,87 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+const (
+	totpSecretSize        = 20
+	recoveryCodeSize      = 10
+	recoveryCodeGroupSize = 4
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret generates random base32 encoded TOTP secret with 160 bits of entropy.
+func NewTOTPSecret() (string, error) {
+	var secret [totpSecretSize]byte
+
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", fmt.Errorf("random: %w", err)
+	}
+
+	return base32NoPadding.EncodeToString(secret[:]), nil
+}
+
+// TOTPURI generates otpauth URI which may be rendered as QR code and scanned by authenticator apps.
+func TOTPURI(issuer, account, secret string) string {
+	uri := url.URL{ //nolint:exhaustruct
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + account,
+		RawQuery: url.Values{
+			"secret":    {secret},
+			"issuer":    {issuer},
+			"algorithm": {"SHA1"},
+			"digits":    {strconv.Itoa(domain.TOTPDigits)},
+			"period":    {strconv.Itoa(int(domain.TOTPPeriod / time.Second))},
+		}.Encode(),
+	}
+
+	return uri.String()
+}
+
+// NewRecoveryCodes generates one-time recovery codes with 80 bits of entropy, formatted in groups for readability.
+func NewRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		var code [recoveryCodeSize]byte
+
+		if _, err := rand.Read(code[:]); err != nil {
+			return nil, fmt.Errorf("random: %w", err)
+		}
+
+		encoded := strings.ToLower(base32NoPadding.EncodeToString(code[:]))
+		groups := make([]string, 0, len(encoded)/recoveryCodeGroupSize)
+
+		for j := 0; j < len(encoded); j += recoveryCodeGroupSize {
+			groups = append(groups, encoded[j:j+recoveryCodeGroupSize])
+		}
+
+		codes = append(codes, strings.Join(groups, "-"))
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode normalizes recovery code, so that case and separators do not matter, and hashes it using sha256.
+func HashRecoveryCode(code string) []byte {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+
+		return r
+	}, strings.ToLower(code))
+
+	return Hash(normalized)
+}
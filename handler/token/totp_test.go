@@ -0,0 +1,79 @@
+package token_test
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"go.ectobit.com/arc/handler/token"
+)
+
+func TestNewTOTPSecret(t *testing.T) {
+	t.Parallel()
+
+	secret, err := token.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`^[A-Z2-7]{32}$`).MatchString(secret) {
+		t.Errorf("NewTOTPSecret() = %q; want 32 base32 characters", secret)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	t.Parallel()
+
+	got, err := url.Parse(token.TOTPURI("Arc", "john.doe@sixpack.com", "SECRET"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Scheme != "otpauth" || got.Host != "totp" || got.Path != "/Arc:john.doe@sixpack.com" {
+		t.Errorf("TOTPURI() = %s; want otpauth://totp/Arc:john.doe@sixpack.com", got)
+	}
+
+	query := got.Query()
+
+	if query.Get("secret") != "SECRET" || query.Get("issuer") != "Arc" || query.Get("digits") != "6" ||
+		query.Get("period") != "30" {
+		t.Errorf("TOTPURI() = query %v; want secret, issuer, digits and period", query)
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	t.Parallel()
+
+	codes, err := token.NewRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != 10 {
+		t.Fatalf("NewRecoveryCodes(10) = %d codes; want 10", len(codes))
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := map[string]bool{}
+
+	for _, code := range codes {
+		if !format.MatchString(code) || seen[code] {
+			t.Errorf("NewRecoveryCodes() = %q; want unique code formatted in groups", code)
+		}
+
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	t.Parallel()
+
+	want := token.HashRecoveryCode("abcd-efgh-ijkl-mnop")
+
+	for _, in := range []string{"ABCD-EFGH-IJKL-MNOP", "abcdefghijklmnop", "abcd efgh ijkl mnop"} {
+		if got := token.HashRecoveryCode(in); !bytes.Equal(got, want) {
+			t.Errorf("HashRecoveryCode(%q) = %x; want %x", in, got, want)
+		}
+	}
+}
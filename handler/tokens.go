@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+)
+
+// startSession generates auth and refresh tokens and starts refresh token family identified by session id.
+func startSession(ctx context.Context, jwt *token.JWT, refreshTokensRepo repository.RefreshTokens,
+	userID, familyID string, grant *token.Grant,
+) (string, string, error) {
+	authToken, refreshToken, err := jwt.Tokens(userID, familyID, grant)
+	if err != nil {
+		return "", "", fmt.Errorf("generate tokens: %w", err)
+	}
+
+	clientID := ""
+	if grant != nil {
+		clientID = grant.ClientID
+	}
+
+	if _, err := refreshTokensRepo.Create(ctx, familyID, userID, clientID, token.Hash(refreshToken),
+		time.Now().Add(jwt.RefreshTokenExp())); err != nil {
+		return "", "", fmt.Errorf("create refresh token family: %w", err)
+	}
+
+	return authToken, refreshToken, nil
+}
+
+// rotateTokens generates new auth and refresh tokens within the session of the parsed refresh token and rotates
+// the refresh token. Repository errors are wrapped so that reuse and revocation may be detected by the caller.
+func rotateTokens(ctx context.Context, jwt *token.JWT, refreshTokensRepo repository.RefreshTokens,
+	refreshToken string, claims *token.Claims,
+) (string, string, error) {
+	authToken, newRefreshToken, err := jwt.Tokens(claims.Subject, claims.SessionID, claims.Grant())
+	if err != nil {
+		return "", "", fmt.Errorf("generate tokens: %w", err)
+	}
+
+	if _, err = refreshTokensRepo.Rotate(ctx, token.Hash(refreshToken), token.Hash(newRefreshToken),
+		time.Now().Add(jwt.RefreshTokenExp())); err != nil {
+		return "", "", fmt.Errorf("rotate refresh token: %w", err)
+	}
+
+	return authToken, newRefreshToken, nil
+}
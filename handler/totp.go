@@ -0,0 +1,386 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// Second factor methods listed in MFA challenge.
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodRecoveryCode = "recovery_code"
+)
+
+const (
+	recoveryCodesCount = 10
+	maxTOTPFailures    = 5
+)
+
+// EnrollTOTP generates new TOTP secret for the current user. Enrollment has to be confirmed by the first code
+// generated by authenticator app before second factor is required at login. Step-up token is required.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/totp [post]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Success 201 {object} response.TOTPEnrollment
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Enroll TOTP second factor.
+func (h *UsersHandler) EnrollTOTP(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	secret, err := token.NewTOTPSecret()
+	if err != nil {
+		h.log.Warn("totp secret", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if _, err := h.totpsRepo.Enroll(req.Context(), domainUser.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrUniqueViolation) {
+			response.RenderErrorStatus(res, http.StatusConflict, "totp already enabled", h.log)
+
+			return
+		}
+
+		h.log.Warn("enroll totp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusCreated, &response.TOTPEnrollment{
+		Secret: secret,
+		URI:    token.TOTPURI(h.totpIssuer, domainUser.Email, secret),
+	}, h.log)
+}
+
+// ConfirmTOTP confirms TOTP enrollment of the current user by the first code and generates recovery codes, which
+// are shown just once.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/totp/confirm [post]
+// @Security BearerAuth
+// @Param code body request.TOTPCode true "TOTP code"
+// @Success 200 {object} response.RecoveryCodes
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Confirm TOTP second factor.
+func (h *UsersHandler) ConfirmTOTP(res http.ResponseWriter, req *http.Request) { //nolint:funlen,cyclop
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	totpCode, err := request.TOTPCodeFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	totp, err := h.totpsRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, "totp not enrolled", h.log)
+
+			return
+		}
+
+		h.log.Warn("find totp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if totp.IsConfirmed() {
+		response.RenderErrorStatus(res, http.StatusConflict, "totp already enabled", h.log)
+
+		return
+	}
+
+	step, ok := totp.Validate(totpCode.Code, time.Now())
+	if !ok {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid code", h.log)
+
+		return
+	}
+
+	recoveryCodes, err := token.NewRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		h.log.Warn("recovery codes", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	recoveryCodeHashes := make([][]byte, 0, len(recoveryCodes))
+
+	for _, recoveryCode := range recoveryCodes {
+		recoveryCodeHashes = append(recoveryCodeHashes, token.HashRecoveryCode(recoveryCode))
+	}
+
+	if err := h.totpsRepo.Confirm(req.Context(), claims.Subject, step, recoveryCodeHashes); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusConflict, "totp already enabled", h.log)
+
+			return
+		}
+
+		h.log.Warn("confirm totp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, &response.RecoveryCodes{RecoveryCodes: recoveryCodes}, h.log)
+}
+
+// DisableTOTP disables TOTP second factor of the current user and deletes recovery codes. Current code is required
+// and verified like at login, so failures count towards the limit and used codes can't be replayed. Step-up token is
+// required.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/totp [delete]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Param code body request.TOTPCode true "TOTP code"
+// @Success 204
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 403 {object} response.Error
+// @Failure 429 {object} response.Error
+// @Failure 500
+// @Summary Disable TOTP second factor.
+func (h *UsersHandler) DisableTOTP(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	totpCode, err := request.TOTPCodeFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	if err := h.verifyTOTP(req.Context(), claims.Subject, totpCode.Code); err != nil {
+		var reqErr *request.Error
+
+		if !errors.As(err, &reqErr) {
+			h.log.Warn("verify totp", lax.Error(err))
+		}
+
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	if err := h.totpsRepo.Delete(req.Context(), claims.Subject); err != nil {
+		h.log.Warn("delete totp", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// LoginMFA completes login by the second factor, either TOTP code, one-time recovery code or code sent per email.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/mfa [post]
+// @Param mfaLogin body request.MFALogin true "MFA challenge token and code"
+// @Success 200 {object} response.User
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 429 {object} response.Error
+// @Failure 500
+// @Summary Complete login by second factor.
+func (h *UsersHandler) LoginMFA(res http.ResponseWriter, req *http.Request) {
+	mfaLogin, err := request.MFALoginFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	claims, err := h.jwt.ParseMFAToken(mfaLogin.MFAToken)
+	if err != nil {
+		h.log.Warn("parse mfa token", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid mfa token", h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid mfa token", h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	switch {
+	case mfaLogin.RecoveryCode != "":
+		err = h.verifyRecoveryCode(req.Context(), domainUser.ID, mfaLogin.RecoveryCode)
+	case mfaLogin.EmailCode != "":
+		err = h.verifyEmailOTP(req.Context(), domainUser.ID, domain.EmailOTPLogin, mfaLogin.EmailCode)
+	default:
+		err = h.verifyTOTP(req.Context(), domainUser.ID, mfaLogin.Code)
+	}
+
+	if err != nil {
+		var reqErr *request.Error
+
+		if !errors.As(err, &reqErr) {
+			h.log.Warn("verify second factor", lax.Error(err))
+		}
+
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	h.completeLogin(res, req, domainUser)
+}
+
+// mfaMethods returns second factor methods enabled by the user.
+func (h *UsersHandler) mfaMethods(ctx context.Context, userID string) ([]string, error) {
+	methods := []string{}
+
+	totp, err := h.totpsRepo.FindOne(ctx, userID)
+	if err != nil && !errors.Is(err, repository.ErrResourceNotFound) {
+		return nil, fmt.Errorf("find totp: %w", err)
+	}
+
+	if err == nil && totp.IsConfirmed() {
+		methods = append(methods, MFAMethodTOTP, MFAMethodRecoveryCode)
+	}
+
+	credentials, err := h.webAuthnRepo.FindCredentials(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find webauthn credentials: %w", err)
+	}
+
+	if len(credentials) != 0 {
+		methods = append(methods, MFAMethodWebAuthn)
+	}
+
+	emailOTPEnabled, err := h.emailOTPsRepo.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("is email otp enabled: %w", err)
+	}
+
+	if emailOTPEnabled {
+		methods = append(methods, MFAMethodEmailOTP)
+	}
+
+	return methods, nil
+}
+
+// verifyTOTP verifies TOTP code of the user. Every attempt is counted as failure before the code is checked and
+// failures are reset just by valid code, so verification is refused after too many consecutive failures, until
+// recovery code is used, even if attempts run in parallel. Failed verification returns request error.
+func (h *UsersHandler) verifyTOTP(ctx context.Context, userID, code string) error {
+	totp, err := h.totpsRepo.FindOne(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return &request.Error{StatusCode: http.StatusUnauthorized, Message: "totp not enabled"}
+		}
+
+		return fmt.Errorf("find totp: %w", err)
+	}
+
+	if !totp.IsConfirmed() {
+		return &request.Error{StatusCode: http.StatusUnauthorized, Message: "totp not enabled"}
+	}
+
+	if err := h.totpsRepo.Attempt(ctx, userID, maxTOTPFailures); err != nil {
+		if errors.Is(err, repository.ErrTooManyAttempts) {
+			return &request.Error{StatusCode: http.StatusTooManyRequests, Message: "too many failed attempts"}
+		}
+
+		return fmt.Errorf("attempt totp: %w", err)
+	}
+
+	step, ok := totp.Validate(code, time.Now())
+	if !ok {
+		return &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid code"}
+	}
+
+	if err := h.totpsRepo.Use(ctx, userID, step); err != nil {
+		if errors.Is(err, repository.ErrTokenReused) {
+			return &request.Error{StatusCode: http.StatusUnauthorized, Message: "code already used"}
+		}
+
+		return fmt.Errorf("use totp: %w", err)
+	}
+
+	return nil
+}
+
+// verifyRecoveryCode verifies and consumes recovery code of the user. Failed verification returns request error.
+func (h *UsersHandler) verifyRecoveryCode(ctx context.Context, userID, recoveryCode string) error {
+	if err := h.totpsRepo.UseRecoveryCode(ctx, userID, token.HashRecoveryCode(recoveryCode)); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid recovery code"}
+		}
+
+		return fmt.Errorf("use recovery code: %w", err)
+	}
+
+	return nil
+}
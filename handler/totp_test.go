@@ -0,0 +1,310 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestTOTP(t *testing.T) { //nolint:funlen,cyclop
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newTOTPServer(t, jwt)
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var enrollment response.TOTPEnrollment
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/totp", authToken, "", http.StatusCreated, &enrollment)
+
+	if !strings.HasPrefix(enrollment.URI, "otpauth://totp/Arc:john.doe@sixpack.com?") ||
+		!strings.Contains(enrollment.URI, "secret="+enrollment.Secret) {
+		t.Errorf("EnrollTOTP() = uri %q; want otpauth uri with secret", enrollment.URI)
+	}
+
+	var user response.User
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusOK, &user)
+
+	step := time.Now().Unix() / int64(domain.TOTPPeriod.Seconds())
+	code := func(step int64) string {
+		code, err := domain.TOTPCode(enrollment.Secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return code
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/totp/confirm", authToken,
+		fmt.Sprintf(`{"code":%q}`, code(step-5)), http.StatusBadRequest, nil)
+
+	var recoveryCodes response.RecoveryCodes
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/totp/confirm", authToken,
+		fmt.Sprintf(`{"code":%q}`, code(step)), http.StatusOK, &recoveryCodes)
+
+	if len(recoveryCodes.RecoveryCodes) != 10 {
+		t.Fatalf("ConfirmTOTP() = %d recovery codes; want 10", len(recoveryCodes.RecoveryCodes))
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/totp", authToken, "", http.StatusConflict, nil)
+
+	var challenge response.MFAChallenge
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusAccepted,
+		&challenge)
+
+	if challenge.MFAToken == "" || len(challenge.Methods) != 2 {
+		t.Fatalf("Login() = %+v; want mfa challenge", challenge)
+	}
+
+	mfaLogin := func(field, value string) string {
+		return fmt.Sprintf(`{"mfaToken":%q,%q:%q}`, challenge.MFAToken, field, value)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", `{"mfaToken":"invalid","code":"123456"}`,
+		http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "",
+		fmt.Sprintf(`{"mfaToken":%q,"code":"123456"}`, authToken), http.StatusUnauthorized, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("code", code(step)),
+		http.StatusUnauthorized, nil)
+
+	user = response.User{} //nolint:exhaustruct
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("code", code(step+1)), http.StatusOK,
+		&user)
+
+	if user.AuthToken == "" || user.RefreshToken == "" {
+		t.Errorf("LoginMFA() = %+v; want tokens", user)
+	}
+
+	statuses := postInParallel(t, 10, server.URL+"/users/login/mfa", mfaLogin("code", "000000"))
+
+	if statuses[http.StatusUnauthorized] != 5 || statuses[http.StatusTooManyRequests] != 5 {
+		t.Errorf("parallel LoginMFA() = statuses %v; want five failures and five refusals", statuses)
+	}
+
+	recoveryCode := strings.ToUpper(recoveryCodes.RecoveryCodes[0])
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("recoveryCode", recoveryCode),
+		http.StatusOK, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("recoveryCode", recoveryCode),
+		http.StatusUnauthorized, nil)
+
+	for i := 0; i < 5; i++ {
+		doJSON(t, http.MethodDelete, server.URL+"/users/me/totp", authToken, `{"code":"000000"}`,
+			http.StatusUnauthorized, nil)
+	}
+
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/totp", authToken, fmt.Sprintf(`{"code":%q}`, code(step+1)),
+		http.StatusTooManyRequests, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/mfa", "", mfaLogin("recoveryCode", recoveryCodes.RecoveryCodes[1]),
+		http.StatusOK, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/totp", authToken, fmt.Sprintf(`{"code":%q}`, code(step+1)),
+		http.StatusUnauthorized, nil)
+}
+
+func newTOTPServer(t *testing.T, jwt *token.JWT) *httptest.Server {
+	t.Helper()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}       //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login", usersHandler.Login)
+	mux.Post("/users/login/mfa", usersHandler.LoginMFA)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Post("/users/me/totp", usersHandler.EnrollTOTP)
+		r.Post("/users/me/totp/confirm", usersHandler.ConfirmTOTP)
+		r.Delete("/users/me/totp", usersHandler.DisableTOTP)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// postInParallel posts the same JSON body n times in parallel and counts response statuses.
+func postInParallel(t *testing.T, n int, url, body string) map[int]int {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		statuses = map[int]int{}
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			res.Body.Close()
+
+			mu.Lock()
+			statuses[res.StatusCode]++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return statuses
+}
+
+func loginBody(email string) string {
+	return fmt.Sprintf(`{"email":%q,"password":%q}`, email, testPassword)
+}
+
+var _ repository.TOTPs = (*totpsRepositoryFake)(nil)
+
+type totpsRepositoryFake struct {
+	mu            sync.Mutex
+	totps         map[string]*domain.TOTP
+	recoveryCodes map[string]bool
+}
+
+func (repo *totpsRepositoryFake) Enroll(ctx context.Context, userID, secret string) (*domain.TOTP, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.totps == nil {
+		repo.totps = map[string]*domain.TOTP{}
+	}
+
+	if totp, ok := repo.totps[userID]; ok && totp.IsConfirmed() {
+		return nil, repository.ErrUniqueViolation
+	}
+
+	repo.totps[userID] = &domain.TOTP{UserID: userID, Secret: secret} //nolint:exhaustruct
+
+	return repo.totps[userID], nil
+}
+
+func (repo *totpsRepositoryFake) FindOne(ctx context.Context, userID string) (*domain.TOTP, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	totp, ok := repo.totps[userID]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	copied := *totp
+
+	return &copied, nil
+}
+
+func (repo *totpsRepositoryFake) Confirm(ctx context.Context, userID string, step int64,
+	recoveryCodeHashes [][]byte,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	now := time.Now()
+	repo.totps[userID].Confirmed = &now
+	repo.totps[userID].LastStep = step
+	repo.recoveryCodes = map[string]bool{}
+
+	for _, codeHash := range recoveryCodeHashes {
+		repo.recoveryCodes[string(codeHash)] = false
+	}
+
+	return nil
+}
+
+func (repo *totpsRepositoryFake) Use(ctx context.Context, userID string, step int64) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.totps[userID].LastStep >= step {
+		return repository.ErrTokenReused
+	}
+
+	repo.totps[userID].LastStep = step
+	repo.totps[userID].Failures = 0
+
+	return nil
+}
+
+func (repo *totpsRepositoryFake) Attempt(ctx context.Context, userID string, maxFailures int) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	totp, ok := repo.totps[userID]
+	if !ok || totp.Failures >= maxFailures {
+		return repository.ErrTooManyAttempts
+	}
+
+	totp.Failures++
+
+	return nil
+}
+
+func (repo *totpsRepositoryFake) UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if used, ok := repo.recoveryCodes[string(codeHash)]; !ok || used {
+		return repository.ErrResourceNotFound
+	}
+
+	repo.recoveryCodes[string(codeHash)] = true
+	repo.totps[userID].Failures = 0
+
+	return nil
+}
+
+func (repo *totpsRepositoryFake) Delete(ctx context.Context, userID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delete(repo.totps, userID)
+
+	return nil
+}
@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/nbutton23/zxcvbn-go"
+	"go.ectobit.com/arc/domain"
 	"go.ectobit.com/arc/handler/request"
 	"go.ectobit.com/arc/handler/response"
 	"go.ectobit.com/arc/handler/token"
@@ -16,27 +20,111 @@ import (
 	"go.ectobit.com/lax"
 )
 
+const (
+	// activationResendInterval is the minimal period between two activation links sent to the same address.
+	activationResendInterval = 5 * time.Minute
+	// magicLinkResendInterval is the minimal period between two magic links sent to the same address.
+	magicLinkResendInterval = time.Minute
+)
+
 // UsersHandler contains user related http handlers.
 type UsersHandler struct {
 	usersRepo                 repository.Users
+	refreshTokensRepo         repository.RefreshTokens
+	revocationsRepo           repository.Revocations
+	sessionsRepo              repository.Sessions
+	totpsRepo                 repository.TOTPs
+	webAuthnRepo              repository.WebAuthn
+	magicLinksRepo            repository.MagicLinks
+	emailOTPsRepo             repository.EmailOTPs
+	emailChangesRepo          repository.EmailChanges
+	invitationsRepo           repository.Invitations
+	consentsRepo              repository.Consents
 	jwt                       *token.JWT
 	sender                    send.Sender
 	externalURL               string
 	frontendPasswordResetPath string
+	frontendMagicLinkPath     string
+	frontendEmailCancelPath   string
+	activationTokenExp        time.Duration
+	recoveryTokenExp          time.Duration
+	magicLinkExp              time.Duration
+	emailOTPExp               time.Duration
+	emailChangeExp            time.Duration
+	accountDeletionGrace      time.Duration
+	totpIssuer                string
+	webAuthn                  *webauthn.WebAuthn
+	cookies                   *Cookies
+	trustedProxies            uint
 	log                       lax.Logger
 }
 
+// UsersHandlerConfig contains dependencies and settings of users handler. Links sent per email point to the frontend
+// paths at ExternalURL and tokens sent within expire after the respective durations. WebAuthn ceremonies are verified
+// by WebAuthn relying party. If Cookies are provided, tokens are set in cookies instead of being sent in response body.
+// TrustedProxies is the number of reverse proxies appending client address to X-Forwarded-For header.
+type UsersHandlerConfig struct {
+	UsersRepo                 repository.Users
+	RefreshTokensRepo         repository.RefreshTokens
+	RevocationsRepo           repository.Revocations
+	SessionsRepo              repository.Sessions
+	TOTPsRepo                 repository.TOTPs
+	WebAuthnRepo              repository.WebAuthn
+	MagicLinksRepo            repository.MagicLinks
+	EmailOTPsRepo             repository.EmailOTPs
+	EmailChangesRepo          repository.EmailChanges
+	InvitationsRepo           repository.Invitations
+	ConsentsRepo              repository.Consents
+	JWT                       *token.JWT
+	Sender                    send.Sender
+	ExternalURL               string
+	FrontendPasswordResetPath string
+	FrontendMagicLinkPath     string
+	FrontendEmailCancelPath   string
+	ActivationTokenExp        time.Duration
+	RecoveryTokenExp          time.Duration
+	MagicLinkExp              time.Duration
+	EmailOTPExp               time.Duration
+	EmailChangeExp            time.Duration
+	AccountDeletionGrace      time.Duration
+	TOTPIssuer                string
+	WebAuthn                  *webauthn.WebAuthn
+	Cookies                   *Cookies
+	TrustedProxies            uint
+	Log                       lax.Logger
+}
+
 // NewUsersHandler creates users handler.
-func NewUsersHandler(ur repository.Users, jwt *token.JWT, sender send.Sender, externalURL string,
-	frontendPasswordResetPath string, log lax.Logger,
-) *UsersHandler {
+func NewUsersHandler(cfg *UsersHandlerConfig) *UsersHandler {
 	return &UsersHandler{
-		usersRepo:                 ur,
-		jwt:                       jwt,
-		sender:                    sender,
-		externalURL:               externalURL,
-		frontendPasswordResetPath: frontendPasswordResetPath,
-		log:                       log,
+		usersRepo:                 cfg.UsersRepo,
+		refreshTokensRepo:         cfg.RefreshTokensRepo,
+		revocationsRepo:           cfg.RevocationsRepo,
+		sessionsRepo:              cfg.SessionsRepo,
+		totpsRepo:                 cfg.TOTPsRepo,
+		webAuthnRepo:              cfg.WebAuthnRepo,
+		magicLinksRepo:            cfg.MagicLinksRepo,
+		emailOTPsRepo:             cfg.EmailOTPsRepo,
+		emailChangesRepo:          cfg.EmailChangesRepo,
+		invitationsRepo:           cfg.InvitationsRepo,
+		consentsRepo:              cfg.ConsentsRepo,
+		jwt:                       cfg.JWT,
+		sender:                    cfg.Sender,
+		externalURL:               cfg.ExternalURL,
+		frontendPasswordResetPath: cfg.FrontendPasswordResetPath,
+		frontendMagicLinkPath:     cfg.FrontendMagicLinkPath,
+		frontendEmailCancelPath:   cfg.FrontendEmailCancelPath,
+		activationTokenExp:        cfg.ActivationTokenExp,
+		recoveryTokenExp:          cfg.RecoveryTokenExp,
+		magicLinkExp:              cfg.MagicLinkExp,
+		emailOTPExp:               cfg.EmailOTPExp,
+		emailChangeExp:            cfg.EmailChangeExp,
+		accountDeletionGrace:      cfg.AccountDeletionGrace,
+		totpIssuer:                cfg.TOTPIssuer,
+		webAuthn:                  cfg.WebAuthn,
+		cookies:                   cfg.Cookies,
+		trustedProxies:            cfg.TrustedProxies,
+		log:                       cfg.Log,
 	}
 }
 
@@ -60,7 +148,16 @@ func (h *UsersHandler) Register(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	domainUser, err := h.usersRepo.Create(req.Context(), userRegistration.Email, userRegistration.HashedPassword)
+	activationToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("activation token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.Create(req.Context(), userRegistration.Email, userRegistration.HashedPassword,
+		token.Hash(activationToken))
 	if err != nil {
 		if errors.Is(err, repository.ErrUniqueViolation) {
 			response.RenderErrorStatus(res, http.StatusConflict, "already registered", h.log)
@@ -74,9 +171,7 @@ func (h *UsersHandler) Register(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	message := fmt.Sprintf("%s/users/activate/%s", h.externalURL, domainUser.ActivationToken)
-
-	if err = h.sender.Send(domainUser.Email, "Account activation", message); err != nil {
+	if err = h.sendActivationLink(domainUser.Email, activationToken); err != nil {
 		h.log.Warn("send activation link", lax.Error(err))
 
 		response.Render(res, http.StatusInternalServerError, nil, h.log)
@@ -90,7 +185,7 @@ func (h *UsersHandler) Register(res http.ResponseWriter, req *http.Request) {
 	response.Render(res, http.StatusCreated, user, h.log)
 }
 
-// Activate activates user account.
+// Activate activates user account. Activation token expires after configured period.
 //
 // @Tags users
 // @Accept json
@@ -100,17 +195,19 @@ func (h *UsersHandler) Register(res http.ResponseWriter, req *http.Request) {
 // @Success 200 {object} response.User
 // @Failure 400 {object} response.Error
 // @Failure 404 {object} response.Error
+// @Failure 410 {object} response.Error
 // @Failure 500
 // @Summary Activate user account.
 func (h *UsersHandler) Activate(res http.ResponseWriter, req *http.Request) {
-	token := chi.URLParam(req, "token")
-	if token == "" {
+	activationToken := chi.URLParam(req, "token")
+	if activationToken == "" {
 		response.Render(res, http.StatusBadRequest, nil, h.log)
 
 		return
 	}
 
-	domainUser, err := h.usersRepo.Activate(req.Context(), token)
+	domainUser, err := h.usersRepo.Activate(req.Context(), token.Hash(activationToken),
+		time.Now().Add(-h.activationTokenExp))
 	if err != nil {
 		if errors.Is(err, repository.ErrResourceNotFound) {
 			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
@@ -118,6 +215,12 @@ func (h *UsersHandler) Activate(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		if errors.Is(err, repository.ErrTokenExpired) {
+			response.RenderErrorStatus(res, http.StatusGone, "activation token expired", h.log)
+
+			return
+		}
+
 		h.log.Warn("activate user account", lax.Error(err))
 		response.Render(res, http.StatusInternalServerError, nil, h.log)
 
@@ -130,14 +233,67 @@ func (h *UsersHandler) Activate(res http.ResponseWriter, req *http.Request) {
 	response.Render(res, http.StatusOK, user, h.log)
 }
 
-// Login logins user.
+// ResendActivation generates new activation token for not yet active account and sends activation link again.
+// Link can't be resent more often than once per resend interval. Response is the same whether such account exists
+// or not, so failure to send the link is just logged.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/activate/resend [post]
+// @Param email body request.Email true "E-mail address"
+// @Success 202
+// @Failure 400 {object} response.Error
+// @Failure 500
+// @Summary Resend account activation link.
+func (h *UsersHandler) ResendActivation(res http.ResponseWriter, req *http.Request) {
+	email, err := request.EmailFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	activationToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("activation token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.SetActivationToken(req.Context(), email.Email, token.Hash(activationToken),
+		time.Now().Add(-activationResendInterval))
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.Render(res, http.StatusAccepted, nil, h.log)
+
+			return
+		}
+
+		h.log.Warn("set activation token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.sendActivationLink(domainUser.Email, activationToken); err != nil {
+		h.log.Warn("send activation link", lax.Error(err))
+	}
+
+	response.Render(res, http.StatusAccepted, nil, h.log)
+}
+
+// Login logins user. If user has enabled second factor, MFA challenge token is rendered instead of tokens and
+// login has to be completed by /users/login/mfa.
 //
 // @Tags users
 // @Accept json
 // @Produce json
 // @Router /users/login [post]
 // @Param user body request.UserLogin true "User"
-// @Success 201 {object} response.User
+// @Success 200 {object} response.User
+// @Success 202 {object} response.MFAChallenge
 // @Failure 400 {object} response.Error
 // @Failure 401 {object} response.Error
 // @Failure 404 {object} response.Error
@@ -177,17 +333,13 @@ func (h *UsersHandler) Login(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	user := response.FromDomainUser(domainUser)
-	requestID := middleware.GetReqID(req.Context())
-
-	if user.AuthToken, user.RefreshToken, err = h.jwt.Tokens(user.ID, requestID); err != nil {
-		h.log.Warn("tokens", lax.Error(err))
-		response.Render(res, http.StatusInternalServerError, nil, h.log)
+	if domainUser.Status.IsBlocked(time.Now()) {
+		response.RenderErrorStatus(res, http.StatusForbidden, blockedMessage(&domainUser.Status), h.log)
 
 		return
 	}
 
-	response.Render(res, http.StatusOK, user, h.log)
+	h.continueLogin(res, req, domainUser)
 }
 
 // RequestPasswordReset requests password reset.
@@ -210,7 +362,15 @@ func (h *UsersHandler) RequestPasswordReset(res http.ResponseWriter, req *http.R
 		return
 	}
 
-	user, err := h.usersRepo.FetchRecoveryToken(req.Context(), email.Email)
+	recoveryToken, err := token.NewSecret()
+	if err != nil {
+		h.log.Warn("recovery token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	user, err := h.usersRepo.SetRecoveryToken(req.Context(), email.Email, token.Hash(recoveryToken))
 	if err != nil {
 		if errors.Is(err, repository.ErrResourceNotFound) {
 			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
@@ -224,7 +384,7 @@ func (h *UsersHandler) RequestPasswordReset(res http.ResponseWriter, req *http.R
 		return
 	}
 
-	message := fmt.Sprintf("%s/%s/%s", h.externalURL, h.frontendPasswordResetPath, user.RecoveryToken)
+	message := fmt.Sprintf("%s/%s/%s", h.externalURL, h.frontendPasswordResetPath, recoveryToken)
 
 	if err = h.sender.Send(user.Email, "Password reset request", message); err != nil {
 		h.log.Warn("send password reset token", lax.Error(err))
@@ -260,7 +420,7 @@ func (h *UsersHandler) CheckPasswordStrength(res http.ResponseWriter, req *http.
 	}, h.log)
 }
 
-// ResetPassword sets new user's password.
+// ResetPassword sets new user's password. Password reset token expires after configured period.
 //
 // @Tags users
 // @Accept json
@@ -270,6 +430,7 @@ func (h *UsersHandler) CheckPasswordStrength(res http.ResponseWriter, req *http.
 // @Success 200 {object} response.User
 // @Failure 400 {object} response.Error
 // @Failure 404 {object} response.Error
+// @Failure 410 {object} response.Error
 // @Failure 500
 // @Summary Set new user's password.
 func (h *UsersHandler) ResetPassword(res http.ResponseWriter, req *http.Request) {
@@ -280,8 +441,8 @@ func (h *UsersHandler) ResetPassword(res http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	domainUser, err := h.usersRepo.ResetPassword(req.Context(), resetPassword.RecoveryToken,
-		resetPassword.HashedPassword)
+	domainUser, err := h.usersRepo.ResetPassword(req.Context(), token.Hash(resetPassword.RecoveryToken),
+		time.Now().Add(-h.recoveryTokenExp), resetPassword.HashedPassword)
 	if err != nil {
 		if errors.Is(err, repository.ErrResourceNotFound) {
 			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
@@ -289,6 +450,12 @@ func (h *UsersHandler) ResetPassword(res http.ResponseWriter, req *http.Request)
 			return
 		}
 
+		if errors.Is(err, repository.ErrTokenExpired) {
+			response.RenderErrorStatus(res, http.StatusGone, "recovery token expired", h.log)
+
+			return
+		}
+
 		h.log.Warn("reset password", lax.Error(err))
 		response.Render(res, http.StatusInternalServerError, nil, h.log)
 
@@ -301,16 +468,435 @@ func (h *UsersHandler) ResetPassword(res http.ResponseWriter, req *http.Request)
 	response.Render(res, http.StatusAccepted, user, h.log)
 }
 
-// RefreshToken refreshes JWT authentication token.
-func (h *UsersHandler) RefreshToken(res http.ResponseWriter, req *http.Request) {
-	refreshToken, err := request.RefreshTokenFromBody(req.Body, h.log)
+// RefreshToken refreshes JWT authentication token. In cookie mode refresh token is taken from cookie if present and
+// new tokens are set in cookies. Refresh tokens issued to OAuth clients are refused, since clients have to refresh
+// them at token endpoint, authenticating themselves.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/refresh [post]
+// @Param refreshToken body request.RefreshToken false "Refresh token"
+// @Success 200 {object} response.Tokens
+// @Success 204
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 500
+// @Summary Refresh authentication token.
+func (h *UsersHandler) RefreshToken(res http.ResponseWriter, req *http.Request) { //nolint:cyclop
+	refreshToken := &request.RefreshToken{RefreshToken: ""}
+
+	if h.cookies != nil {
+		refreshToken.RefreshToken = refreshTokenFromCookie(req)
+	}
+
+	if refreshToken.RefreshToken == "" {
+		var err error
+
+		if refreshToken, err = request.RefreshTokenFromBody(req.Body, h.log); err != nil {
+			response.RenderError(res, err, h.log)
+
+			return
+		}
+	}
+
+	claims, err := h.jwt.ParseRefreshToken(refreshToken.RefreshToken)
 	if err != nil {
-		response.RenderError(res, err, h.log)
+		h.log.Warn("parse refresh token", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid refresh token", h.log)
+
+		return
+	}
+
+	if claims.ClientID != "" {
+		response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid refresh token", h.log)
+
+		return
+	}
+
+	tokens := &response.Tokens{} //nolint:exhaustruct
+
+	if tokens.AuthToken, tokens.RefreshToken, err = rotateTokens(req.Context(), h.jwt, h.refreshTokensRepo,
+		refreshToken.RefreshToken, claims); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrResourceNotFound):
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid refresh token", h.log)
+		case errors.Is(err, repository.ErrTokenReused):
+			h.log.Warn("refresh token reuse detected, family revoked", lax.String("user", claims.Subject))
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "refresh token reused", h.log)
+		case errors.Is(err, repository.ErrTokenRevoked):
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "refresh token revoked", h.log)
+		default:
+			h.log.Warn("rotate tokens", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+		}
+
+		return
+	}
+
+	if err := h.sessionsRepo.Touch(req.Context(), claims.SessionID); err != nil {
+		h.log.Warn("touch session", lax.Error(err))
+	}
+
+	if h.cookies != nil {
+		if err := h.cookies.set(res, h.jwt, tokens.AuthToken, tokens.RefreshToken); err != nil {
+			h.log.Warn("set cookies", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		response.Render(res, http.StatusNoContent, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, tokens, h.log)
+}
+
+// Logout revokes authentication token and refresh token family of the current session. In cookie mode token
+// cookies are removed.
+//
+// @Tags users
+// @Produce json
+// @Router /users/logout [post]
+// @Security BearerAuth
+// @Success 204
+// @Failure 401
+// @Failure 500
+// @Summary Logout.
+func (h *UsersHandler) Logout(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
 
 		return
 	}
 
-	_ = refreshToken
+	if err := h.revocationsRepo.Revoke(req.Context(), claims.ID, claims.Subject, claims.Expires); err != nil {
+		h.log.Warn("revoke token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if claims.SessionID != "" {
+		if err := h.refreshTokensRepo.Revoke(req.Context(), claims.SessionID); err != nil {
+			h.log.Warn("revoke refresh token family", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+	}
+
+	if h.cookies != nil {
+		h.cookies.clear(res)
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// LogoutAll revokes all tokens of the current user.
+//
+// @Tags users
+// @Produce json
+// @Router /users/logout-all [post]
+// @Security BearerAuth
+// @Success 204
+// @Failure 401
+// @Failure 500
+// @Summary Logout from all sessions.
+func (h *UsersHandler) LogoutAll(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	if err := h.revokeAll(req.Context(), claims.Subject); err != nil {
+		h.log.Warn("revoke all", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if h.cookies != nil {
+		h.cookies.clear(res)
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// UserInfo renders OpenID Connect claims about the authenticated user. Email claims are rendered to first-party
+// applications and to OAuth clients granted email scope.
+//
+// @Tags users
+// @Produce json
+// @Router /userinfo [get]
+// @Security BearerAuth
+// @Success 200 {object} response.UserInfo
+// @Failure 401
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary OpenID Connect user info.
+func (h *UsersHandler) UserInfo(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainUser, err := h.usersRepo.FindOne(req.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("find user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	withEmail := claims.ClientID == "" || token.HasScope(claims.Scope, token.ScopeEmail)
+
+	response.Render(res, http.StatusOK, response.UserInfoFromDomainUser(domainUser, withEmail), h.log)
+}
+
+// Sessions lists active sessions of the current user.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/sessions [get]
+// @Security BearerAuth
+// @Success 200 {array} response.Session
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary List active sessions.
+func (h *UsersHandler) Sessions(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainSessions, err := h.sessionsRepo.FindAll(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find all sessions", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	sessions := make([]*response.Session, 0, len(domainSessions))
+
+	for i := range domainSessions {
+		sessions = append(sessions, response.FromDomainSession(&domainSessions[i], claims.SessionID))
+	}
+
+	response.Render(res, http.StatusOK, sessions, h.log)
+}
+
+// RevokeSession revokes session of the current user, logging out the device it belongs to.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/sessions/{id} [delete]
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Revoke session.
+func (h *UsersHandler) RevokeSession(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	if err := h.sessionsRepo.Revoke(req.Context(), claims.Subject, chi.URLParam(req, "id")); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("revoke session", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// continueLogin continues login of the user authenticated by the first factor. MFA challenge is rendered if user
+// has enabled second factor, otherwise login is completed.
+func (h *UsersHandler) continueLogin(res http.ResponseWriter, req *http.Request, domainUser *domain.User) {
+	methods, err := h.mfaMethods(req.Context(), domainUser.ID)
+	if err != nil {
+		h.log.Warn("mfa methods", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if len(methods) != 0 {
+		mfaToken, err := h.jwt.MFAToken(domainUser.ID)
+		if err != nil {
+			h.log.Warn("mfa token", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		response.Render(res, http.StatusAccepted, &response.MFAChallenge{MFAToken: mfaToken, Methods: methods}, h.log)
+
+		return
+	}
+
+	h.completeLogin(res, req, domainUser)
+}
+
+// completeLogin issues tokens to the authenticated user and renders them together with user data. Suspended and
+// banned users are refused.
+func (h *UsersHandler) completeLogin(res http.ResponseWriter, req *http.Request, domainUser *domain.User) {
+	if domainUser.Status.IsBlocked(time.Now()) {
+		response.RenderErrorStatus(res, http.StatusForbidden, blockedMessage(&domainUser.Status), h.log)
+
+		return
+	}
+
+	user := response.FromDomainUser(domainUser)
+
+	var err error
+
+	if user.AuthToken, user.RefreshToken, err = h.issueTokens(req, user.ID); err != nil {
+		h.log.Warn("tokens", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if user.IDToken, err = h.jwt.IDToken(&token.IDTokenClaims{
+		Subject:       domainUser.ID,
+		Email:         domainUser.Email,
+		EmailVerified: domainUser.IsActive(),
+		Audience:      h.jwt.Issuer(),
+		Nonce:         "",
+		AuthTime:      time.Now(),
+	}); err != nil {
+		h.log.Warn("id token", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if h.cookies != nil {
+		if err := h.cookies.set(res, h.jwt, user.AuthToken, user.RefreshToken); err != nil {
+			h.log.Warn("set cookies", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		user.AuthToken, user.RefreshToken = "", ""
+	}
+
+	response.Render(res, http.StatusOK, user, h.log)
+}
+
+// blockedMessage describes why suspended or banned user is refused.
+func blockedMessage(status *domain.UserStatus) string {
+	message := "account " + status.Status
+	if status.Until != nil {
+		message += " until " + status.Until.Format(time.RFC3339)
+	}
+
+	return message
+}
+
+// issueTokens generates auth and refresh tokens, starts new refresh token family and records the session of the
+// device the request came from.
+func (h *UsersHandler) issueTokens(req *http.Request, userID string) (string, string, error) {
+	familyID, err := token.NewID()
+	if err != nil {
+		return "", "", fmt.Errorf("refresh token family id: %w", err)
+	}
+
+	authToken, refreshToken, err := startSession(req.Context(), h.jwt, h.refreshTokensRepo, userID, familyID, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	if _, err := h.sessionsRepo.Create(req.Context(), &domain.Session{ //nolint:exhaustruct
+		ID:        familyID,
+		UserID:    userID,
+		UserAgent: request.UserAgent(req),
+		IP:        request.ClientIP(req, h.trustedProxies),
+	}); err != nil {
+		return "", "", fmt.Errorf("create session: %w", err)
+	}
+
+	return authToken, refreshToken, nil
+}
+
+// revokeAll revokes all authentication tokens and refresh token families of the user.
+func (h *UsersHandler) revokeAll(ctx context.Context, userID string) error {
+	return revokeAll(ctx, h.revocationsRepo, h.refreshTokensRepo, userID)
+}
+
+// revokeAll revokes all authentication tokens and refresh token families of the user.
+func revokeAll(ctx context.Context, revocationsRepo repository.Revocations,
+	refreshTokensRepo repository.RefreshTokens, userID string,
+) error {
+	if err := revocationsRepo.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revoke tokens: %w", err)
+	}
+
+	if err := refreshTokensRepo.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("revoke refresh token families: %w", err)
+	}
+
+	return nil
+}
+
+// sendActivationLink sends account activation link containing activation token.
+func (h *UsersHandler) sendActivationLink(email, activationToken string) error {
+	message := fmt.Sprintf("%s/users/activate/%s", h.externalURL, activationToken)
+
+	if err := h.sender.Send(email, "Account activation", message); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	return nil
+}
+
+func claimsFromJWT(ctx context.Context) (*token.Claims, error) {
+	jwtToken, _, err := jwtauth.FromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("token from context: %w", err)
+	}
+
+	claims, err := token.ClaimsFromToken(jwtToken)
+	if err != nil {
+		return nil, fmt.Errorf("claims from token: %w", err)
+	}
 
-	panic("unimplemented")
+	return claims, nil
 }
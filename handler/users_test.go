@@ -3,31 +3,38 @@ package handler_test
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"go.ectobit.com/arc/domain"
 	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/response"
 	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/mw"
 	"go.ectobit.com/arc/repository"
 	"go.ectobit.com/arc/send"
 	"go.ectobit.com/lax"
 	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestRegister(t *testing.T) { //nolint:funlen
 	t.Parallel()
 
-	jwt, err := token.NewJWT("test", "test", time.Hour, time.Hour)
-	if err != nil {
-		t.Fatal(err)
-	}
+	jwt := newJWT(t, time.Hour)
 
-	log := lax.NewZapAdapter(zaptest.NewLogger(t))
-	usersHandler := handler.NewUsersHandler(&usersRepositoryFake{}, jwt, &send.Fake{}, "", "", log)
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{}) //nolint:exhaustruct
 	server := httptest.NewServer(http.HandlerFunc(usersHandler.Register))
 
 	tests := map[string]struct {
@@ -83,32 +90,1278 @@ func TestRegister(t *testing.T) { //nolint:funlen
 	}
 }
 
+func newJWT(t *testing.T, authTokenExp time.Duration) *token.JWT {
+	t.Helper()
+
+	key, err := token.NewSymmetricKey("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token.NewJWT("test", token.NewStaticKeyring(key), authTokenExp, time.Hour)
+}
+
+const testPassword = "h+z67{GxLSL~]Cl(I88AqV7w"
+
+// usersHandlerOptions contains dependencies of the users handler under test. Fakes are used for the missing ones.
+type usersHandlerOptions struct {
+	usersRepo         repository.Users
+	refreshTokensRepo repository.RefreshTokens
+	revocationsRepo   repository.Revocations
+	sessionsRepo      repository.Sessions
+	magicLinksRepo    repository.MagicLinks
+	emailOTPsRepo     repository.EmailOTPs
+	emailChangesRepo  repository.EmailChanges
+	invitationsRepo   repository.Invitations
+	consentsRepo      repository.Consents
+	sender            send.Sender
+	webAuthn          *webauthn.WebAuthn
+	cookies           *handler.Cookies
+}
+
+func newUsersHandler(t *testing.T, jwt *token.JWT, opts usersHandlerOptions) *handler.UsersHandler { //nolint:cyclop
+	t.Helper()
+
+	if opts.usersRepo == nil {
+		opts.usersRepo = &usersRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.refreshTokensRepo == nil {
+		opts.refreshTokensRepo = &refreshTokensRepositoryFake{tokens: map[string]bool{}} //nolint:exhaustruct
+	}
+
+	if opts.revocationsRepo == nil {
+		opts.revocationsRepo = &revocationsRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.sessionsRepo == nil {
+		opts.sessionsRepo = &sessionsRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.magicLinksRepo == nil {
+		opts.magicLinksRepo = &magicLinksRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.emailOTPsRepo == nil {
+		opts.emailOTPsRepo = &emailOTPsRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.emailChangesRepo == nil {
+		opts.emailChangesRepo = &emailChangesRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.invitationsRepo == nil {
+		opts.invitationsRepo = &invitationsRepositoryFake{} //nolint:exhaustruct
+	}
+
+	if opts.consentsRepo == nil {
+		opts.consentsRepo = &consentsRepositoryFake{consents: map[string][]string{}} //nolint:exhaustruct
+	}
+
+	if opts.sender == nil {
+		opts.sender = &send.Fake{}
+	}
+
+	return handler.NewUsersHandler(&handler.UsersHandlerConfig{
+		UsersRepo:                 opts.usersRepo,
+		RefreshTokensRepo:         opts.refreshTokensRepo,
+		RevocationsRepo:           opts.revocationsRepo,
+		SessionsRepo:              opts.sessionsRepo,
+		TOTPsRepo:                 &totpsRepositoryFake{},    //nolint:exhaustruct
+		WebAuthnRepo:              &webAuthnRepositoryFake{}, //nolint:exhaustruct
+		MagicLinksRepo:            opts.magicLinksRepo,
+		EmailOTPsRepo:             opts.emailOTPsRepo,
+		EmailChangesRepo:          opts.emailChangesRepo,
+		InvitationsRepo:           opts.invitationsRepo,
+		ConsentsRepo:              opts.consentsRepo,
+		JWT:                       jwt,
+		Sender:                    opts.sender,
+		ExternalURL:               "http://localhost:3000",
+		FrontendPasswordResetPath: "frontend-password-reset-path",
+		FrontendMagicLinkPath:     "frontend-magic-link-path",
+		FrontendEmailCancelPath:   "frontend-email-cancel-path",
+		ActivationTokenExp:        time.Hour,
+		RecoveryTokenExp:          time.Hour,
+		MagicLinkExp:              time.Minute,
+		EmailOTPExp:               time.Minute,
+		EmailChangeExp:            time.Hour,
+		AccountDeletionGrace:      24 * time.Hour,
+		TOTPIssuer:                "Arc",
+		WebAuthn:                  opts.webAuthn,
+		Cookies:                   opts.cookies,
+		TrustedProxies:            1,
+		Log:                       lax.NewZapAdapter(zaptest.NewLogger(t)),
+	})
+}
+
 var _ repository.Users = (*usersRepositoryFake)(nil)
 
-type usersRepositoryFake struct{}
+// usersRepositoryFake knows active users john and jane, records registered users as not yet active, issue times
+// of activation and recovery tokens by their hashes, changed passwords, status changes, deleted users and passwords
+// of users created already activated by their emails.
+type usersRepositoryFake struct {
+	mu               sync.Mutex
+	inactive         map[string]time.Time
+	activationTokens map[string]time.Time
+	recoveryTokens   map[string]time.Time
+	profiles         map[string]domain.Profile
+	passwords        map[string][]byte
+	deletions        map[string]time.Time
+	statuses         map[string][]domain.UserStatus
+	deleted          map[string]bool
+	created          map[string][]byte
+	roles            map[string]string
+}
+
+func (repo *usersRepositoryFake) Create(ctx context.Context, email string,
+	password, activationTokenHash []byte,
+) (*domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.activationTokens == nil {
+		repo.inactive = map[string]time.Time{}
+		repo.activationTokens = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	repo.inactive[email] = now
+	repo.activationTokens[string(activationTokenHash)] = now
+
+	return &domain.User{Email: email, ActivationTokenIssued: &now}, nil //nolint:exhaustruct
+}
+
+func (repo *usersRepositoryFake) CreateActivated(ctx context.Context, email string,
+	password []byte,
+) (*domain.User, error) {
+	if _, err := repo.FindOneByEmail(ctx, email); err == nil {
+		return nil, repository.ErrUniqueViolation
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.created == nil {
+		repo.created = map[string][]byte{}
+	}
+
+	repo.created[email] = password
+	active := true
+
+	return &domain.User{ID: "new", Email: email, Active: &active}, nil //nolint:exhaustruct
+}
+
+// FindAll lists john, created first, and jane. Email prefix filter, sort order and pagination are honored, while
+// cursors pointing to unknown users are invalid.
+func (repo *usersRepositoryFake) FindAll(ctx context.Context, //nolint:cyclop
+	query *repository.UsersQuery,
+) (*repository.UsersPage, error) {
+	created := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+	page := &repository.UsersPage{Users: []domain.User{}} //nolint:exhaustruct
+
+	for i, id := range []string{"john", "jane"} {
+		domainUser, err := repo.FindOne(ctx, id)
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		domainUser.Email = id + ".doe@sixpack.com"
+		domainUser.Password = nil
+		userCreated := created.Add(time.Duration(i) * time.Hour)
+		domainUser.Created = &userCreated
+
+		if strings.HasPrefix(domainUser.Email, query.EmailPrefix) {
+			page.Users = append(page.Users, *domainUser)
+		}
+	}
+
+	page.Total = int64(len(page.Users))
+
+	if query.Sort == repository.UsersByEmail {
+		sort.Slice(page.Users, func(i, j int) bool { return page.Users[i].Email < page.Users[j].Email })
+	}
+
+	if query.Descending {
+		for i, j := 0, len(page.Users)-1; i < j; i, j = i+1, j-1 {
+			page.Users[i], page.Users[j] = page.Users[j], page.Users[i]
+		}
+	}
+
+	if query.After != nil {
+		found := false
+
+		for i := range page.Users {
+			if page.Users[i].ID == query.After.ID {
+				page.Users, found = page.Users[i+1:], true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+
+	if len(page.Users) > query.Limit {
+		page.Users = page.Users[:query.Limit]
+		page.Next = &repository.Cursor{Sort: query.SortOrder(), Value: "", ID: page.Users[query.Limit-1].ID}
+	}
 
-func (repo *usersRepositoryFake) Create(ctx context.Context, email string, password []byte) (*domain.User, error) {
-	return &domain.User{}, nil //nolint:exhaustruct
+	return page, nil
 }
 
 func (repo *usersRepositoryFake) FindOne(ctx context.Context, id string) (*domain.User, error) {
-	panic("unimplemented")
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.deleted[id] {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	password, err := repo.password(id)
+	if err != nil {
+		return nil, err
+	}
+
+	active := true
+
+	domainUser := &domain.User{ //nolint:exhaustruct
+		ID: id, Email: "john.doe@sixpack.com", Password: password, Active: &active, Profile: repo.profiles[id],
+		Role: repo.roles[id],
+	}
+
+	if deletion, ok := repo.deletions[id]; ok {
+		domainUser.DeletionScheduled = &deletion
+	}
+
+	if statuses := repo.statuses[id]; len(statuses) > 0 {
+		domainUser.Status = statuses[len(statuses)-1]
+	}
+
+	return domainUser, nil
 }
 
 func (repo *usersRepositoryFake) FindOneByEmail(ctx context.Context, email string) (*domain.User, error) {
-	panic("unimplemented")
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	id, ok := map[string]string{"john.doe@sixpack.com": "john", "jane.doe@sixpack.com": "jane"}[email]
+	if !ok {
+		if password, ok := repo.created[email]; ok {
+			active := true
+
+			return &domain.User{ID: "new", Email: email, Password: password, Active: &active}, nil //nolint:exhaustruct
+		}
+
+		return nil, repository.ErrResourceNotFound
+	}
+
+	password, err := repo.password(id)
+	if err != nil {
+		return nil, err
+	}
+
+	active := true
+
+	domainUser := &domain.User{ID: id, Email: email, Password: password, Active: &active} //nolint:exhaustruct
+
+	if statuses := repo.statuses[id]; len(statuses) > 0 {
+		domainUser.Status = statuses[len(statuses)-1]
+	}
+
+	return domainUser, nil
 }
 
-func (repo *usersRepositoryFake) Activate(ctx context.Context, token string) (*domain.User, error) {
-	panic("unimplemented")
+func (repo *usersRepositoryFake) Activate(ctx context.Context, activationTokenHash []byte,
+	issuedAfter time.Time,
+) (*domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := useToken(repo.activationTokens, activationTokenHash, issuedAfter); err != nil {
+		return nil, err
+	}
+
+	active := true
+
+	return &domain.User{ID: "john", Email: "john.doe@sixpack.com", Active: &active}, nil //nolint:exhaustruct
 }
 
-func (repo *usersRepositoryFake) FetchRecoveryToken(ctx context.Context, email string) (*domain.User, error) {
-	panic("unimplemented")
+func (repo *usersRepositoryFake) UpdateProfile(ctx context.Context, id string,
+	profile *domain.Profile,
+) (*domain.User, error) {
+	repo.mu.Lock()
+
+	if repo.profiles == nil {
+		repo.profiles = map[string]domain.Profile{}
+	}
+
+	repo.profiles[id] = *profile
+
+	repo.mu.Unlock()
+
+	return repo.FindOne(ctx, id)
 }
 
-func (repo *usersRepositoryFake) ResetPassword(ctx context.Context, recoveryToken string,
-	password []byte,
+func (repo *usersRepositoryFake) ChangePassword(ctx context.Context, id string, password []byte) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.passwords == nil {
+		repo.passwords = map[string][]byte{}
+	}
+
+	repo.passwords[id] = password
+
+	return nil
+}
+
+func (repo *usersRepositoryFake) SetStatus(ctx context.Context, id string,
+	status *domain.UserStatus,
+) (*domain.User, error) {
+	repo.mu.Lock()
+
+	if repo.deleted[id] {
+		repo.mu.Unlock()
+
+		return nil, repository.ErrResourceNotFound
+	}
+
+	if repo.statuses == nil {
+		repo.statuses = map[string][]domain.UserStatus{}
+	}
+
+	changed := time.Now()
+	userStatus := *status
+	userStatus.Changed = &changed
+	repo.statuses[id] = append(repo.statuses[id], userStatus)
+
+	repo.mu.Unlock()
+
+	return repo.FindOne(ctx, id)
+}
+
+func (repo *usersRepositoryFake) FindStatusHistory(ctx context.Context, id string) ([]domain.UserStatus, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	statuses := make([]domain.UserStatus, 0, len(repo.statuses[id]))
+
+	for i := len(repo.statuses[id]) - 1; i >= 0; i-- {
+		statuses = append(statuses, repo.statuses[id][i])
+	}
+
+	return statuses, nil
+}
+
+func (repo *usersRepositoryFake) Delete(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.deleted[id] {
+		return repository.ErrResourceNotFound
+	}
+
+	if repo.deleted == nil {
+		repo.deleted = map[string]bool{}
+	}
+
+	repo.deleted[id] = true
+
+	return nil
+}
+
+func (repo *usersRepositoryFake) ScheduleDeletion(ctx context.Context, id string,
+	at time.Time,
+) (*domain.User, error) {
+	repo.mu.Lock()
+
+	if repo.deletions == nil {
+		repo.deletions = map[string]time.Time{}
+	}
+
+	repo.deletions[id] = at
+
+	repo.mu.Unlock()
+
+	return repo.FindOne(ctx, id)
+}
+
+func (repo *usersRepositoryFake) CancelDeletion(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.deletions[id]; !ok {
+		return repository.ErrResourceNotFound
+	}
+
+	delete(repo.deletions, id)
+
+	return nil
+}
+
+func (repo *usersRepositoryFake) DeleteScheduled(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (repo *usersRepositoryFake) SetActivationToken(ctx context.Context, email string, activationTokenHash []byte,
+	issuedBefore time.Time,
+) (*domain.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	issued, ok := repo.inactive[email]
+	if !ok || !issued.Before(issuedBefore) {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	now := time.Now()
+	repo.inactive[email] = now
+	repo.activationTokens[string(activationTokenHash)] = now
+
+	return &domain.User{Email: email, ActivationTokenIssued: &now}, nil //nolint:exhaustruct
+}
+
+func (repo *usersRepositoryFake) SetRecoveryToken(ctx context.Context, email string,
+	recoveryTokenHash []byte,
+) (*domain.User, error) {
+	domainUser, err := repo.FindOneByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.recoveryTokens == nil {
+		repo.recoveryTokens = map[string]time.Time{}
+	}
+
+	now := time.Now()
+	repo.recoveryTokens[string(recoveryTokenHash)] = now
+	domainUser.RecoveryTokenIssued = &now
+
+	return domainUser, nil
+}
+
+func (repo *usersRepositoryFake) ResetPassword(ctx context.Context, recoveryTokenHash []byte,
+	issuedAfter time.Time, password []byte,
 ) (*domain.User, error) {
-	panic("unimplemented")
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := useToken(repo.recoveryTokens, recoveryTokenHash, issuedAfter); err != nil {
+		return nil, err
+	}
+
+	return &domain.User{ID: "john", Email: "john.doe@sixpack.com"}, nil //nolint:exhaustruct
+}
+
+// password returns changed password of the user or hash of the test password. Mutex has to be locked.
+func (repo *usersRepositoryFake) password(id string) ([]byte, error) {
+	if password, ok := repo.passwords[id]; ok {
+		return password, nil
+	}
+
+	password, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		return nil, fmt.Errorf("bcrypt: %w", err)
+	}
+
+	return password, nil
+}
+
+// expireTokens moves issue times of all activation and recovery tokens to the distant past.
+func (repo *usersRepositoryFake) expireTokens() {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, tokens := range []map[string]time.Time{repo.inactive, repo.activationTokens, repo.recoveryTokens} {
+		for tokenHash := range tokens {
+			tokens[tokenHash] = time.Time{}
+		}
+	}
+}
+
+func useToken(tokens map[string]time.Time, tokenHash []byte, issuedAfter time.Time) error {
+	issued, ok := tokens[string(tokenHash)]
+	if !ok {
+		return repository.ErrResourceNotFound
+	}
+
+	if !issued.After(issuedAfter) {
+		return repository.ErrTokenExpired
+	}
+
+	delete(tokens, string(tokenHash))
+
+	return nil
+}
+
+func TestActivate(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}             //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo: usersRepo,
+		sender:    sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users", usersHandler.Register)
+	mux.Get("/users/activate/{token}", usersHandler.Activate)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	register := func() string {
+		doJSON(t, http.MethodPost, server.URL+"/users", "",
+			fmt.Sprintf(`{"email":"new@sixpack.com","password":%q}`, testPassword), http.StatusCreated, nil)
+
+		message, ok := sender.message("new@sixpack.com")
+		if !ok || !strings.HasPrefix(message, "http://localhost:3000/users/activate/") {
+			t.Fatalf("Register() = message %q; want activation link", message)
+		}
+
+		return message[strings.LastIndex(message, "/")+1:]
+	}
+
+	activationToken := register()
+
+	doJSON(t, http.MethodGet, server.URL+"/users/activate/unknown", "", "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/activate/"+activationToken, "", "", http.StatusOK, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/activate/"+activationToken, "", "", http.StatusNotFound, nil)
+
+	activationToken = register()
+	usersRepo.expireTokens()
+
+	doJSON(t, http.MethodGet, server.URL+"/users/activate/"+activationToken, "", "", http.StatusGone, nil)
+}
+
+func TestResendActivation(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}             //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo: usersRepo,
+		sender:    sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users", usersHandler.Register)
+	mux.Post("/users/activate/resend", usersHandler.ResendActivation)
+	mux.Get("/users/activate/{token}", usersHandler.Activate)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	resend := func(email string) {
+		doJSON(t, http.MethodPost, server.URL+"/users/activate/resend", "", fmt.Sprintf(`{"email":%q}`, email),
+			http.StatusAccepted, nil)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users", "",
+		fmt.Sprintf(`{"email":"new@sixpack.com","password":%q}`, testPassword), http.StatusCreated, nil)
+
+	registrationLink, _ := sender.message("new@sixpack.com")
+
+	resend("new@sixpack.com")
+
+	if message, _ := sender.message("new@sixpack.com"); message != registrationLink {
+		t.Errorf("ResendActivation() = message %q; want no message within resend interval", message)
+	}
+
+	resend("unknown@sixpack.com")
+	resend("john.doe@sixpack.com")
+
+	if _, ok := sender.message("unknown@sixpack.com"); ok {
+		t.Error("ResendActivation() sent link to unknown user")
+	}
+
+	if _, ok := sender.message("john.doe@sixpack.com"); ok {
+		t.Error("ResendActivation() sent link to active user")
+	}
+
+	usersRepo.expireTokens()
+	resend("new@sixpack.com")
+
+	message, _ := sender.message("new@sixpack.com")
+	if message == registrationLink || !strings.HasPrefix(message, "http://localhost:3000/users/activate/") {
+		t.Fatalf("ResendActivation() = message %q; want new activation link", message)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/users/activate/"+message[strings.LastIndex(message, "/")+1:], "", "",
+		http.StatusOK, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/activate/resend", "", `{}`, http.StatusBadRequest, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/users", "",
+		fmt.Sprintf(`{"email":"other@sixpack.com","password":%q}`, testPassword), http.StatusCreated, nil)
+	usersRepo.expireTokens()
+	sender.fail()
+	resend("other@sixpack.com")
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	sender := &senderFake{}             //nolint:exhaustruct
+	usersRepo := &usersRepositoryFake{} //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		usersRepo: usersRepo,
+		sender:    sender,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/reset-password", usersHandler.RequestPasswordReset)
+	mux.Patch("/users/reset-password", usersHandler.ResetPassword)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	requestReset := func() string {
+		doJSON(t, http.MethodPost, server.URL+"/users/reset-password", "", `{"email":"john.doe@sixpack.com"}`,
+			http.StatusAccepted, nil)
+
+		message, ok := sender.message("john.doe@sixpack.com")
+		if !ok {
+			t.Fatal("RequestPasswordReset() sent no message")
+		}
+
+		return message[strings.LastIndex(message, "/")+1:]
+	}
+
+	resetBody := func(recoveryToken string) string {
+		return fmt.Sprintf(`{"recoveryToken":%q,"password":%q}`, recoveryToken, testPassword)
+	}
+
+	recoveryToken := requestReset()
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/reset-password", "", resetBody("unknown"), http.StatusNotFound,
+		nil)
+	doJSON(t, http.MethodPatch, server.URL+"/users/reset-password", "", resetBody(recoveryToken),
+		http.StatusAccepted, nil)
+	doJSON(t, http.MethodPatch, server.URL+"/users/reset-password", "", resetBody(recoveryToken),
+		http.StatusNotFound, nil)
+
+	recoveryToken = requestReset()
+	usersRepo.expireTokens()
+
+	doJSON(t, http.MethodPatch, server.URL+"/users/reset-password", "", resetBody(recoveryToken), http.StatusGone,
+		nil)
+}
+
+func TestRefreshToken(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, 0)
+
+	refreshToken := func(userID string) string {
+		_, refreshToken, err := jwt.Tokens(userID, "session", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return refreshToken
+	}
+
+	unknownToken := refreshToken("unknown")
+	validToken := refreshToken("valid")
+	usedToken := refreshToken("used")
+
+	_, clientToken, err := jwt.Tokens("client", "session", &token.Grant{ClientID: testClientID, Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshTokensRepo := &refreshTokensRepositoryFake{ //nolint:exhaustruct
+		tokens: map[string]bool{
+			string(token.Hash(validToken)):  false,
+			string(token.Hash(usedToken)):   true,
+			string(token.Hash(clientToken)): false,
+		},
+	}
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{refreshTokensRepo: refreshTokensRepo}) //nolint:exhaustruct
+	server := httptest.NewServer(http.HandlerFunc(usersHandler.RefreshToken))
+
+	tests := map[string]struct {
+		in         string
+		wantStatus int
+		wantBody   string
+	}{
+		"invalid json body": {"", http.StatusBadRequest, `{"error":"invalid json body"}`},
+		"empty body":        {`{}`, http.StatusBadRequest, `{"error":"empty refresh token"}`},
+		"invalid token":     {`{"refreshToken":"test"}`, http.StatusUnauthorized, `{"error":"invalid refresh token"}`},
+		"unknown token": {
+			fmt.Sprintf(`{"refreshToken":%q}`, unknownToken),
+			http.StatusUnauthorized, `{"error":"invalid refresh token"}`,
+		},
+		"reused token": {
+			fmt.Sprintf(`{"refreshToken":%q}`, usedToken),
+			http.StatusUnauthorized, `{"error":"refresh token reused"}`,
+		},
+		"client token": {
+			fmt.Sprintf(`{"refreshToken":%q}`, clientToken),
+			http.StatusUnauthorized, `{"error":"invalid refresh token"}`,
+		},
+		"ok": {fmt.Sprintf(`{"refreshToken":%q}`, validToken), http.StatusOK, ""},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			buf := bytes.NewBufferString(test.in)
+
+			gotRes, gotErr := http.DefaultClient.Post(server.URL, "application/json", buf) //nolint:noctx
+			if gotErr != nil {
+				t.Fatal(gotErr)
+			}
+
+			defer func() {
+				err := gotRes.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+			}()
+
+			if gotRes.StatusCode != test.wantStatus {
+				t.Fatalf("Post() = status %d; want status %d", gotRes.StatusCode, test.wantStatus)
+			}
+
+			gotBody, gotErr := io.ReadAll(gotRes.Body)
+			if gotErr != nil {
+				t.Fatal(gotErr)
+			}
+
+			if test.wantBody != "" && string(gotBody) != test.wantBody {
+				t.Errorf("ReadAll() = %q; want %q", string(gotBody), test.wantBody)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+
+	sessionsRepo := &sessionsRepositoryFake{sessions: map[string]*domain.Session{ //nolint:exhaustruct
+		"current": {ID: "current", UserID: "user", UserAgent: "Firefox", IP: "192.0.2.1"}, //nolint:exhaustruct
+		"phone":   {ID: "phone", UserID: "user", UserAgent: "Safari", IP: "192.0.2.2"},    //nolint:exhaustruct
+		"foreign": {ID: "foreign", UserID: "someone", UserAgent: "Edge", IP: "192.0.2.3"}, //nolint:exhaustruct
+	}}
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+	})
+
+	mux := chi.NewRouter()
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Use(mw.FirstParty(log))
+		r.Get("/users/me/sessions", usersHandler.Sessions)
+		r.Delete("/users/me/sessions/{id}", usersHandler.RevokeSession)
+		r.Post("/users/logout-all", usersHandler.LogoutAll)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authToken, _, err := jwt.Tokens("user", "current", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientToken, _, err := jwt.Tokens("user", "client", &token.Grant{ClientID: testClientID, Scope: "openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sessions []response.Session
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me/sessions", authToken, "", http.StatusOK, &sessions)
+
+	if len(sessions) != 2 || !sessions[0].Current || sessions[0].UserAgent != "Firefox" || sessions[1].Current {
+		t.Errorf("Sessions() = %+v; want current and phone sessions", sessions)
+	}
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me/sessions", clientToken, "", http.StatusForbidden, nil)
+	doJSON(t, http.MethodPost, server.URL+"/users/logout-all", clientToken, "", http.StatusForbidden, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/sessions/foreign", authToken, "", http.StatusNotFound, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/sessions/phone", authToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me/sessions", authToken, "", http.StatusOK, &sessions)
+
+	if len(sessions) != 1 || sessions[0].ID != "current" {
+		t.Errorf("Sessions() = %+v; want current session", sessions)
+	}
+
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/sessions/current", authToken, "", http.StatusNoContent, nil)
+	doJSON(t, http.MethodGet, server.URL+"/users/me/sessions", authToken, "", http.StatusUnauthorized, nil)
+}
+
+func TestUserInfo(t *testing.T) {
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{}) //nolint:exhaustruct
+
+	mux := chi.NewRouter()
+	mux.With(mw.Verifier(jwt), jwtauth.Authenticator).Get("/userinfo", usersHandler.UserInfo)
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tests := map[string]struct {
+		grant     *token.Grant
+		wantEmail string
+	}{
+		"first party":  {nil, "john.doe@sixpack.com"},
+		"email scope":  {&token.Grant{ClientID: testClientID, Scope: "openid email"}, "john.doe@sixpack.com"},
+		"openid scope": {&token.Grant{ClientID: testClientID, Scope: "openid"}, ""},
+	}
+
+	for name, test := range tests {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			authToken, _, err := jwt.Tokens("john", "session", test.grant)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var userInfo response.UserInfo
+
+			doJSON(t, http.MethodGet, server.URL+"/userinfo", authToken, "", http.StatusOK, &userInfo)
+
+			if userInfo.Subject != "john" || userInfo.Email != test.wantEmail ||
+				(userInfo.EmailVerified != nil) != (test.wantEmail != "") {
+				t.Errorf("UserInfo() = %+v; want email %q", userInfo, test.wantEmail)
+			}
+		})
+	}
+}
+
+func TestCookieMode(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	cookies := handler.NewCookies("", true, http.SameSiteStrictMode)
+
+	refreshJWT := newJWT(t, 0)
+
+	_, refreshToken, err := refreshJWT.Tokens("user", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshTokensRepo := &refreshTokensRepositoryFake{ //nolint:exhaustruct
+		tokens: map[string]bool{string(token.Hash(refreshToken)): false},
+	}
+	refreshHandler := newUsersHandler(t, refreshJWT, usersHandlerOptions{ //nolint:exhaustruct
+		refreshTokensRepo: refreshTokensRepo,
+		cookies:           cookies,
+	})
+	refreshServer := httptest.NewServer(http.HandlerFunc(refreshHandler.RefreshToken))
+	t.Cleanup(refreshServer.Close)
+
+	req := newCookieRequest(t, refreshServer.URL, map[string]string{handler.CookieRefreshToken: refreshToken}, "")
+	gotCookies := doCookies(t, req, http.StatusNoContent)
+
+	for name, httpOnly := range map[string]bool{
+		handler.CookieAuthToken: true, handler.CookieRefreshToken: true, mw.CSRFCookie: false,
+	} {
+		cookie, ok := gotCookies[name]
+		if !ok || cookie.Value == "" || cookie.HttpOnly != httpOnly || !cookie.Secure ||
+			cookie.SameSite != http.SameSiteStrictMode {
+			t.Errorf("RefreshToken() = cookie %s %+v; want http only %t, secure and strict", name, cookie, httpOnly)
+		}
+	}
+
+	jwt := newJWT(t, time.Hour)
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}       //nolint:exhaustruct
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+		cookies:         cookies,
+	})
+
+	mux := chi.NewRouter()
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Use(mw.CSRF(log))
+		r.Post("/users/logout", usersHandler.Logout)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	authToken := func() string {
+		authToken, _, err := jwt.Tokens("user", "session", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return authToken
+	}
+
+	tests := map[string]struct {
+		cookies    map[string]string
+		csrfHeader string
+		bearer     bool
+		wantStatus int
+	}{
+		"missing csrf cookie": {map[string]string{}, "csrf", false, http.StatusForbidden},
+		"missing csrf header": {map[string]string{mw.CSRFCookie: "csrf"}, "", false, http.StatusForbidden},
+		"csrf mismatch":       {map[string]string{mw.CSRFCookie: "csrf"}, "other", false, http.StatusForbidden},
+		"csrf match":          {map[string]string{mw.CSRFCookie: "csrf"}, "csrf", false, http.StatusNoContent},
+		"bearer":              {nil, "", true, http.StatusNoContent},
+	}
+
+	for n, test := range tests { //nolint:paralleltest
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			authToken := authToken()
+
+			req := newCookieRequest(t, server.URL+"/users/logout", test.cookies, test.csrfHeader)
+
+			if test.bearer {
+				req.Header.Set("Authorization", "Bearer "+authToken)
+			} else {
+				req.AddCookie(&http.Cookie{Name: handler.CookieAuthToken, Value: authToken}) //nolint:exhaustruct
+			}
+
+			gotCookies := doCookies(t, req, test.wantStatus)
+
+			if test.wantStatus == http.StatusNoContent {
+				if cookie, ok := gotCookies[handler.CookieAuthToken]; !ok || cookie.MaxAge >= 0 {
+					t.Errorf("Logout() = cookie %+v; want cleared", cookie)
+				}
+			}
+		})
+	}
+}
+
+func newCookieRequest(t *testing.T, url string, cookies map[string]string, csrfHeader string) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value}) //nolint:exhaustruct
+	}
+
+	if csrfHeader != "" {
+		req.Header.Set(mw.CSRFHeader, csrfHeader)
+	}
+
+	return req
+}
+
+func doCookies(t *testing.T, req *http.Request, wantStatus int) map[string]*http.Cookie {
+	t.Helper()
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != wantStatus {
+		t.Fatalf("Do() = status %d; want status %d", res.StatusCode, wantStatus)
+	}
+
+	cookies := map[string]*http.Cookie{}
+
+	for _, cookie := range res.Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+
+	return cookies
+}
+
+var _ repository.RefreshTokens = (*refreshTokensRepositoryFake)(nil)
+
+// refreshTokensRepositoryFake keeps usage of tokens by hash. Families of the tokens are tracked just for tokens
+// created by Create, so that revocation of the families may be tested. Revoking families revokes sessions of the
+// linked sessions repository, if any, as sessions are identified by refresh token families.
+type refreshTokensRepositoryFake struct {
+	mu           sync.Mutex
+	tokens       map[string]bool
+	families     map[string]string
+	owners       map[string]string
+	clients      map[string]string
+	revoked      map[string]bool
+	sessionsRepo *sessionsRepositoryFake
+}
+
+func (repo *refreshTokensRepositoryFake) Create(ctx context.Context, familyID, userID, clientID string,
+	tokenHash []byte, expires time.Time,
+) (*domain.RefreshToken, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.families == nil {
+		repo.families = map[string]string{}
+		repo.owners = map[string]string{}
+		repo.clients = map[string]string{}
+		repo.revoked = map[string]bool{}
+	}
+
+	repo.tokens[string(tokenHash)] = false
+	repo.families[string(tokenHash)] = familyID
+	repo.owners[familyID] = userID
+	repo.clients[familyID] = clientID
+
+	return &domain.RefreshToken{UserID: userID, Expires: &expires}, nil //nolint:exhaustruct
+}
+
+func (repo *refreshTokensRepositoryFake) Rotate(ctx context.Context, tokenHash, newTokenHash []byte,
+	expires time.Time,
+) (*domain.RefreshToken, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	used, ok := repo.tokens[string(tokenHash)]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	if used {
+		return nil, repository.ErrTokenReused
+	}
+
+	familyID, ok := repo.families[string(tokenHash)]
+	if ok && repo.revoked[familyID] {
+		return nil, repository.ErrTokenRevoked
+	}
+
+	repo.tokens[string(tokenHash)] = true
+	repo.tokens[string(newTokenHash)] = false
+
+	if ok {
+		repo.families[string(newTokenHash)] = familyID
+	}
+
+	return &domain.RefreshToken{Expires: &expires}, nil //nolint:exhaustruct
+}
+
+func (repo *refreshTokensRepositoryFake) FindOne(ctx context.Context,
+	tokenHash []byte,
+) (*domain.RefreshToken, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	used, ok := repo.tokens[string(tokenHash)]
+	if !ok {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	expires := time.Now().Add(time.Hour)
+	refreshToken := &domain.RefreshToken{Expires: &expires} //nolint:exhaustruct
+
+	if used {
+		refreshToken.Used = &expires
+	}
+
+	return refreshToken, nil
+}
+
+func (repo *refreshTokensRepositoryFake) Revoke(ctx context.Context, familyID string) error {
+	return nil
+}
+
+func (repo *refreshTokensRepositoryFake) RevokeAll(ctx context.Context, userID string) error {
+	return nil
+}
+
+func (repo *refreshTokensRepositoryFake) RevokeAllOfClient(ctx context.Context, clientID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for id, client := range repo.clients {
+		if client == clientID {
+			repo.revoked[id] = true
+		}
+	}
+
+	return nil
+}
+
+func (repo *refreshTokensRepositoryFake) RevokeAllExcept(ctx context.Context, userID, familyID string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for id, owner := range repo.owners {
+		if owner == userID && id != familyID {
+			repo.revoked[id] = true
+		}
+	}
+
+	if repo.sessionsRepo == nil {
+		return nil
+	}
+
+	sessions, err := repo.sessionsRepo.FindAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.ID != familyID {
+			if err := repo.sessionsRepo.Revoke(ctx, userID, session.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+var _ repository.Revocations = (*revocationsRepositoryFake)(nil)
+
+type revocationsRepositoryFake struct {
+	mu       sync.Mutex
+	revoked  map[string]bool
+	subjects map[string]time.Time
+}
+
+func (repo *revocationsRepositoryFake) Revoke(ctx context.Context, jti, subject string, expires time.Time) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.revoked == nil {
+		repo.revoked = map[string]bool{}
+	}
+
+	repo.revoked[jti] = true
+
+	return nil
+}
+
+func (repo *revocationsRepositoryFake) RevokeAll(ctx context.Context, subject string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.subjects == nil {
+		repo.subjects = map[string]time.Time{}
+	}
+
+	repo.subjects[subject] = time.Now()
+
+	return nil
+}
+
+func (repo *revocationsRepositoryFake) IsRevoked(ctx context.Context, jti, subject string,
+	issuedAt time.Time,
+) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	issuedBefore, ok := repo.subjects[subject]
+
+	return repo.revoked[jti] || ok && !issuedAt.After(issuedBefore), nil
+}
+
+var _ repository.Sessions = (*sessionsRepositoryFake)(nil)
+
+type sessionsRepositoryFake struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session
+	revoked  map[string]bool
+}
+
+func (repo *sessionsRepositoryFake) Create(ctx context.Context, session *domain.Session) (*domain.Session, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.sessions == nil {
+		repo.sessions = map[string]*domain.Session{}
+	}
+
+	repo.sessions[session.ID] = session
+
+	return session, nil
+}
+
+func (repo *sessionsRepositoryFake) FindAll(ctx context.Context, userID string) ([]domain.Session, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	sessions := []domain.Session{}
+
+	for _, session := range repo.sessions {
+		if session.UserID == userID && !repo.revoked[session.ID] {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (repo *sessionsRepositoryFake) Touch(ctx context.Context, id string) error {
+	return nil
+}
+
+func (repo *sessionsRepositoryFake) Revoke(ctx context.Context, userID, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	session, ok := repo.sessions[id]
+	if !ok || session.UserID != userID {
+		return repository.ErrResourceNotFound
+	}
+
+	if repo.revoked == nil {
+		repo.revoked = map[string]bool{}
+	}
+
+	repo.revoked[id] = true
+
+	return nil
+}
+
+func (repo *sessionsRepositoryFake) IsActive(ctx context.Context, id string) (bool, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return !repo.revoked[id], nil
 }
@@ -0,0 +1,607 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// MFAMethodWebAuthn is second factor method listed in MFA challenge when user has registered WebAuthn credentials.
+const MFAMethodWebAuthn = "webauthn"
+
+// webAuthnTimeout is time given to the user to complete the ceremony, after which challenge expires.
+const webAuthnTimeout = 5 * time.Minute
+
+// NewWebAuthn creates WebAuthn relying party identified by the host name of external URL and accepting ceremonies
+// from its origin. Attestation is not requested, so any authenticator may be registered.
+func NewWebAuthn(externalURL *url.URL, name string) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{Enforce: false, Timeout: webAuthnTimeout, TimeoutUVD: webAuthnTimeout}
+
+	webAuthn, err := webauthn.New(&webauthn.Config{ //nolint:exhaustruct
+		RPID:                  externalURL.Hostname(),
+		RPDisplayName:         name,
+		RPOrigins:             []string{externalURL.Scheme + "://" + externalURL.Host},
+		AttestationPreference: protocol.PreferNoAttestation,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{ //nolint:exhaustruct
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn: %w", err)
+	}
+
+	return webAuthn, nil
+}
+
+// BeginWebAuthnRegistration issues registration challenge for new WebAuthn credential (passkey) of the current user.
+// Step-up token is required.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/webauthn/register/begin [post]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Success 200 {object} response.WebAuthnCreationOptions
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary Begin WebAuthn credential registration.
+func (h *UsersHandler) BeginWebAuthnRegistration(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	user, err := h.findWebAuthnUser(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find webauthn user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	creation, session, err := h.webAuthn.BeginRegistration(user, webauthn.WithExclusions(user.descriptors()))
+	if err != nil {
+		h.log.Warn("begin webauthn registration", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.storeWebAuthnChallenge(req.Context(), session.Challenge, claims.Subject,
+		domain.WebAuthnRegistration); err != nil {
+		h.log.Warn("webauthn challenge", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.NewWebAuthnCreationOptions(&creation.Response), h.log)
+}
+
+// FinishWebAuthnRegistration verifies authenticator response to the registration challenge and stores new WebAuthn
+// credential of the current user.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/me/webauthn/register/finish [post]
+// @Security BearerAuth
+// @Param registration body request.WebAuthnRegistration true "Authenticator attestation response"
+// @Success 201 {object} response.WebAuthnCredential
+// @Failure 400 {object} response.Error
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 409 {object} response.Error
+// @Failure 500
+// @Summary Finish WebAuthn credential registration.
+func (h *UsersHandler) FinishWebAuthnRegistration(res http.ResponseWriter, req *http.Request) { //nolint:funlen
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	registration, err := request.WebAuthnRegistrationFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	parsed, err := protocol.CredentialCreationResponse{ //nolint:exhaustruct
+		PublicKeyCredential: protocol.PublicKeyCredential{ //nolint:exhaustruct
+			Credential: protocol.Credential{ID: registration.ID, Type: string(protocol.PublicKeyCredentialType)},
+			RawID:      registration.RawID,
+		},
+		AttestationResponse: protocol.AuthenticatorAttestationResponse{ //nolint:exhaustruct
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: registration.RawClientDataJSON},
+			AttestationObject:     registration.RawAttestation,
+		},
+	}.Parse()
+	if err != nil {
+		h.log.Warn("parse webauthn registration", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid attestation", h.log)
+
+		return
+	}
+
+	challenge, err := h.consumeWebAuthnChallenge(req.Context(), parsed.Response.CollectedClientData.Challenge,
+		domain.WebAuthnRegistration)
+	if err != nil {
+		var reqErr *request.Error
+
+		if !errors.As(err, &reqErr) {
+			h.log.Warn("consume webauthn challenge", lax.Error(err))
+		}
+
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	if challenge.UserID != claims.Subject {
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid challenge", h.log)
+
+		return
+	}
+
+	user, err := h.findWebAuthnUser(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find webauthn user", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	credential, err := h.webAuthn.CreateCredential(user, webauthn.SessionData{ //nolint:exhaustruct
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		UserID:           user.WebAuthnID(),
+		UserVerification: protocol.VerificationPreferred,
+	}, parsed)
+	if err != nil {
+		h.log.Warn("verify webauthn registration", lax.Error(err))
+		response.RenderErrorStatus(res, http.StatusBadRequest, "invalid attestation", h.log)
+
+		return
+	}
+
+	domainCredential, err := h.webAuthnRepo.CreateCredential(req.Context(), &domain.WebAuthnCredential{
+		ID:        credential.ID,
+		UserID:    claims.Subject,
+		Name:      registration.Name,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.Authenticator.SignCount,
+		LastUsed:  nil,
+		Created:   nil,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUniqueViolation) {
+			response.RenderErrorStatus(res, http.StatusConflict, "credential already registered", h.log)
+
+			return
+		}
+
+		h.log.Warn("create webauthn credential", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusCreated, response.FromDomainWebAuthnCredential(domainCredential), h.log)
+}
+
+// WebAuthnCredentials lists WebAuthn credentials of the current user.
+//
+// @Tags users
+// @Produce json
+// @Router /users/me/webauthn/credentials [get]
+// @Security BearerAuth
+// @Success 200 {array} response.WebAuthnCredential
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 500
+// @Summary List WebAuthn credentials.
+func (h *UsersHandler) WebAuthnCredentials(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	domainCredentials, err := h.webAuthnRepo.FindCredentials(req.Context(), claims.Subject)
+	if err != nil {
+		h.log.Warn("find webauthn credentials", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	credentials := make([]*response.WebAuthnCredential, 0, len(domainCredentials))
+
+	for i := range domainCredentials {
+		credentials = append(credentials, response.FromDomainWebAuthnCredential(&domainCredentials[i]))
+	}
+
+	response.Render(res, http.StatusOK, credentials, h.log)
+}
+
+// DeleteWebAuthnCredential deletes WebAuthn credential of the current user. Step-up token is required.
+//
+// @Tags users
+// @Router /users/me/webauthn/credentials/{id} [delete]
+// @Security BearerAuth
+// @Param X-Step-Up-Token header string true "Step-up token"
+// @Param id path string true "Base64url encoded credential id"
+// @Success 204
+// @Failure 401
+// @Failure 403 {object} response.Error
+// @Failure 404 {object} response.Error
+// @Failure 500
+// @Summary Delete WebAuthn credential.
+func (h *UsersHandler) DeleteWebAuthnCredential(res http.ResponseWriter, req *http.Request) {
+	claims, err := claimsFromJWT(req.Context())
+	if err != nil {
+		h.log.Warn("claims from jwt", lax.Error(err))
+		response.Render(res, http.StatusUnauthorized, nil, h.log)
+
+		return
+	}
+
+	id, ok := request.CredentialID(chi.URLParam(req, "id"))
+	if !ok {
+		response.RenderErrorStatus(res, http.StatusNotFound, repository.ErrResourceNotFound.Error(), h.log)
+
+		return
+	}
+
+	if err := h.webAuthnRepo.DeleteCredential(req.Context(), claims.Subject, id); err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			response.RenderErrorStatus(res, http.StatusNotFound, err.Error(), h.log)
+
+			return
+		}
+
+		h.log.Warn("delete webauthn credential", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusNoContent, nil, h.log)
+}
+
+// BeginWebAuthnLogin issues authentication challenge. If MFA challenge token is provided, WebAuthn is used as the
+// second factor and just credentials of the user are allowed. Otherwise any discoverable credential may be used for
+// passwordless login, which requires user verification.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/webauthn/begin [post]
+// @Param loginBegin body request.WebAuthnLoginBegin false "Optional MFA challenge token"
+// @Success 200 {object} response.WebAuthnRequestOptions
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 500
+// @Summary Begin WebAuthn login.
+func (h *UsersHandler) BeginWebAuthnLogin(res http.ResponseWriter, req *http.Request) { //nolint:funlen
+	loginBegin, err := request.WebAuthnLoginBeginFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	var user *webAuthnUser
+
+	if loginBegin.MFAToken != "" {
+		claims, err := h.jwt.ParseMFAToken(loginBegin.MFAToken)
+		if err != nil {
+			h.log.Warn("parse mfa token", lax.Error(err))
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "invalid mfa token", h.log)
+
+			return
+		}
+
+		if user, err = h.findWebAuthnUser(req.Context(), claims.Subject); err != nil {
+			h.log.Warn("find webauthn user", lax.Error(err))
+			response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+			return
+		}
+
+		if len(user.credentials) == 0 {
+			response.RenderErrorStatus(res, http.StatusUnauthorized, "webauthn not enabled", h.log)
+
+			return
+		}
+	}
+
+	var (
+		assertion *protocol.CredentialAssertion
+		session   *webauthn.SessionData
+	)
+
+	if user != nil {
+		assertion, session, err = h.webAuthn.BeginLogin(user,
+			webauthn.WithUserVerification(protocol.VerificationDiscouraged))
+	} else {
+		assertion, session, err = h.webAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired))
+	}
+
+	if err != nil {
+		h.log.Warn("begin webauthn login", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	if err := h.storeWebAuthnChallenge(req.Context(), session.Challenge, string(session.UserID),
+		domain.WebAuthnAuthentication); err != nil {
+		h.log.Warn("webauthn challenge", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, response.NewWebAuthnRequestOptions(&assertion.Response), h.log)
+}
+
+// FinishWebAuthnLogin verifies authenticator response to the authentication challenge and completes login.
+//
+// @Tags users
+// @Accept json
+// @Produce json
+// @Router /users/login/webauthn/finish [post]
+// @Param assertion body request.WebAuthnAssertion true "Authenticator assertion response"
+// @Success 200 {object} response.User
+// @Failure 400 {object} response.Error
+// @Failure 401 {object} response.Error
+// @Failure 500
+// @Summary Finish WebAuthn login.
+func (h *UsersHandler) FinishWebAuthnLogin(res http.ResponseWriter, req *http.Request) {
+	assertion, err := request.WebAuthnAssertionFromJSON(req.Body, h.log)
+	if err != nil {
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	domainUser, err := h.verifyWebAuthnAssertion(req.Context(), assertion)
+	if err != nil {
+		var reqErr *request.Error
+
+		if !errors.As(err, &reqErr) {
+			h.log.Warn("verify webauthn assertion", lax.Error(err))
+		}
+
+		response.RenderError(res, err, h.log)
+
+		return
+	}
+
+	h.completeLogin(res, req, domainUser)
+}
+
+// storeWebAuthnChallenge stores hash of the challenge issued for the ceremony.
+func (h *UsersHandler) storeWebAuthnChallenge(ctx context.Context, challenge, userID, ceremony string) error {
+	if err := h.webAuthnRepo.CreateChallenge(ctx, &domain.WebAuthnChallenge{
+		Hash:     token.Hash(challenge),
+		UserID:   userID,
+		Ceremony: ceremony,
+		Expires:  time.Now().Add(webAuthnTimeout),
+		Created:  nil,
+	}); err != nil {
+		return fmt.Errorf("create challenge: %w", err)
+	}
+
+	return nil
+}
+
+// consumeWebAuthnChallenge consumes challenge of the ceremony returned in client data. Unknown challenge returns
+// request error.
+func (h *UsersHandler) consumeWebAuthnChallenge(ctx context.Context, challenge,
+	ceremony string,
+) (*domain.WebAuthnChallenge, error) {
+	consumed, err := h.webAuthnRepo.ConsumeChallenge(ctx, token.Hash(challenge), ceremony)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return nil, &request.Error{StatusCode: http.StatusBadRequest, Message: "invalid challenge"}
+		}
+
+		return nil, fmt.Errorf("consume challenge: %w", err)
+	}
+
+	return consumed, nil
+}
+
+// verifyWebAuthnAssertion verifies assertion of the authentication ceremony and returns the user the credential
+// belongs to. Credential of the second factor challenge has to belong to the user the challenge was issued to,
+// while passwordless login requires user verification and user handle of the credential owner. Failed verification
+// returns request error.
+func (h *UsersHandler) verifyWebAuthnAssertion(ctx context.Context, //nolint:cyclop,funlen
+	assertion *request.WebAuthnAssertion,
+) (*domain.User, error) {
+	parsed, err := protocol.CredentialAssertionResponse{ //nolint:exhaustruct
+		PublicKeyCredential: protocol.PublicKeyCredential{ //nolint:exhaustruct
+			Credential: protocol.Credential{ID: assertion.ID, Type: string(protocol.PublicKeyCredentialType)},
+			RawID:      assertion.RawID,
+		},
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: assertion.RawClientDataJSON},
+			AuthenticatorData:     assertion.RawAuthenticatorData,
+			Signature:             assertion.RawSignature,
+			UserHandle:            assertion.RawUserHandle,
+		},
+	}.Parse()
+	if err != nil {
+		h.log.Warn("parse assertion", lax.Error(err))
+
+		return nil, &request.Error{StatusCode: http.StatusBadRequest, Message: "invalid assertion"}
+	}
+
+	challenge, err := h.consumeWebAuthnChallenge(ctx, parsed.Response.CollectedClientData.Challenge,
+		domain.WebAuthnAuthentication)
+	if err != nil {
+		return nil, err
+	}
+
+	session := webauthn.SessionData{ //nolint:exhaustruct
+		Challenge:        parsed.Response.CollectedClientData.Challenge,
+		UserVerification: protocol.VerificationRequired,
+	}
+
+	var (
+		user      *webAuthnUser
+		lookupErr error
+	)
+
+	if challenge.IsPasswordless() {
+		_, err = h.webAuthn.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			user, lookupErr = h.findWebAuthnUser(ctx, string(userHandle))
+
+			return user, lookupErr
+		}, session, parsed)
+	} else {
+		if user, lookupErr = h.findWebAuthnUser(ctx, challenge.UserID); lookupErr == nil {
+			session.UserID = user.WebAuthnID()
+			session.AllowedCredentialIDs = user.credentialIDs()
+			session.UserVerification = protocol.VerificationDiscouraged
+			_, err = h.webAuthn.ValidateLogin(user, session, parsed)
+		}
+	}
+
+	if lookupErr != nil {
+		if errors.Is(lookupErr, repository.ErrResourceNotFound) {
+			return nil, &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid credential"}
+		}
+
+		return nil, fmt.Errorf("find webauthn user: %w", lookupErr)
+	}
+
+	if err != nil {
+		h.log.Warn("verify assertion", lax.Error(err))
+
+		return nil, &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid assertion"}
+	}
+
+	// Sign counter is checked and stored atomically, so that concurrent replays of the assertion are rejected.
+	if err := h.webAuthnRepo.UseCredential(ctx, parsed.RawID,
+		parsed.Response.AuthenticatorData.Counter); err != nil {
+		if errors.Is(err, repository.ErrTokenReused) {
+			return nil, &request.Error{StatusCode: http.StatusUnauthorized, Message: "invalid assertion"}
+		}
+
+		return nil, fmt.Errorf("use credential: %w", err)
+	}
+
+	if !user.user.IsActive() {
+		return nil, &request.Error{StatusCode: http.StatusUnauthorized, Message: "account not activated"}
+	}
+
+	return user.user, nil
+}
+
+// findWebAuthnUser finds user and its WebAuthn credentials.
+func (h *UsersHandler) findWebAuthnUser(ctx context.Context, userID string) (*webAuthnUser, error) {
+	domainUser, err := h.usersRepo.FindOne(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+
+	credentials, err := h.webAuthnRepo.FindCredentials(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find credentials: %w", err)
+	}
+
+	return &webAuthnUser{user: domainUser, credentials: credentials}, nil
+}
+
+var _ webauthn.User = (*webAuthnUser)(nil)
+
+// webAuthnUser adapts user and its credentials to WebAuthn relying party. User handle is the user ID.
+type webAuthnUser struct {
+	user        *domain.User
+	credentials []domain.WebAuthnCredential
+}
+
+// WebAuthnID implements webauthn.User interface.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(u.user.ID)
+}
+
+// WebAuthnName implements webauthn.User interface.
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+// WebAuthnDisplayName implements webauthn.User interface.
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Email
+}
+
+// WebAuthnIcon implements webauthn.User interface.
+func (u *webAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials implements webauthn.User interface.
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+
+	for i := range u.credentials {
+		credentials = append(credentials, webauthn.Credential{ //nolint:exhaustruct
+			ID:              u.credentials[i].ID,
+			PublicKey:       u.credentials[i].PublicKey,
+			AttestationType: string(protocol.PreferNoAttestation),
+			Authenticator:   webauthn.Authenticator{SignCount: u.credentials[i].SignCount}, //nolint:exhaustruct
+		})
+	}
+
+	return credentials
+}
+
+func (u *webAuthnUser) credentialIDs() [][]byte {
+	ids := make([][]byte, 0, len(u.credentials))
+
+	for i := range u.credentials {
+		ids = append(ids, u.credentials[i].ID)
+	}
+
+	return ids
+}
+
+func (u *webAuthnUser) descriptors() []protocol.CredentialDescriptor {
+	credentials := u.WebAuthnCredentials()
+	descriptors := make([]protocol.CredentialDescriptor, 0, len(credentials))
+
+	for i := range credentials {
+		descriptors = append(descriptors, credentials[i].Descriptor())
+	}
+
+	return descriptors
+}
@@ -0,0 +1,336 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/handler"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/handler/webauthntest"
+	"go.ectobit.com/arc/mw"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestWebAuthn(t *testing.T) { //nolint:funlen,cyclop
+	t.Parallel()
+
+	jwt := newJWT(t, time.Hour)
+	server := newWebAuthnServer(t, jwt)
+	authenticator := webauthntest.New("localhost", "http://localhost:3000")
+
+	authToken, _, err := jwt.Tokens("john", "session", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var creationOptions response.WebAuthnCreationOptions
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/webauthn/register/begin", authToken, "", http.StatusOK,
+		&creationOptions)
+
+	if creationOptions.RP.ID != "localhost" || creationOptions.User.Name != "john.doe@sixpack.com" {
+		t.Errorf("BeginWebAuthnRegistration() = %+v; want localhost relying party and john", creationOptions)
+	}
+
+	attestation, err := authenticator.Create(decodeBase64URL(t, creationOptions.Challenge),
+		decodeBase64URL(t, creationOptions.User.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var credential response.WebAuthnCredential
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/webauthn/register/finish", authToken,
+		registrationBody("YubiKey", attestation), http.StatusCreated, &credential)
+
+	if credential.ID != base64.RawURLEncoding.EncodeToString(attestation.CredentialID) || credential.Name != "YubiKey" {
+		t.Errorf("FinishWebAuthnRegistration() = %+v; want registered credential", credential)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/me/webauthn/register/finish", authToken,
+		registrationBody("YubiKey", attestation), http.StatusBadRequest, nil)
+
+	var credentials []response.WebAuthnCredential
+
+	doJSON(t, http.MethodGet, server.URL+"/users/me/webauthn/credentials", authToken, "", http.StatusOK, &credentials)
+
+	if len(credentials) != 1 {
+		t.Errorf("WebAuthnCredentials() = %d credentials; want 1", len(credentials))
+	}
+
+	var challenge response.MFAChallenge
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("john.doe@sixpack.com"), http.StatusAccepted,
+		&challenge)
+
+	if len(challenge.Methods) != 1 || challenge.Methods[0] != handler.MFAMethodWebAuthn {
+		t.Errorf("Login() = methods %v; want [%s]", challenge.Methods, handler.MFAMethodWebAuthn)
+	}
+
+	var requestOptions response.WebAuthnRequestOptions
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/begin", "",
+		fmt.Sprintf(`{"mfaToken":%q}`, challenge.MFAToken), http.StatusOK, &requestOptions)
+
+	if len(requestOptions.AllowCredentials) != 1 || requestOptions.UserVerification != "discouraged" {
+		t.Errorf("BeginWebAuthnLogin() = %+v; want second factor options", requestOptions)
+	}
+
+	var user response.User
+
+	authenticator.UserVerified = false
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/finish", "",
+		assertionBody(t, authenticator, attestation.CredentialID, requestOptions.Challenge), http.StatusOK, &user)
+
+	if user.AuthToken == "" || user.RefreshToken == "" {
+		t.Errorf("FinishWebAuthnLogin() = %+v; want tokens", user)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/begin", "", "", http.StatusOK, &requestOptions)
+
+	if len(requestOptions.AllowCredentials) != 0 || requestOptions.UserVerification != "required" {
+		t.Errorf("BeginWebAuthnLogin() = %+v; want passwordless options", requestOptions)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/finish", "",
+		assertionBody(t, authenticator, attestation.CredentialID, requestOptions.Challenge), http.StatusUnauthorized,
+		nil)
+
+	authenticator.UserVerified = true
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/begin", "", "", http.StatusOK, &requestOptions)
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/finish", "",
+		assertionBody(t, authenticator, attestation.CredentialID, requestOptions.Challenge), http.StatusOK, nil)
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login", "", loginBody("jane.doe@sixpack.com"), http.StatusOK, nil)
+
+	janeMFAToken, err := jwt.MFAToken("jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doJSON(t, http.MethodPost, server.URL+"/users/login/webauthn/begin", "",
+		fmt.Sprintf(`{"mfaToken":%q}`, janeMFAToken), http.StatusUnauthorized, nil)
+
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/webauthn/credentials/"+credential.ID, authToken, "",
+		http.StatusNoContent, nil)
+	doJSON(t, http.MethodDelete, server.URL+"/users/me/webauthn/credentials/"+credential.ID, authToken, "",
+		http.StatusNotFound, nil)
+}
+
+func newWebAuthnServer(t *testing.T, jwt *token.JWT) *httptest.Server {
+	t.Helper()
+
+	log := lax.NewZapAdapter(zaptest.NewLogger(t))
+	revocationsRepo := &revocationsRepositoryFake{} //nolint:exhaustruct
+	sessionsRepo := &sessionsRepositoryFake{}       //nolint:exhaustruct
+
+	webAuthn, err := handler.NewWebAuthn(&url.URL{Scheme: "http", Host: "localhost:3000"}, "Arc") //nolint:exhaustruct
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usersHandler := newUsersHandler(t, jwt, usersHandlerOptions{ //nolint:exhaustruct
+		revocationsRepo: revocationsRepo,
+		sessionsRepo:    sessionsRepo,
+		webAuthn:        webAuthn,
+	})
+
+	mux := chi.NewRouter()
+	mux.Post("/users/login", usersHandler.Login)
+	mux.Post("/users/login/webauthn/begin", usersHandler.BeginWebAuthnLogin)
+	mux.Post("/users/login/webauthn/finish", usersHandler.FinishWebAuthnLogin)
+	mux.Group(func(r chi.Router) {
+		r.Use(mw.Verifier(jwt))
+		r.Use(jwtauth.Authenticator)
+		r.Use(mw.Validator(revocationsRepo, sessionsRepo, log))
+		r.Post("/users/me/webauthn/register/begin", usersHandler.BeginWebAuthnRegistration)
+		r.Post("/users/me/webauthn/register/finish", usersHandler.FinishWebAuthnRegistration)
+		r.Get("/users/me/webauthn/credentials", usersHandler.WebAuthnCredentials)
+		r.Delete("/users/me/webauthn/credentials/{id}", usersHandler.DeleteWebAuthnCredential)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func registrationBody(name string, attestation *webauthntest.Attestation) string {
+	return fmt.Sprintf(`{"name":%q,"id":%q,"clientDataJSON":%q,"attestationObject":%q}`, name,
+		base64.RawURLEncoding.EncodeToString(attestation.CredentialID),
+		base64.RawURLEncoding.EncodeToString(attestation.ClientDataJSON),
+		base64.RawURLEncoding.EncodeToString(attestation.AttestationObject))
+}
+
+func assertionBody(t *testing.T, authenticator *webauthntest.Authenticator, credentialID []byte,
+	challenge string,
+) string {
+	t.Helper()
+
+	assertion, err := authenticator.Get(credentialID, decodeBase64URL(t, challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fmt.Sprintf(`{"id":%q,"clientDataJSON":%q,"authenticatorData":%q,"signature":%q,"userHandle":%q}`,
+		base64.RawURLEncoding.EncodeToString(assertion.CredentialID),
+		base64.RawURLEncoding.EncodeToString(assertion.ClientDataJSON),
+		base64.RawURLEncoding.EncodeToString(assertion.AuthenticatorData),
+		base64.RawURLEncoding.EncodeToString(assertion.Signature),
+		base64.RawURLEncoding.EncodeToString(assertion.UserHandle))
+}
+
+func decodeBase64URL(t *testing.T, encoded string) []byte {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decoded
+}
+
+var _ repository.WebAuthn = (*webAuthnRepositoryFake)(nil)
+
+type webAuthnRepositoryFake struct {
+	mu          sync.Mutex
+	credentials []*domain.WebAuthnCredential
+	challenges  map[string]*domain.WebAuthnChallenge
+}
+
+func (repo *webAuthnRepositoryFake) CreateCredential(ctx context.Context,
+	credential *domain.WebAuthnCredential,
+) (*domain.WebAuthnCredential, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, stored := range repo.credentials {
+		if bytes.Equal(stored.ID, credential.ID) {
+			return nil, repository.ErrUniqueViolation
+		}
+	}
+
+	now := time.Now()
+	created := *credential
+	created.Created = &now
+	repo.credentials = append(repo.credentials, &created)
+
+	return &created, nil
+}
+
+func (repo *webAuthnRepositoryFake) FindCredential(ctx context.Context,
+	id []byte,
+) (*domain.WebAuthnCredential, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, credential := range repo.credentials {
+		if bytes.Equal(credential.ID, id) {
+			copied := *credential
+
+			return &copied, nil
+		}
+	}
+
+	return nil, repository.ErrResourceNotFound
+}
+
+func (repo *webAuthnRepositoryFake) FindCredentials(ctx context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	credentials := []domain.WebAuthnCredential{}
+
+	for _, credential := range repo.credentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, *credential)
+		}
+	}
+
+	return credentials, nil
+}
+
+func (repo *webAuthnRepositoryFake) UseCredential(ctx context.Context, id []byte, signCount uint32) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, credential := range repo.credentials {
+		if !bytes.Equal(credential.ID, id) {
+			continue
+		}
+
+		if credential.SignCount >= signCount && (credential.SignCount != 0 || signCount != 0) {
+			return repository.ErrTokenReused
+		}
+
+		now := time.Now()
+		credential.SignCount = signCount
+		credential.LastUsed = &now
+
+		return nil
+	}
+
+	return repository.ErrResourceNotFound
+}
+
+func (repo *webAuthnRepositoryFake) DeleteCredential(ctx context.Context, userID string, id []byte) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for i, credential := range repo.credentials {
+		if credential.UserID == userID && bytes.Equal(credential.ID, id) {
+			repo.credentials = append(repo.credentials[:i], repo.credentials[i+1:]...)
+
+			return nil
+		}
+	}
+
+	return repository.ErrResourceNotFound
+}
+
+func (repo *webAuthnRepositoryFake) CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.challenges == nil {
+		repo.challenges = map[string]*domain.WebAuthnChallenge{}
+	}
+
+	repo.challenges[string(challenge.Hash)] = challenge
+
+	return nil
+}
+
+func (repo *webAuthnRepositoryFake) ConsumeChallenge(ctx context.Context, hash []byte,
+	ceremony string,
+) (*domain.WebAuthnChallenge, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	challenge, ok := repo.challenges[string(hash)]
+	if !ok || challenge.Ceremony != ceremony || time.Now().After(challenge.Expires) {
+		return nil, repository.ErrResourceNotFound
+	}
+
+	delete(repo.challenges, string(hash))
+
+	return challenge, nil
+}
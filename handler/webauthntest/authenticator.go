@@ -0,0 +1,200 @@
+// Package webauthntest provides software authenticator to be used in tests of Web Authentication ceremonies.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const credentialIDLength = 16
+
+// ErrUnknownCredential is returned when authenticator does not contain requested credential.
+var ErrUnknownCredential = errors.New("unknown credential")
+
+// Authenticator is software authenticator creating discoverable ES256 credentials for the relying party and signing
+// assertions as the browser at origin would request. Signature counter is incremented by each assertion.
+type Authenticator struct {
+	mu           sync.Mutex
+	rpID         string
+	origin       string
+	UserVerified bool
+	credentials  map[string]*credential
+}
+
+type credential struct {
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+	signCount  uint32
+}
+
+// New creates software authenticator for the relying party id and origin.
+func New(rpID, origin string) *Authenticator {
+	return &Authenticator{ //nolint:exhaustruct
+		rpID:         rpID,
+		origin:       origin,
+		UserVerified: true,
+		credentials:  map[string]*credential{},
+	}
+}
+
+// Attestation contains authenticator response to the registration ceremony.
+type Attestation struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// Assertion contains authenticator response to the authentication ceremony.
+type Assertion struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte
+}
+
+// Create creates new credential of the user identified by user handle using "none" attestation format.
+func (a *Authenticator) Create(challenge, userHandle []byte) (*Attestation, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	id := make([]byte, credentialIDLength)
+
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("random: %w", err)
+	}
+
+	a.mu.Lock()
+	a.credentials[string(id)] = &credential{key: key, userHandle: userHandle, signCount: 0}
+	a.mu.Unlock()
+
+	clientDataJSON, err := a.clientData(protocol.CreateCeremony, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := EncodeCOSEKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	attested := make([]byte, 16+2, 16+2+len(id)) //nolint:gomnd
+	binary.BigEndian.PutUint16(attested[16:], uint16(len(id)))
+	attested = append(attested, id...)
+	attested = append(attested, publicKey...)
+
+	authData := append(a.authData(protocol.FlagAttestedCredentialData, 0), attested...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal attestation object: %w", err)
+	}
+
+	return &Attestation{CredentialID: id, ClientDataJSON: clientDataJSON, AttestationObject: attestationObject}, nil
+}
+
+// Get signs assertion by credential.
+func (a *Authenticator) Get(credentialID, challenge []byte) (*Assertion, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cred, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return nil, ErrUnknownCredential
+	}
+
+	clientDataJSON, err := a.clientData(protocol.AssertCeremony, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	cred.signCount++
+	authData := a.authData(0, cred.signCount)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	hash := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, cred.key, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+
+	return &Assertion{
+		CredentialID:      credentialID,
+		ClientDataJSON:    clientDataJSON,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        cred.userHandle,
+	}, nil
+}
+
+func (a *Authenticator) clientData(ceremony protocol.CeremonyType, challenge []byte) ([]byte, error) {
+	clientDataJSON, err := json.Marshal(map[string]interface{}{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal client data: %w", err)
+	}
+
+	return clientDataJSON, nil
+}
+
+func (a *Authenticator) authData(flags protocol.AuthenticatorFlags, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+
+	flags |= protocol.FlagUserPresent
+	if a.UserVerified {
+		flags |= protocol.FlagUserVerified
+	}
+
+	authData := make([]byte, 32+1+4) //nolint:gomnd
+	copy(authData, rpIDHash[:])
+	authData[32] = byte(flags)
+	binary.BigEndian.PutUint32(authData[33:], signCount)
+
+	return authData
+}
+
+// EncodeCOSEKey encodes ES256 public key as COSE_Key.
+func EncodeCOSEKey(key *ecdsa.PublicKey) ([]byte, error) {
+	x := make([]byte, 32) //nolint:gomnd
+	y := make([]byte, 32) //nolint:gomnd
+
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+
+	data, err := webauthncbor.Marshal(&webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{ //nolint:exhaustruct
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: x,
+		YCoord: y,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal cose key: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.ectobit.com/arc/handler/request"
+	"go.ectobit.com/arc/handler/response"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/lax"
+)
+
+// WellKnownHandler contains discovery related http handlers.
+type WellKnownHandler struct {
+	jwt                   *token.JWT
+	externalURL           string
+	frontendAuthorizePath string
+	log                   lax.Logger
+}
+
+// NewWellKnownHandler creates well-known handler.
+func NewWellKnownHandler(jwt *token.JWT, externalURL, frontendAuthorizePath string,
+	log lax.Logger,
+) *WellKnownHandler {
+	return &WellKnownHandler{
+		jwt:                   jwt,
+		externalURL:           externalURL,
+		frontendAuthorizePath: frontendAuthorizePath,
+		log:                   log,
+	}
+}
+
+// OpenIDConfiguration renders OpenID Connect discovery metadata. Authorization endpoint is the frontend page which
+// authenticates the user, asks for consent and calls /oauth/authorize.
+//
+// @Tags well-known
+// @Produce json
+// @Router /.well-known/openid-configuration [get]
+// @Success 200 {object} response.OpenIDConfiguration
+// @Summary OpenID Connect discovery.
+func (h *WellKnownHandler) OpenIDConfiguration(res http.ResponseWriter, req *http.Request) {
+	response.Render(res, http.StatusOK, &response.OpenIDConfiguration{ //nolint:exhaustruct
+		Issuer:                 h.jwt.Issuer(),
+		AuthorizationEndpoint:  fmt.Sprintf("%s/%s", h.externalURL, h.frontendAuthorizePath),
+		TokenEndpoint:          h.externalURL + "/oauth/token",
+		IntrospectionEndpoint:  h.externalURL + "/oauth/introspect",
+		RevocationEndpoint:     h.externalURL + "/oauth/revoke",
+		UserinfoEndpoint:       h.externalURL + "/userinfo",
+		JWKSURI:                h.externalURL + "/.well-known/jwks.json",
+		ResponseTypesSupported: []string{request.ResponseTypeCode},
+		GrantTypesSupported: []string{
+			request.GrantTypeAuthorizationCode, request.GrantTypeRefreshToken, request.GrantTypeClientCredentials,
+		},
+		CodeChallengeMethodsSupported:     []string{request.CodeChallengeMethodS256},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{h.jwt.Algorithm()},
+		ScopesSupported:                   token.SupportedScopes(),
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified",
+		},
+	}, h.log)
+}
+
+// JWKS renders public keys which may be used to verify tokens.
+//
+// @Tags well-known
+// @Produce json
+// @Router /.well-known/jwks.json [get]
+// @Success 200
+// @Failure 500
+// @Summary JSON Web Key Set.
+func (h *WellKnownHandler) JWKS(res http.ResponseWriter, req *http.Request) {
+	set, err := h.jwt.JWKS()
+	if err != nil {
+		h.log.Warn("jwks", lax.Error(err))
+		response.Render(res, http.StatusInternalServerError, nil, h.log)
+
+		return
+	}
+
+	response.Render(res, http.StatusOK, set, h.log)
+}
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE email_otps;
+DROP TABLE email_otp_enrollments;
+
+COMMIT;
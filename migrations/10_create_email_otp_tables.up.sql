@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TABLE email_otp_enrollments (
+  user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE email_otp_enrollments IS 'users who require code sent per email as the second factor at login';
+
+CREATE TABLE email_otps (
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  purpose character varying(16) NOT NULL CHECK (purpose IN ('login', 'step_up')),
+  code_hash bytea NOT NULL,
+  attempts integer DEFAULT 0 NOT NULL,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL,
+  PRIMARY KEY (user_id, purpose)
+);
+
+COMMENT ON TABLE email_otps IS 'just the newest challenge of the user per purpose is valid, it is deleted once used';
+COMMENT ON COLUMN email_otps.code_hash IS 'sha256 hash of the code bound to the user and purpose';
+COMMENT ON COLUMN email_otps.attempts IS 'failed verification attempts, challenge is refused once limit is reached';
+
+COMMIT;
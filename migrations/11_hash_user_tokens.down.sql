@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE users
+  ADD COLUMN activation_token uuid,
+  ADD COLUMN recovery_token uuid;
+
+UPDATE users SET activation_token=gen_random_uuid() WHERE NOT active;
+
+ALTER TABLE users
+  DROP COLUMN activation_token_hash,
+  DROP COLUMN activation_token_issued,
+  DROP COLUMN recovery_token_hash,
+  DROP COLUMN recovery_token_issued;
+
+COMMENT ON COLUMN users.activation_token IS 'used just for initial account activation';
+
+CREATE INDEX ON users (active, activation_token);
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE users
+  ADD COLUMN activation_token_hash bytea UNIQUE,
+  ADD COLUMN activation_token_issued timestamp with time zone,
+  ADD COLUMN recovery_token_hash bytea UNIQUE,
+  ADD COLUMN recovery_token_issued timestamp with time zone;
+
+UPDATE users SET activation_token_hash=sha256(convert_to(activation_token::text, 'UTF8')), activation_token_issued=created
+WHERE activation_token IS NOT NULL;
+UPDATE users SET recovery_token_hash=sha256(convert_to(recovery_token::text, 'UTF8')), recovery_token_issued=now()
+WHERE recovery_token IS NOT NULL;
+
+ALTER TABLE users
+  DROP COLUMN activation_token,
+  DROP COLUMN recovery_token;
+
+COMMENT ON COLUMN users.activation_token_hash IS 'sha256 hash of the token used just for initial account activation';
+COMMENT ON COLUMN users.activation_token_issued IS 'activation token expires after configured period';
+COMMENT ON COLUMN users.recovery_token_hash IS 'sha256 hash of the password reset token';
+COMMENT ON COLUMN users.recovery_token_issued IS 'recovery token expires after configured period';
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE users
+  DROP COLUMN display_name,
+  DROP COLUMN locale,
+  DROP COLUMN timezone,
+  DROP COLUMN metadata;
+
+COMMIT;
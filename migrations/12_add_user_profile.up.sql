@@ -0,0 +1,13 @@
+BEGIN;
+
+ALTER TABLE users
+  ADD COLUMN display_name character varying(100) DEFAULT '' NOT NULL,
+  ADD COLUMN locale character varying(35) DEFAULT '' NOT NULL,
+  ADD COLUMN timezone character varying(64) DEFAULT '' NOT NULL,
+  ADD COLUMN metadata jsonb DEFAULT '{}' NOT NULL CHECK (jsonb_typeof(metadata) = 'object');
+
+COMMENT ON COLUMN users.locale IS 'BCP 47 language tag';
+COMMENT ON COLUMN users.timezone IS 'IANA time zone name';
+COMMENT ON COLUMN users.metadata IS 'arbitrary data set by the user';
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE email_changes (
+  user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  new_email character varying(254) NOT NULL CHECK (new_email != ''),
+  confirm_token_hash bytea UNIQUE NOT NULL,
+  cancel_token_hash bytea UNIQUE NOT NULL,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE email_changes IS 'just the newest change of the user is pending, users.email is swapped once confirmed';
+COMMENT ON COLUMN email_changes.confirm_token_hash IS 'sha256 hash of the token sent to the new address';
+COMMENT ON COLUMN email_changes.cancel_token_hash IS 'sha256 hash of the token sent to the old address';
+
+COMMIT;
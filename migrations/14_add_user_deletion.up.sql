@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN deletion_scheduled timestamp with time zone;
+
+COMMENT ON COLUMN users.deletion_scheduled IS 'user is hard deleted once this time passes, unless deletion is canceled';
+
+CREATE INDEX ON users (deletion_scheduled) WHERE deletion_scheduled IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS users_created_id_idx;
+
+COMMIT;
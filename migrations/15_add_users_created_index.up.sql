@@ -0,0 +1,7 @@
+BEGIN;
+
+CREATE INDEX users_created_id_idx ON users (created, id);
+
+COMMENT ON INDEX users_created_id_idx IS 'keyset pagination of users listing by creation time';
+
+COMMIT;
@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE user_status_changes;
+
+ALTER TABLE users DROP COLUMN status;
+ALTER TABLE users DROP COLUMN status_until;
+ALTER TABLE users DROP COLUMN status_reason;
+ALTER TABLE users DROP COLUMN status_actor;
+ALTER TABLE users DROP COLUMN status_changed;
+
+COMMIT;
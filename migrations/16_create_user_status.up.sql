@@ -0,0 +1,28 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN status character varying(9) DEFAULT 'active' NOT NULL
+  CHECK (status IN ('active', 'suspended', 'banned'));
+ALTER TABLE users ADD COLUMN status_until timestamp with time zone;
+ALTER TABLE users ADD COLUMN status_reason text DEFAULT '' NOT NULL;
+ALTER TABLE users ADD COLUMN status_actor text DEFAULT '' NOT NULL;
+ALTER TABLE users ADD COLUMN status_changed timestamp with time zone;
+
+COMMENT ON COLUMN users.status IS 'suspended and banned users can not log in, unlike not yet activated ones';
+COMMENT ON COLUMN users.status_until IS 'end of suspension, NULL for suspension lasting until lifted';
+COMMENT ON COLUMN users.status_actor IS 'subject of the administrator who set the status';
+
+CREATE TABLE user_status_changes (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  status character varying(9) NOT NULL,
+  until timestamp with time zone,
+  reason text NOT NULL,
+  actor text NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE user_status_changes IS 'history of users.status changes';
+
+CREATE INDEX ON user_status_changes (user_id, created);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE invitations;
+
+ALTER TABLE users DROP COLUMN role;
+
+COMMIT;
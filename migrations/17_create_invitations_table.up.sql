@@ -0,0 +1,25 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN role text DEFAULT '' NOT NULL;
+
+COMMENT ON COLUMN users.role IS 'Casbin role of the user, empty if user has no role';
+
+CREATE TABLE invitations (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  email character varying(254) NOT NULL CHECK (email != ''),
+  role text DEFAULT '' NOT NULL,
+  inviter text NOT NULL,
+  token_hash bytea UNIQUE NOT NULL,
+  expires timestamp with time zone NOT NULL,
+  accepted timestamp with time zone,
+  user_id uuid REFERENCES users (id) ON DELETE SET NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE invitations IS 'just the newest invitation of the email address is pending, accepted ones are kept';
+COMMENT ON COLUMN invitations.inviter IS 'subject of the user or client who sent the invitation';
+COMMENT ON COLUMN invitations.token_hash IS 'sha256 hash of the token sent to the invitee';
+
+CREATE UNIQUE INDEX invitations_pending_email_idx ON invitations (email) WHERE accepted IS NULL;
+
+COMMIT;
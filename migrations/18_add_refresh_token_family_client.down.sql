@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE refresh_token_families DROP COLUMN client_id;
+
+COMMIT;
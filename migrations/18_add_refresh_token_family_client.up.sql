@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE refresh_token_families ADD COLUMN client_id uuid REFERENCES oauth_clients (id) ON DELETE CASCADE;
+
+COMMENT ON COLUMN refresh_token_families.client_id IS 'OAuth client of the family, null for first-party sessions';
+
+CREATE INDEX ON refresh_token_families (client_id) WHERE client_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TABLE refresh_token_families (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  revoked timestamp with time zone,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN refresh_token_families.revoked IS 'set when reuse of already rotated refresh token is detected';
+
+CREATE TABLE refresh_tokens (
+  token_hash bytea PRIMARY KEY,
+  family_id uuid NOT NULL REFERENCES refresh_token_families (id) ON DELETE CASCADE,
+  used timestamp with time zone,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'sha256 hash of the refresh token';
+
+CREATE INDEX ON refresh_tokens (family_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE revoked_subjects;
+DROP TABLE revoked_tokens;
+
+COMMIT;
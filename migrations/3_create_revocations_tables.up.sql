@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE revoked_tokens (
+  jti character varying(255) PRIMARY KEY,
+  subject character varying(254) NOT NULL,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE revoked_tokens IS 'rows may be purged once expires has passed';
+
+CREATE INDEX ON revoked_tokens (expires);
+
+CREATE TABLE revoked_subjects (
+  subject character varying(254) PRIMARY KEY,
+  issued_before timestamp with time zone NOT NULL
+);
+
+COMMENT ON TABLE revoked_subjects IS 'all tokens of the subject issued before issued_before are revoked';
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE oauth_consents;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_clients;
+
+COMMIT;
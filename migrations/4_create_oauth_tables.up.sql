@@ -0,0 +1,41 @@
+BEGIN;
+
+CREATE TABLE oauth_clients (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  name character varying(255) NOT NULL CHECK (name != ''),
+  redirect_uris text[] NOT NULL,
+  trusted boolean DEFAULT FALSE NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN oauth_clients.redirect_uris IS 'redirect uris are matched exactly';
+COMMENT ON COLUMN oauth_clients.trusted IS 'first-party clients which do not require user consent';
+
+CREATE TABLE oauth_authorization_codes (
+  code_hash bytea PRIMARY KEY,
+  client_id uuid NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  family_id uuid NOT NULL,
+  redirect_uri text NOT NULL,
+  scope text NOT NULL,
+  code_challenge character varying(128) NOT NULL,
+  nonce text NOT NULL,
+  auth_time timestamp with time zone NOT NULL,
+  used timestamp with time zone,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN oauth_authorization_codes.code_hash IS 'sha256 hash of the authorization code';
+COMMENT ON COLUMN oauth_authorization_codes.family_id IS 'refresh token family started by exchanging the code';
+
+CREATE TABLE oauth_consents (
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  client_id uuid NOT NULL REFERENCES oauth_clients (id) ON DELETE CASCADE,
+  scopes text[] NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL,
+  updated timestamp with time zone,
+  PRIMARY KEY (user_id, client_id)
+);
+
+COMMIT;
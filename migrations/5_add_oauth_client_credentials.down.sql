@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE oauth_clients ALTER COLUMN redirect_uris DROP DEFAULT;
+ALTER TABLE oauth_clients DROP COLUMN scopes;
+ALTER TABLE oauth_clients DROP COLUMN secret_hash;
+
+COMMIT;
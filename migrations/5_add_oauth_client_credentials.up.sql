@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE oauth_clients ADD COLUMN secret_hash bytea;
+ALTER TABLE oauth_clients ADD COLUMN scopes text[] DEFAULT '{}' NOT NULL;
+ALTER TABLE oauth_clients ALTER COLUMN redirect_uris SET DEFAULT '{}';
+
+COMMENT ON COLUMN oauth_clients.secret_hash IS 'sha256 hash of the secret of confidential client, NULL for public clients';
+COMMENT ON COLUMN oauth_clients.scopes IS 'scopes client may request besides OpenID Connect ones';
+
+COMMIT;
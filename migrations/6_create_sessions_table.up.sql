@@ -0,0 +1,17 @@
+BEGIN;
+
+CREATE TABLE sessions (
+  id uuid PRIMARY KEY REFERENCES refresh_token_families (id) ON DELETE CASCADE,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  user_agent text NOT NULL,
+  ip character varying(45) NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL,
+  last_seen timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN sessions.id IS 'session is identified by its refresh token family';
+COMMENT ON COLUMN sessions.last_seen IS 'updated on every refresh token rotation';
+
+CREATE INDEX ON sessions (user_id);
+
+COMMIT;
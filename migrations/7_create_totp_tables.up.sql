@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE user_totp (
+  user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  secret character varying(64) NOT NULL,
+  last_step bigint DEFAULT 0 NOT NULL,
+  failures integer DEFAULT 0 NOT NULL,
+  confirmed timestamp with time zone,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN user_totp.secret IS 'base32 encoded shared secret';
+COMMENT ON COLUMN user_totp.last_step IS 'time step of the last used code, codes of the same or earlier steps are rejected';
+COMMENT ON COLUMN user_totp.failures IS 'consecutive failed attempts, reset by successful one';
+COMMENT ON COLUMN user_totp.confirmed IS 'second factor is required at login once enrollment is confirmed';
+
+CREATE TABLE recovery_codes (
+  code_hash bytea PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  used timestamp with time zone,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN recovery_codes.code_hash IS 'sha256 hash of the normalized recovery code';
+
+CREATE INDEX ON recovery_codes (user_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE webauthn_challenges;
+DROP TABLE webauthn_credentials;
+
+COMMIT;
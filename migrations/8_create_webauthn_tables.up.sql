@@ -0,0 +1,30 @@
+BEGIN;
+
+CREATE TABLE webauthn_credentials (
+  id bytea PRIMARY KEY,
+  user_id uuid NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name character varying(64) NOT NULL,
+  public_key bytea NOT NULL,
+  sign_count bigint DEFAULT 0 NOT NULL,
+  last_used timestamp with time zone,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN webauthn_credentials.id IS 'credential id generated by authenticator';
+COMMENT ON COLUMN webauthn_credentials.public_key IS 'COSE encoded credential public key';
+COMMENT ON COLUMN webauthn_credentials.sign_count IS 'signature counter of the last assertion, used to detect cloned authenticators';
+
+CREATE INDEX ON webauthn_credentials (user_id);
+
+CREATE TABLE webauthn_challenges (
+  challenge_hash bytea PRIMARY KEY,
+  user_id uuid REFERENCES users (id) ON DELETE CASCADE,
+  ceremony character varying(16) NOT NULL CHECK (ceremony IN ('registration', 'authentication')),
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON COLUMN webauthn_challenges.challenge_hash IS 'sha256 hash of the challenge';
+COMMENT ON COLUMN webauthn_challenges.user_id IS 'user performing the ceremony, empty for passwordless login';
+
+COMMIT;
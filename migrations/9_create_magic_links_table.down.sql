@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE magic_links;
+
+COMMIT;
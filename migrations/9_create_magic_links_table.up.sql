@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE magic_links (
+  user_id uuid PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  token_hash bytea UNIQUE NOT NULL,
+  expires timestamp with time zone NOT NULL,
+  created timestamp with time zone DEFAULT current_timestamp NOT NULL
+);
+
+COMMENT ON TABLE magic_links IS 'just the newest link of the user is valid, it is deleted once used';
+COMMENT ON COLUMN magic_links.token_hash IS 'sha256 hash of the login token';
+
+COMMIT;
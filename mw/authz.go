@@ -8,17 +8,24 @@ import (
 
 	"github.com/casbin/casbin"
 	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
 	"go.ectobit.com/lax"
 )
 
 // ErrInvalidSubject is returned when there is no sub within JWT claim or when it is not of a string type.
 var ErrInvalidSubject = errors.New("invalid subject")
 
-// Authorizer is middleware to enforce Casbin authorization.
-func Authorizer(enforcer *casbin.Enforcer, log lax.Logger) func(next http.Handler) http.Handler {
+// Authorizer is middleware to enforce Casbin authorization. Policies are matched against the subject and, if the
+// subject is not allowed itself, against the role of the user. Tokens issued to OAuth clients on behalf of users are
+// refused, so that third-party clients never gain privileges of the user, while clients acting on their own behalf
+// by client credentials grant are authorized by their subject like users.
+func Authorizer(
+	enforcer *casbin.Enforcer, users repository.Users, log lax.Logger,
+) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-			user, err := SubjectFromJWT(req.Context())
+			jwtToken, _, err := jwtauth.FromContext(req.Context())
 			if err != nil {
 				log.Warn("authorizer", lax.Error(err))
 				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
@@ -26,12 +33,36 @@ func Authorizer(enforcer *casbin.Enforcer, log lax.Logger) func(next http.Handle
 				return
 			}
 
-			if !enforcer.Enforce(user, req.URL.Path, req.Method) {
+			claims, err := token.ClaimsFromToken(jwtToken)
+			if err != nil {
+				log.Warn("authorizer", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			if claims.ClientID != "" && claims.ClientID != claims.Subject {
 				http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 
 				return
 			}
 
+			if !enforcer.Enforce(claims.Subject, req.URL.Path, req.Method) {
+				role, err := RoleOf(req.Context(), users, claims.Subject)
+				if err != nil {
+					log.Warn("authorizer role", lax.Error(err))
+					http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+					return
+				}
+
+				if role == "" || !enforcer.Enforce(role, req.URL.Path, req.Method) {
+					http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+					return
+				}
+			}
+
 			next.ServeHTTP(res, req)
 		})
 	}
@@ -56,3 +87,17 @@ func SubjectFromJWT(ctx context.Context) (string, error) {
 
 	return s, nil
 }
+
+// RoleOf returns role of the user. Subjects which are not users, like clients, have no role.
+func RoleOf(ctx context.Context, users repository.Users, subject string) (string, error) {
+	user, err := users.FindOne(ctx, subject)
+	if err != nil {
+		if errors.Is(err, repository.ErrResourceNotFound) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("find user: %w", err)
+	}
+
+	return user.Role, nil
+}
@@ -0,0 +1,55 @@
+package mw
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"go.ectobit.com/lax"
+)
+
+// CSRF token cookie and header names.
+const (
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// CSRF is middleware implementing double-submit cookie protection. Requests with unsafe methods, authenticated by
+// cookie, have to send value of the CSRF token cookie in the header as well. Requests authenticated by authorization
+// header are not subject to CSRF attacks and pass through.
+func CSRF(log lax.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if isSafeMethod(req.Method) || req.Header.Get("Authorization") != "" {
+				next.ServeHTTP(res, req)
+
+				return
+			}
+
+			cookie, err := req.Cookie(CSRFCookie)
+			if err != nil || cookie.Value == "" {
+				log.Warn("csrf", lax.String("reason", "missing cookie"))
+				http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+				return
+			}
+
+			if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(req.Header.Get(CSRFHeader))) != 1 {
+				log.Warn("csrf", lax.String("reason", "token mismatch"))
+				http.Error(res, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,41 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/lax"
+)
+
+// FirstParty is middleware restricting account management to tokens issued to first-party applications, so that
+// OAuth clients cannot act on the account of the user who authorized them. It has to be placed after Validator.
+func FirstParty(log lax.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			jwtToken, _, err := jwtauth.FromContext(req.Context())
+			if err != nil {
+				log.Warn("first party", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := token.ClaimsFromToken(jwtToken)
+			if err != nil {
+				log.Warn("first party", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			if claims.ClientID != "" {
+				http.Error(res, "first-party authentication required", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/lax"
+)
+
+// StepUpHeader is the name of the header carrying step-up token.
+const StepUpHeader = "X-Step-Up-Token"
+
+// StepUp is middleware protecting sensitive operations by demanding recent re-authentication. Step-up token has to
+// be issued to the same user and session as the auth token. It has to be placed after Validator.
+func StepUp(jwt *token.JWT, log lax.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			jwtToken, _, err := jwtauth.FromContext(req.Context())
+			if err != nil {
+				log.Warn("step-up", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := token.ClaimsFromToken(jwtToken)
+			if err != nil {
+				log.Warn("step-up", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			stepUpToken := req.Header.Get(StepUpHeader)
+			if stepUpToken == "" {
+				http.Error(res, "step-up authentication required", http.StatusForbidden)
+
+				return
+			}
+
+			stepUpClaims, err := jwt.ParseStepUpToken(stepUpToken)
+			if err != nil || stepUpClaims.Subject != claims.Subject || stepUpClaims.SessionID != claims.SessionID {
+				log.Warn("step-up", lax.String("reason", "invalid token"))
+				http.Error(res, "step-up authentication required", http.StatusForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"go.ectobit.com/arc/handler/token"
+	"go.ectobit.com/arc/repository"
+	"go.ectobit.com/lax"
+)
+
+// Validator is middleware to reject refresh and revoked tokens, including tokens of revoked sessions. It should be
+// used after jwtauth.Authenticator.
+func Validator(revocations repository.Revocations, sessions repository.Sessions,
+	log lax.Logger,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			jwtToken, _, err := jwtauth.FromContext(req.Context())
+			if err != nil {
+				log.Warn("validator", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			claims, err := token.ClaimsFromToken(jwtToken)
+			if err != nil {
+				log.Warn("validator", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			if claims.Type != token.TypeAuth {
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			revoked, err := revocations.IsRevoked(req.Context(), claims.ID, claims.Subject, claims.IssuedAt)
+			if err != nil {
+				log.Warn("is revoked", lax.Error(err))
+				http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+				return
+			}
+
+			if revoked {
+				http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+				return
+			}
+
+			if claims.SessionID != "" {
+				active, err := sessions.IsActive(req.Context(), claims.SessionID)
+				if err != nil {
+					log.Warn("is session active", lax.Error(err))
+					http.Error(res, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+					return
+				}
+
+				if !active {
+					http.Error(res, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+					return
+				}
+			}
+
+			next.ServeHTTP(res, req)
+		})
+	}
+}
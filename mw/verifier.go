@@ -0,0 +1,36 @@
+package mw
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"go.ectobit.com/arc/handler/token"
+)
+
+// Verifier is middleware to verify jwt token found in authorization header or cookie using all keys of the
+// keyring. Verification result is stored in context in the same way as jwtauth.Verifier does, so that
+// jwtauth.Authenticator may be used afterwards.
+func Verifier(jwtToken *token.JWT) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			var (
+				verified jwt.Token
+				err      error = jwtauth.ErrNoTokenFound
+			)
+
+			tokenString := jwtauth.TokenFromHeader(req)
+			if tokenString == "" {
+				tokenString = jwtauth.TokenFromCookie(req)
+			}
+
+			if tokenString != "" {
+				if verified, err = jwtToken.Verify(tokenString); err != nil {
+					err = jwtauth.ErrorReason(err)
+				}
+			}
+
+			next.ServeHTTP(res, req.WithContext(jwtauth.NewContext(req.Context(), verified, err)))
+		})
+	}
+}
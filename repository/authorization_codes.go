@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// AuthorizationCodes abstracts OAuth authorization codes repository methods.
+type AuthorizationCodes interface {
+	// Create stores authorization code using provided code hash.
+	Create(ctx context.Context, codeHash []byte, code *domain.AuthorizationCode) error
+	// Exchange marks not expired authorization code as used and returns it. If already used code is provided,
+	// refresh token family started by its first exchange gets revoked.
+	Exchange(ctx context.Context, codeHash []byte) (*domain.AuthorizationCode, error)
+}
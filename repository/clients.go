@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Clients abstracts OAuth clients repository methods.
+type Clients interface {
+	// Create registers new OAuth client.
+	Create(ctx context.Context, client *domain.Client) (*domain.Client, error)
+	// FindOne fetches OAuth client using ID.
+	FindOne(ctx context.Context, id string) (*domain.Client, error)
+	// FindAll fetches all OAuth clients.
+	FindAll(ctx context.Context) ([]domain.Client, error)
+	// SetSecret replaces secret hash of OAuth client.
+	SetSecret(ctx context.Context, id string, secretHash []byte) error
+	// Delete deletes OAuth client together with its authorization codes and consents.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Consents abstracts OAuth consents repository methods.
+type Consents interface {
+	// Grant stores user's consent to the client accessing provided scopes, extending previously granted ones.
+	Grant(ctx context.Context, userID, clientID string, scopes []string) error
+	// IsGranted checks if user already consented to the client accessing all provided scopes.
+	IsGranted(ctx context.Context, userID, clientID string, scopes []string) (bool, error)
+	// FindAll fetches all consents of the user.
+	FindAll(ctx context.Context, userID string) ([]domain.Consent, error)
+}
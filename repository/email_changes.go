@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// EmailChanges abstracts email address changes repository methods.
+type EmailChanges interface {
+	// Create stores new pending email change of the user, invalidating the previous one.
+	Create(ctx context.Context, emailChange *domain.EmailChange) error
+	// Confirm deletes pending email change and swaps email address of the user. ErrTokenExpired is returned for
+	// expired changes, ErrResourceNotFound for unknown ones and ErrUniqueViolation if the new address has been taken
+	// in the meantime.
+	Confirm(ctx context.Context, confirmTokenHash []byte) (*domain.User, error)
+	// Cancel deletes pending email change. ErrResourceNotFound is returned if there is no such change.
+	Cancel(ctx context.Context, cancelTokenHash []byte) error
+	// FindAll fetches pending email changes of the user, the newest first.
+	FindAll(ctx context.Context, userID string) ([]domain.EmailChange, error)
+}
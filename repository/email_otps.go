@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// EmailOTPs abstracts email one-time password challenges and second factor enrollments repository methods.
+type EmailOTPs interface {
+	// Enable requires email OTP as the second factor at login of the user. Enabling it again has no effect.
+	Enable(ctx context.Context, userID string) error
+	// IsEnabled checks if email OTP is required as the second factor at login of the user.
+	IsEnabled(ctx context.Context, userID string) (bool, error)
+	// Disable stops requiring email OTP at login of the user and deletes pending login challenge.
+	// ErrResourceNotFound is returned if it hasn't been enabled.
+	Disable(ctx context.Context, userID string) error
+	// Create stores new challenge of the user, invalidating the previous one of the same purpose. Failed attempts
+	// of the previous challenge are kept unless it has expired, so resending doesn't grant new attempts.
+	Create(ctx context.Context, emailOTP *domain.EmailOTP) error
+	// FindOne fetches challenge of the user by purpose.
+	FindOne(ctx context.Context, userID, purpose string) (*domain.EmailOTP, error)
+	// Attempt counts verification attempt of the challenge unless there have already been maxAttempts attempts or
+	// the challenge has expired. Check and increment are atomic, so parallel attempts can't exceed the limit.
+	// ErrTooManyAttempts is returned if the attempt is refused.
+	Attempt(ctx context.Context, userID, purpose string, maxAttempts int) error
+	// Delete deletes challenge once it is used. ErrResourceNotFound is returned if it has already been deleted.
+	Delete(ctx context.Context, userID, purpose string) error
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Invitations abstracts invitations repository methods.
+type Invitations interface {
+	// Create stores new invitation, replacing pending invitation of the same email address.
+	Create(ctx context.Context, invitation *domain.Invitation) (*domain.Invitation, error)
+	// FindAll fetches pending invitations, the newest first.
+	FindAll(ctx context.Context) ([]domain.Invitation, error)
+	// FindAllByUser fetches invitations sent or accepted by the user, the newest first.
+	FindAllByUser(ctx context.Context, userID string) ([]domain.Invitation, error)
+	// FindOneByToken fetches pending invitation using hash of its token. ErrTokenExpired is returned for expired
+	// invitations and ErrResourceNotFound for unknown or already accepted ones.
+	FindOneByToken(ctx context.Context, tokenHash []byte) (*domain.Invitation, error)
+	// Accept creates already activated user with provided password and role of the pending invitation and marks
+	// the invitation as accepted. ErrTokenExpired is returned for expired invitations, ErrResourceNotFound for
+	// unknown or already accepted ones and ErrUniqueViolation if the email address has been registered in the
+	// meantime.
+	Accept(ctx context.Context, tokenHash, password []byte) (*domain.User, error)
+	// Delete deletes pending invitation. ErrResourceNotFound is returned if there is no such invitation.
+	Delete(ctx context.Context, id string) error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// MagicLinks abstracts magic login links repository methods.
+type MagicLinks interface {
+	// Create stores new magic link of the user, invalidating the previous one, unless the previous link has been
+	// created after provided time. ErrResourceNotFound is returned if the previous link is too recent.
+	Create(ctx context.Context, magicLink *domain.MagicLink, createdBefore time.Time) error
+	// Consume deletes not expired magic link and returns it. ErrResourceNotFound is returned if there is no such link.
+	Consume(ctx context.Context, tokenHash []byte) (*domain.MagicLink, error)
+}
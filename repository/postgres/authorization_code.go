@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// AuthorizationCode entity.
+type AuthorizationCode struct {
+	ClientID      string
+	UserID        string
+	FamilyID      string
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	Nonce         string
+	AuthTime      pgtype.Timestamptz
+	Used          pgtype.Timestamptz
+	Expires       pgtype.Timestamptz
+	Created       pgtype.Timestamptz
+}
+
+// DomainAuthorizationCode converts authorization code entity to domain authorization code.
+func (c *AuthorizationCode) DomainAuthorizationCode() *domain.AuthorizationCode {
+	domainCode := &domain.AuthorizationCode{ //nolint:exhaustruct
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		FamilyID:      c.FamilyID,
+		RedirectURI:   c.RedirectURI,
+		Scope:         c.Scope,
+		CodeChallenge: c.CodeChallenge,
+		Nonce:         c.Nonce,
+	}
+
+	if c.AuthTime.Status == pgtype.Present {
+		domainCode.AuthTime = &c.AuthTime.Time
+	}
+
+	if c.Used.Status == pgtype.Present {
+		domainCode.Used = &c.Used.Time
+	}
+
+	if c.Expires.Status == pgtype.Present {
+		domainCode.Expires = &c.Expires.Time
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainCode.Created = &c.Created.Time
+	}
+
+	return domainCode
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.AuthorizationCodes = (*AuthorizationCodesRepository)(nil)
+
+// AuthorizationCodesRepository implements repository.AuthorizationCodes interface using PostgreSQL database.
+type AuthorizationCodesRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuthorizationCodesRepository creates new authorization codes repository using PostgreSQL database.
+func NewAuthorizationCodesRepository(conn *pgxpool.Pool) *AuthorizationCodesRepository {
+	return &AuthorizationCodesRepository{pool: conn}
+}
+
+// Create stores authorization code in PostgreSQL database.
+func (repo *AuthorizationCodesRepository) Create(ctx context.Context, codeHash []byte,
+	code *domain.AuthorizationCode,
+) error {
+	query := `INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, family_id, redirect_uri, scope,
+code_challenge, nonce, auth_time, expires) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), codeHash, code.ClientID, code.UserID,
+		code.FamilyID, code.RedirectURI, code.Scope, code.CodeChallenge, code.Nonce, code.AuthTime,
+		code.Expires); err != nil {
+		return repositoryError("create authorization code", err)
+	}
+
+	return nil
+}
+
+// Exchange marks authorization code as used in PostgreSQL database. Reuse of already exchanged code revokes
+// refresh token family started by its first exchange.
+func (repo *AuthorizationCodesRepository) Exchange(ctx context.Context,
+	codeHash []byte,
+) (*domain.AuthorizationCode, error) {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return nil, repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `SELECT client_id, user_id, family_id, redirect_uri, scope, code_challenge, nonce, auth_time, used,
+expires, created FROM oauth_authorization_codes WHERE code_hash=$1 AND expires>now() FOR UPDATE`
+
+	row := tx.QueryRow(ctx, repository.StripWhitespaces(query), codeHash)
+
+	var code AuthorizationCode
+
+	if err := row.Scan(&code.ClientID, &code.UserID, &code.FamilyID, &code.RedirectURI, &code.Scope,
+		&code.CodeChallenge, &code.Nonce, &code.AuthTime, &code.Used, &code.Expires, &code.Created); err != nil {
+		return nil, repositoryError("find authorization code", err)
+	}
+
+	if code.Used.Status == pgtype.Present {
+		if err := revokeFamily(ctx, tx, code.FamilyID); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return nil, repositoryError("commit", err)
+		}
+
+		return nil, repository.ErrTokenReused
+	}
+
+	query = "UPDATE oauth_authorization_codes SET used=now() WHERE code_hash=$1 RETURNING used"
+
+	if err := tx.QueryRow(ctx, query, codeHash).Scan(&code.Used); err != nil {
+		return nil, repositoryError("mark authorization code used", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repositoryError("commit", err)
+	}
+
+	return code.DomainAuthorizationCode(), nil
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// Client entity.
+type Client struct {
+	ID           string
+	Name         string
+	SecretHash   pgtype.Bytea
+	RedirectURIs pgtype.TextArray
+	Scopes       pgtype.TextArray
+	Trusted      bool
+	Created      pgtype.Timestamptz
+}
+
+// DomainClient converts client entity to domain client.
+func (c *Client) DomainClient() (*domain.Client, error) {
+	domainClient := &domain.Client{ //nolint:exhaustruct
+		ID:      c.ID,
+		Name:    c.Name,
+		Trusted: c.Trusted,
+	}
+
+	if c.SecretHash.Status == pgtype.Present {
+		domainClient.SecretHash = c.SecretHash.Bytes
+	}
+
+	if c.RedirectURIs.Status == pgtype.Present {
+		if err := c.RedirectURIs.AssignTo(&domainClient.RedirectURIs); err != nil {
+			return nil, fmt.Errorf("assign redirect uris: %w", err)
+		}
+	}
+
+	if c.Scopes.Status == pgtype.Present {
+		if err := c.Scopes.AssignTo(&domainClient.Scopes); err != nil {
+			return nil, fmt.Errorf("assign scopes: %w", err)
+		}
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainClient.Created = &c.Created.Time
+	}
+
+	return domainClient, nil
+}
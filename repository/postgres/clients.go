@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.Clients = (*ClientsRepository)(nil)
+
+// ClientsRepository implements repository.Clients interface using PostgreSQL database.
+type ClientsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewClientsRepository creates new OAuth clients repository using PostgreSQL database.
+func NewClientsRepository(conn *pgxpool.Pool) *ClientsRepository {
+	return &ClientsRepository{pool: conn}
+}
+
+// Create registers new OAuth client in PostgreSQL database.
+func (repo *ClientsRepository) Create(ctx context.Context, client *domain.Client) (*domain.Client, error) {
+	query := `INSERT INTO oauth_clients (name, secret_hash, redirect_uris, scopes, trusted)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, name, secret_hash, redirect_uris, scopes, trusted, created`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), client.Name, client.SecretHash,
+		client.RedirectURIs, client.Scopes, client.Trusted)
+
+	var entity Client
+
+	if err := row.Scan(&entity.ID, &entity.Name, &entity.SecretHash, &entity.RedirectURIs, &entity.Scopes,
+		&entity.Trusted, &entity.Created); err != nil {
+		return nil, repositoryError("create client", err)
+	}
+
+	domainClient, err := entity.DomainClient()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain client: %w", err)
+	}
+
+	return domainClient, nil
+}
+
+// FindOne fetches OAuth client from PostgreSQL database using ID.
+func (repo *ClientsRepository) FindOne(ctx context.Context, id string) (*domain.Client, error) {
+	query := "SELECT id, name, secret_hash, redirect_uris, scopes, trusted, created FROM oauth_clients WHERE id=$1"
+
+	row := repo.pool.QueryRow(ctx, query, id)
+
+	var client Client
+
+	if err := row.Scan(&client.ID, &client.Name, &client.SecretHash, &client.RedirectURIs,
+		&client.Scopes, &client.Trusted, &client.Created); err != nil {
+		return nil, repositoryError("find client", err)
+	}
+
+	domainClient, err := client.DomainClient()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain client: %w", err)
+	}
+
+	return domainClient, nil
+}
+
+// FindAll fetches all OAuth clients from PostgreSQL database.
+func (repo *ClientsRepository) FindAll(ctx context.Context) ([]domain.Client, error) {
+	query := "SELECT id, name, secret_hash, redirect_uris, scopes, trusted, created FROM oauth_clients ORDER BY created"
+
+	rows, err := repo.pool.Query(ctx, query)
+	if err != nil {
+		return nil, repositoryError("fetch all clients", err)
+	}
+
+	defer rows.Close()
+
+	domainClients := []domain.Client{}
+
+	for rows.Next() {
+		var client Client
+
+		if err := rows.Scan(&client.ID, &client.Name, &client.SecretHash, &client.RedirectURIs,
+			&client.Scopes, &client.Trusted, &client.Created); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		domainClient, err := client.DomainClient()
+		if err != nil {
+			return nil, fmt.Errorf("convert to domain client: %w", err)
+		}
+
+		domainClients = append(domainClients, *domainClient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return domainClients, nil
+}
+
+// SetSecret replaces secret hash of OAuth client in PostgreSQL database.
+func (repo *ClientsRepository) SetSecret(ctx context.Context, id string, secretHash []byte) error {
+	tag, err := repo.pool.Exec(ctx, "UPDATE oauth_clients SET secret_hash=$1 WHERE id=$2", secretHash, id)
+	if err != nil {
+		return repositoryError("set client secret", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// Delete deletes OAuth client from PostgreSQL database.
+func (repo *ClientsRepository) Delete(ctx context.Context, id string) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM oauth_clients WHERE id=$1", id)
+	if err != nil {
+		return repositoryError("delete client", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// Consent entity.
+type Consent struct {
+	UserID   string
+	ClientID string
+	Scopes   pgtype.TextArray
+	Created  pgtype.Timestamptz
+	Updated  pgtype.Timestamptz
+}
+
+// DomainConsent converts consent entity to domain consent.
+func (c *Consent) DomainConsent() (*domain.Consent, error) {
+	domainConsent := &domain.Consent{ //nolint:exhaustruct
+		UserID:   c.UserID,
+		ClientID: c.ClientID,
+	}
+
+	if c.Scopes.Status == pgtype.Present {
+		if err := c.Scopes.AssignTo(&domainConsent.Scopes); err != nil {
+			return nil, fmt.Errorf("assign scopes: %w", err)
+		}
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainConsent.Created = &c.Created.Time
+	}
+
+	if c.Updated.Status == pgtype.Present {
+		domainConsent.Updated = &c.Updated.Time
+	}
+
+	return domainConsent, nil
+}
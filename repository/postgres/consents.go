@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.Consents = (*ConsentsRepository)(nil)
+
+// ConsentsRepository implements repository.Consents interface using PostgreSQL database.
+type ConsentsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewConsentsRepository creates new OAuth consents repository using PostgreSQL database.
+func NewConsentsRepository(conn *pgxpool.Pool) *ConsentsRepository {
+	return &ConsentsRepository{pool: conn}
+}
+
+// Grant stores user's consent in PostgreSQL database.
+func (repo *ConsentsRepository) Grant(ctx context.Context, userID, clientID string, scopes []string) error {
+	query := `INSERT INTO oauth_consents (user_id, client_id, scopes) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes=ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes)), updated=now()`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), userID, clientID, scopes); err != nil {
+		return repositoryError("grant consent", err)
+	}
+
+	return nil
+}
+
+// IsGranted checks user's consent in PostgreSQL database.
+func (repo *ConsentsRepository) IsGranted(ctx context.Context, userID, clientID string,
+	scopes []string,
+) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM oauth_consents WHERE user_id=$1 AND client_id=$2 AND scopes @> $3)`
+
+	var granted bool
+
+	if err := repo.pool.QueryRow(ctx, query, userID, clientID, scopes).Scan(&granted); err != nil {
+		return false, repositoryError("is consent granted", err)
+	}
+
+	return granted, nil
+}
+
+// FindAll fetches all consents of the user from PostgreSQL database.
+func (repo *ConsentsRepository) FindAll(ctx context.Context, userID string) ([]domain.Consent, error) {
+	query := `SELECT user_id, client_id, scopes, created, updated FROM oauth_consents WHERE user_id=$1
+ORDER BY created`
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), userID)
+	if err != nil {
+		return nil, repositoryError("fetch all consents", err)
+	}
+
+	defer rows.Close()
+
+	domainConsents := []domain.Consent{}
+
+	for rows.Next() {
+		var consent Consent
+
+		if err := rows.Scan(&consent.UserID, &consent.ClientID, &consent.Scopes, &consent.Created,
+			&consent.Updated); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		domainConsent, err := consent.DomainConsent()
+		if err != nil {
+			return nil, fmt.Errorf("convert to domain consent: %w", err)
+		}
+
+		domainConsents = append(domainConsents, *domainConsent)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return domainConsents, nil
+}
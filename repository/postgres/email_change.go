@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// EmailChange entity.
+type EmailChange struct {
+	UserID           string
+	NewEmail         string
+	ConfirmTokenHash []byte
+	CancelTokenHash  []byte
+	Expires          pgtype.Timestamptz
+	Created          pgtype.Timestamptz
+}
+
+// DomainEmailChange converts email change entity to domain email change.
+func (c *EmailChange) DomainEmailChange() *domain.EmailChange {
+	domainEmailChange := &domain.EmailChange{ //nolint:exhaustruct
+		UserID:           c.UserID,
+		NewEmail:         c.NewEmail,
+		ConfirmTokenHash: c.ConfirmTokenHash,
+		CancelTokenHash:  c.CancelTokenHash,
+		Expires:          c.Expires.Time,
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainEmailChange.Created = &c.Created.Time
+	}
+
+	return domainEmailChange
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.EmailChanges = (*EmailChangesRepository)(nil)
+
+// EmailChangesRepository implements repository.EmailChanges interface using PostgreSQL database.
+type EmailChangesRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewEmailChangesRepository creates new email changes repository using PostgreSQL database.
+func NewEmailChangesRepository(conn *pgxpool.Pool) *EmailChangesRepository {
+	return &EmailChangesRepository{pool: conn}
+}
+
+// Create stores new email change in PostgreSQL database replacing the previous one of the same user.
+func (repo *EmailChangesRepository) Create(ctx context.Context, emailChange *domain.EmailChange) error {
+	query := `INSERT INTO email_changes (user_id, new_email, confirm_token_hash, cancel_token_hash, expires)
+VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id) DO UPDATE SET new_email=EXCLUDED.new_email,
+confirm_token_hash=EXCLUDED.confirm_token_hash, cancel_token_hash=EXCLUDED.cancel_token_hash,
+expires=EXCLUDED.expires, created=now()`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), emailChange.UserID, emailChange.NewEmail,
+		emailChange.ConfirmTokenHash, emailChange.CancelTokenHash, emailChange.Expires); err != nil {
+		return repositoryError("create email change", err)
+	}
+
+	return nil
+}
+
+// Confirm deletes email change from PostgreSQL database and swaps email address of the user within the same
+// transaction. Pending password reset token is dropped since it has been sent to the old address.
+func (repo *EmailChangesRepository) Confirm(ctx context.Context, confirmTokenHash []byte) (*domain.User, error) {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return nil, repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `DELETE FROM email_changes WHERE confirm_token_hash=$1
+RETURNING user_id, new_email, confirm_token_hash, cancel_token_hash, expires, created`
+
+	var emailChange EmailChange
+
+	if err := tx.QueryRow(ctx, repository.StripWhitespaces(query), confirmTokenHash).Scan(&emailChange.UserID,
+		&emailChange.NewEmail, &emailChange.ConfirmTokenHash, &emailChange.CancelTokenHash, &emailChange.Expires,
+		&emailChange.Created); err != nil {
+		return nil, repositoryError("delete email change", err)
+	}
+
+	if emailChange.DomainEmailChange().IsExpired(time.Now()) {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, repositoryError("commit", err)
+		}
+
+		return nil, repository.ErrTokenExpired
+	}
+
+	query = `UPDATE users SET email=$2, recovery_token_hash=NULL, recovery_token_issued=NULL, updated=now()
+WHERE id=$1 RETURNING id, email, created, updated, active, display_name, locale, timezone, metadata`
+
+	var user User
+
+	if err := tx.QueryRow(ctx, repository.StripWhitespaces(query), emailChange.UserID,
+		emailChange.NewEmail).Scan(&user.ID, &user.Email, &user.Created, &user.Updated, &user.Active,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("change email", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repositoryError("commit", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// Cancel deletes email change from PostgreSQL database.
+func (repo *EmailChangesRepository) Cancel(ctx context.Context, cancelTokenHash []byte) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM email_changes WHERE cancel_token_hash=$1", cancelTokenHash)
+	if err != nil {
+		return repositoryError("cancel email change", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// FindAll fetches pending email changes of the user from PostgreSQL database, the newest first.
+func (repo *EmailChangesRepository) FindAll(ctx context.Context, userID string) ([]domain.EmailChange, error) {
+	query := `SELECT user_id, new_email, confirm_token_hash, cancel_token_hash, expires, created FROM email_changes
+WHERE user_id=$1 ORDER BY created DESC`
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), userID)
+	if err != nil {
+		return nil, repositoryError("fetch email changes", err)
+	}
+
+	defer rows.Close()
+
+	emailChanges := []domain.EmailChange{}
+
+	for rows.Next() {
+		var entity EmailChange
+
+		if err := rows.Scan(&entity.UserID, &entity.NewEmail, &entity.ConfirmTokenHash, &entity.CancelTokenHash,
+			&entity.Expires, &entity.Created); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		emailChanges = append(emailChanges, *entity.DomainEmailChange())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return emailChanges, nil
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// EmailOTP entity.
+type EmailOTP struct {
+	UserID   string
+	Purpose  string
+	CodeHash []byte
+	Attempts int
+	Expires  pgtype.Timestamptz
+	Created  pgtype.Timestamptz
+}
+
+// DomainEmailOTP converts email OTP entity to domain email OTP.
+func (o *EmailOTP) DomainEmailOTP() *domain.EmailOTP {
+	domainEmailOTP := &domain.EmailOTP{ //nolint:exhaustruct
+		UserID:   o.UserID,
+		Purpose:  o.Purpose,
+		CodeHash: o.CodeHash,
+		Attempts: o.Attempts,
+		Expires:  o.Expires.Time,
+	}
+
+	if o.Created.Status == pgtype.Present {
+		domainEmailOTP.Created = &o.Created.Time
+	}
+
+	return domainEmailOTP
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.EmailOTPs = (*EmailOTPsRepository)(nil)
+
+// EmailOTPsRepository implements repository.EmailOTPs interface using PostgreSQL database.
+type EmailOTPsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewEmailOTPsRepository creates new email OTPs repository using PostgreSQL database.
+func NewEmailOTPsRepository(conn *pgxpool.Pool) *EmailOTPsRepository {
+	return &EmailOTPsRepository{pool: conn}
+}
+
+// Enable stores email OTP second factor enrollment of the user in PostgreSQL database.
+func (repo *EmailOTPsRepository) Enable(ctx context.Context, userID string) error {
+	query := "INSERT INTO email_otp_enrollments (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING"
+
+	if _, err := repo.pool.Exec(ctx, query, userID); err != nil {
+		return repositoryError("enable email otp", err)
+	}
+
+	return nil
+}
+
+// IsEnabled checks if email OTP second factor enrollment of the user exists in PostgreSQL database.
+func (repo *EmailOTPsRepository) IsEnabled(ctx context.Context, userID string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM email_otp_enrollments WHERE user_id=$1)"
+
+	var enabled bool
+
+	if err := repo.pool.QueryRow(ctx, query, userID).Scan(&enabled); err != nil {
+		return false, repositoryError("is email otp enabled", err)
+	}
+
+	return enabled, nil
+}
+
+// Disable deletes email OTP second factor enrollment and pending login challenge of the user from PostgreSQL
+// database.
+func (repo *EmailOTPsRepository) Disable(ctx context.Context, userID string) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "DELETE FROM email_otp_enrollments WHERE user_id=$1", userID)
+	if err != nil {
+		return repositoryError("disable email otp", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	query := "DELETE FROM email_otps WHERE user_id=$1 AND purpose=$2"
+
+	if _, err := tx.Exec(ctx, query, userID, domain.EmailOTPLogin); err != nil {
+		return repositoryError("delete email otp", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return repositoryError("commit", err)
+	}
+
+	return nil
+}
+
+// Create stores new email OTP challenge in PostgreSQL database replacing the previous one of the same user and
+// purpose. Attempts are reset just if the previous challenge has expired.
+func (repo *EmailOTPsRepository) Create(ctx context.Context, emailOTP *domain.EmailOTP) error {
+	query := `INSERT INTO email_otps (user_id, purpose, code_hash, expires) VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, purpose) DO UPDATE SET code_hash=EXCLUDED.code_hash,
+attempts=CASE WHEN email_otps.expires>now() THEN email_otps.attempts ELSE 0 END, expires=EXCLUDED.expires,
+created=now()`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), emailOTP.UserID, emailOTP.Purpose,
+		emailOTP.CodeHash, emailOTP.Expires); err != nil {
+		return repositoryError("create email otp", err)
+	}
+
+	return nil
+}
+
+// FindOne fetches email OTP challenge of the user by purpose from PostgreSQL database.
+func (repo *EmailOTPsRepository) FindOne(ctx context.Context, userID, purpose string) (*domain.EmailOTP, error) {
+	query := `SELECT user_id, purpose, code_hash, attempts, expires, created FROM email_otps
+WHERE user_id=$1 AND purpose=$2`
+
+	var emailOTP EmailOTP
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), userID, purpose).Scan(&emailOTP.UserID,
+		&emailOTP.Purpose, &emailOTP.CodeHash, &emailOTP.Attempts, &emailOTP.Expires,
+		&emailOTP.Created); err != nil {
+		return nil, repositoryError("find email otp", err)
+	}
+
+	return emailOTP.DomainEmailOTP(), nil
+}
+
+// Attempt increments attempts of email OTP challenge in PostgreSQL database unless the limit has been reached or
+// the challenge has expired.
+func (repo *EmailOTPsRepository) Attempt(ctx context.Context, userID, purpose string, maxAttempts int) error {
+	query := `UPDATE email_otps SET attempts=attempts+1
+WHERE user_id=$1 AND purpose=$2 AND attempts<$3 AND expires>now() RETURNING attempts`
+
+	var attempts int
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), userID, purpose,
+		maxAttempts).Scan(&attempts); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrTooManyAttempts
+		}
+
+		return repositoryError("attempt email otp", err)
+	}
+
+	return nil
+}
+
+// Delete deletes email OTP challenge from PostgreSQL database.
+func (repo *EmailOTPsRepository) Delete(ctx context.Context, userID, purpose string) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM email_otps WHERE user_id=$1 AND purpose=$2", userID, purpose)
+	if err != nil {
+		return repositoryError("delete email otp", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
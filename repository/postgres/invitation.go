@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// Invitation entity.
+type Invitation struct {
+	ID        string
+	Email     string
+	Role      string
+	Inviter   string
+	TokenHash []byte
+	Expires   pgtype.Timestamptz
+	Accepted  pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
+// DomainInvitation converts invitation entity to domain invitation.
+func (i *Invitation) DomainInvitation() *domain.Invitation {
+	domainInvitation := &domain.Invitation{ //nolint:exhaustruct
+		ID:        i.ID,
+		Email:     i.Email,
+		Role:      i.Role,
+		Inviter:   i.Inviter,
+		TokenHash: i.TokenHash,
+		Expires:   i.Expires.Time,
+	}
+
+	if i.Accepted.Status == pgtype.Present {
+		domainInvitation.Accepted = &i.Accepted.Time
+	}
+
+	if i.Created.Status == pgtype.Present {
+		domainInvitation.Created = &i.Created.Time
+	}
+
+	return domainInvitation
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.Invitations = (*InvitationsRepository)(nil)
+
+// InvitationsRepository implements repository.Invitations interface using PostgreSQL database.
+type InvitationsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewInvitationsRepository creates new invitations repository using PostgreSQL database.
+func NewInvitationsRepository(conn *pgxpool.Pool) *InvitationsRepository {
+	return &InvitationsRepository{pool: conn}
+}
+
+// Create stores new invitation in PostgreSQL database replacing pending invitation of the same email address.
+func (repo *InvitationsRepository) Create(ctx context.Context,
+	invitation *domain.Invitation,
+) (*domain.Invitation, error) {
+	query := `INSERT INTO invitations (email, role, inviter, token_hash, expires) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (email) WHERE accepted IS NULL DO UPDATE SET role=EXCLUDED.role, inviter=EXCLUDED.inviter,
+token_hash=EXCLUDED.token_hash, expires=EXCLUDED.expires, created=now()
+RETURNING id, email, role, inviter, token_hash, expires, accepted, created`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), invitation.Email, invitation.Role,
+		invitation.Inviter, invitation.TokenHash, invitation.Expires)
+
+	var entity Invitation
+
+	if err := row.Scan(&entity.ID, &entity.Email, &entity.Role, &entity.Inviter, &entity.TokenHash,
+		&entity.Expires, &entity.Accepted, &entity.Created); err != nil {
+		return nil, repositoryError("create invitation", err)
+	}
+
+	return entity.DomainInvitation(), nil
+}
+
+// FindAll fetches pending invitations from PostgreSQL database, the newest first.
+func (repo *InvitationsRepository) FindAll(ctx context.Context) ([]domain.Invitation, error) {
+	query := `SELECT id, email, role, inviter, token_hash, expires, accepted, created FROM invitations
+WHERE accepted IS NULL ORDER BY created DESC`
+
+	return repo.findAll(ctx, query)
+}
+
+// FindAllByUser fetches invitations sent or accepted by the user from PostgreSQL database, the newest first.
+func (repo *InvitationsRepository) FindAllByUser(ctx context.Context, userID string) ([]domain.Invitation, error) {
+	query := `SELECT id, email, role, inviter, token_hash, expires, accepted, created FROM invitations
+WHERE inviter=$1 OR user_id::text=$1 ORDER BY created DESC`
+
+	return repo.findAll(ctx, query, userID)
+}
+
+func (repo *InvitationsRepository) findAll(ctx context.Context, query string,
+	args ...interface{},
+) ([]domain.Invitation, error) {
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), args...)
+	if err != nil {
+		return nil, repositoryError("fetch invitations", err)
+	}
+
+	defer rows.Close()
+
+	invitations := []domain.Invitation{}
+
+	for rows.Next() {
+		var entity Invitation
+
+		if err := rows.Scan(&entity.ID, &entity.Email, &entity.Role, &entity.Inviter, &entity.TokenHash,
+			&entity.Expires, &entity.Accepted, &entity.Created); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		invitations = append(invitations, *entity.DomainInvitation())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return invitations, nil
+}
+
+// FindOneByToken fetches pending invitation from PostgreSQL database using hash of its token.
+func (repo *InvitationsRepository) FindOneByToken(ctx context.Context,
+	tokenHash []byte,
+) (*domain.Invitation, error) {
+	query := `SELECT id, email, role, inviter, token_hash, expires, accepted, created FROM invitations
+WHERE token_hash=$1 AND accepted IS NULL`
+
+	var entity Invitation
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), tokenHash).Scan(&entity.ID, &entity.Email,
+		&entity.Role, &entity.Inviter, &entity.TokenHash, &entity.Expires, &entity.Accepted,
+		&entity.Created); err != nil {
+		return nil, repositoryError("find invitation", err)
+	}
+
+	invitation := entity.DomainInvitation()
+	if invitation.IsExpired(time.Now()) {
+		return nil, repository.ErrTokenExpired
+	}
+
+	return invitation, nil
+}
+
+// Accept creates already activated user in PostgreSQL database and marks the invitation as accepted within the same
+// transaction.
+func (repo *InvitationsRepository) Accept(ctx context.Context, tokenHash, password []byte) (*domain.User, error) {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return nil, repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `SELECT id, email, role, inviter, token_hash, expires, accepted, created FROM invitations
+WHERE token_hash=$1 AND accepted IS NULL FOR UPDATE`
+
+	var invitation Invitation
+
+	if err := tx.QueryRow(ctx, repository.StripWhitespaces(query), tokenHash).Scan(&invitation.ID,
+		&invitation.Email, &invitation.Role, &invitation.Inviter, &invitation.TokenHash, &invitation.Expires,
+		&invitation.Accepted, &invitation.Created); err != nil {
+		return nil, repositoryError("find invitation", err)
+	}
+
+	if invitation.DomainInvitation().IsExpired(time.Now()) {
+		return nil, repository.ErrTokenExpired
+	}
+
+	query = `INSERT INTO users (email, password, role, active, activated) VALUES ($1, $2, $3, TRUE, now())
+RETURNING id, email, role, created, activated, active, display_name, locale, timezone, metadata`
+
+	var user User
+
+	if err := tx.QueryRow(ctx, repository.StripWhitespaces(query), invitation.Email, password,
+		invitation.Role).Scan(&user.ID, &user.Email, &user.Role, &user.Created, &user.Activated, &user.Active,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("create invited user", err)
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE invitations SET accepted=now(), user_id=$2 WHERE id=$1", invitation.ID,
+		user.ID); err != nil {
+		return nil, repositoryError("accept invitation", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repositoryError("commit", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// Delete deletes pending invitation from PostgreSQL database.
+func (repo *InvitationsRepository) Delete(ctx context.Context, id string) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM invitations WHERE id=$1 AND accepted IS NULL", id)
+	if err != nil {
+		return repositoryError("delete invitation", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
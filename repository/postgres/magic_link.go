@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// MagicLink entity.
+type MagicLink struct {
+	UserID    string
+	TokenHash []byte
+	Expires   pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
+// DomainMagicLink converts magic link entity to domain magic link.
+func (l *MagicLink) DomainMagicLink() *domain.MagicLink {
+	domainMagicLink := &domain.MagicLink{ //nolint:exhaustruct
+		UserID:    l.UserID,
+		TokenHash: l.TokenHash,
+		Expires:   l.Expires.Time,
+	}
+
+	if l.Created.Status == pgtype.Present {
+		domainMagicLink.Created = &l.Created.Time
+	}
+
+	return domainMagicLink
+}
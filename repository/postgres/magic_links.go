@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.MagicLinks = (*MagicLinksRepository)(nil)
+
+// MagicLinksRepository implements repository.MagicLinks interface using PostgreSQL database.
+type MagicLinksRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewMagicLinksRepository creates new magic links repository using PostgreSQL database.
+func NewMagicLinksRepository(conn *pgxpool.Pool) *MagicLinksRepository {
+	return &MagicLinksRepository{pool: conn}
+}
+
+// Create stores new magic link in PostgreSQL database replacing the previous one of the same user.
+func (repo *MagicLinksRepository) Create(ctx context.Context, magicLink *domain.MagicLink,
+	createdBefore time.Time,
+) error {
+	query := `INSERT INTO magic_links (user_id, token_hash, expires) VALUES ($1, $2, $3) ON CONFLICT (user_id)
+DO UPDATE SET token_hash=EXCLUDED.token_hash, expires=EXCLUDED.expires, created=now()
+WHERE magic_links.created<$4`
+
+	tag, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), magicLink.UserID, magicLink.TokenHash,
+		magicLink.Expires, createdBefore)
+	if err != nil {
+		return repositoryError("create magic link", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// Consume deletes not expired magic link from PostgreSQL database and returns it.
+func (repo *MagicLinksRepository) Consume(ctx context.Context, tokenHash []byte) (*domain.MagicLink, error) {
+	query := `DELETE FROM magic_links WHERE token_hash=$1 AND expires>now()
+RETURNING user_id, token_hash, expires, created`
+
+	var magicLink MagicLink
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), tokenHash).Scan(&magicLink.UserID,
+		&magicLink.TokenHash, &magicLink.Expires, &magicLink.Created); err != nil {
+		return nil, repositoryError("consume magic link", err)
+	}
+
+	return magicLink.DomainMagicLink(), nil
+}
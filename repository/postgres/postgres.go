@@ -45,10 +45,13 @@ func repositoryError(description string, err error) error {
 	pgErr := &pgconn.PgError{} //nolint:exhaustruct
 
 	if errors.As(err, &pgErr) {
-		switch pgErr.Code { //nolint:gocritic
+		switch pgErr.Code {
 		case pgerrcode.UniqueViolation:
 			// pgErr.ConstraintName may also be checked
 			return repository.ErrUniqueViolation
+		case pgerrcode.InvalidTextRepresentation:
+			// malformed identifiers provided by clients, like uuids, never match any resource
+			return repository.ErrResourceNotFound
 		}
 	}
 
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// RefreshToken entity.
+type RefreshToken struct {
+	FamilyID string
+	UserID   string
+	Used     pgtype.Timestamptz
+	Expires  pgtype.Timestamptz
+	Created  pgtype.Timestamptz
+	Revoked  pgtype.Timestamptz
+}
+
+// DomainRefreshToken converts refresh token entity to domain refresh token.
+func (t *RefreshToken) DomainRefreshToken() *domain.RefreshToken {
+	domainRefreshToken := &domain.RefreshToken{ //nolint:exhaustruct
+		FamilyID: t.FamilyID,
+		UserID:   t.UserID,
+	}
+
+	if t.Used.Status == pgtype.Present {
+		domainRefreshToken.Used = &t.Used.Time
+	}
+
+	if t.Expires.Status == pgtype.Present {
+		domainRefreshToken.Expires = &t.Expires.Time
+	}
+
+	if t.Created.Status == pgtype.Present {
+		domainRefreshToken.Created = &t.Created.Time
+	}
+
+	if t.Revoked.Status == pgtype.Present {
+		domainRefreshToken.Revoked = &t.Revoked.Time
+	}
+
+	return domainRefreshToken
+}
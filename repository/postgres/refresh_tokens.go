@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.RefreshTokens = (*RefreshTokensRepository)(nil)
+
+// RefreshTokensRepository implements repository.RefreshTokens interface using PostgreSQL database.
+type RefreshTokensRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRefreshTokensRepository creates new refresh tokens repository using PostgreSQL database.
+func NewRefreshTokensRepository(conn *pgxpool.Pool) *RefreshTokensRepository {
+	return &RefreshTokensRepository{pool: conn}
+}
+
+// Create starts new refresh token family in PostgreSQL database. Family issued to OAuth client is deleted together
+// with the client.
+func (repo *RefreshTokensRepository) Create(ctx context.Context, familyID, userID, clientID string, tokenHash []byte,
+	expires time.Time,
+) (*domain.RefreshToken, error) {
+	query := `WITH family AS (INSERT INTO refresh_token_families (id, user_id, client_id)
+VALUES ($1, $2, NULLIF($3, '')::uuid) RETURNING id)
+INSERT INTO refresh_tokens (token_hash, family_id, expires) SELECT $4, id, $5 FROM family
+RETURNING family_id, expires, created`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), familyID, userID, clientID, tokenHash,
+		expires)
+
+	refreshToken := RefreshToken{UserID: userID} //nolint:exhaustruct
+
+	if err := row.Scan(&refreshToken.FamilyID, &refreshToken.Expires, &refreshToken.Created); err != nil {
+		return nil, repositoryError("create refresh token family", err)
+	}
+
+	return refreshToken.DomainRefreshToken(), nil
+}
+
+// Rotate marks refresh token as used and stores its successor in PostgreSQL database. Reuse of already
+// rotated refresh token revokes the whole family.
+func (repo *RefreshTokensRepository) Rotate(ctx context.Context, tokenHash, newTokenHash []byte,
+	expires time.Time,
+) (*domain.RefreshToken, error) {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return nil, repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `SELECT t.family_id, f.user_id, t.used, f.revoked FROM refresh_tokens t
+JOIN refresh_token_families f ON f.id=t.family_id WHERE t.token_hash=$1 FOR UPDATE`
+
+	row := tx.QueryRow(ctx, repository.StripWhitespaces(query), tokenHash)
+
+	var current RefreshToken
+
+	if err := row.Scan(&current.FamilyID, &current.UserID, &current.Used, &current.Revoked); err != nil {
+		return nil, repositoryError("find refresh token", err)
+	}
+
+	if current.Revoked.Status == pgtype.Present {
+		return nil, repository.ErrTokenRevoked
+	}
+
+	if current.Used.Status == pgtype.Present {
+		if err := revokeFamily(ctx, tx, current.FamilyID); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return nil, repositoryError("commit", err)
+		}
+
+		return nil, repository.ErrTokenReused
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE refresh_tokens SET used=now() WHERE token_hash=$1", tokenHash); err != nil {
+		return nil, repositoryError("mark refresh token used", err)
+	}
+
+	query = `INSERT INTO refresh_tokens (token_hash, family_id, expires) VALUES ($1, $2, $3)
+RETURNING family_id, expires, created`
+
+	row = tx.QueryRow(ctx, repository.StripWhitespaces(query), newTokenHash, current.FamilyID, expires)
+
+	refreshToken := RefreshToken{UserID: current.UserID} //nolint:exhaustruct
+
+	if err := row.Scan(&refreshToken.FamilyID, &refreshToken.Expires, &refreshToken.Created); err != nil {
+		return nil, repositoryError("create refresh token", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repositoryError("commit", err)
+	}
+
+	return refreshToken.DomainRefreshToken(), nil
+}
+
+// FindOne fetches refresh token from PostgreSQL database using token hash.
+func (repo *RefreshTokensRepository) FindOne(ctx context.Context, tokenHash []byte) (*domain.RefreshToken, error) {
+	query := `SELECT t.family_id, f.user_id, t.used, t.expires, t.created, f.revoked FROM refresh_tokens t
+JOIN refresh_token_families f ON f.id=t.family_id WHERE t.token_hash=$1`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), tokenHash)
+
+	var refreshToken RefreshToken
+
+	if err := row.Scan(&refreshToken.FamilyID, &refreshToken.UserID, &refreshToken.Used, &refreshToken.Expires,
+		&refreshToken.Created, &refreshToken.Revoked); err != nil {
+		return nil, repositoryError("find refresh token", err)
+	}
+
+	return refreshToken.DomainRefreshToken(), nil
+}
+
+// Revoke revokes refresh token family in PostgreSQL database.
+func (repo *RefreshTokensRepository) Revoke(ctx context.Context, familyID string) error {
+	query := "UPDATE refresh_token_families SET revoked=now() WHERE id=$1 AND revoked IS NULL"
+
+	if _, err := repo.pool.Exec(ctx, query, familyID); err != nil {
+		return repositoryError("revoke refresh token family", err)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all refresh token families of the user in PostgreSQL database.
+func (repo *RefreshTokensRepository) RevokeAll(ctx context.Context, userID string) error {
+	query := "UPDATE refresh_token_families SET revoked=now() WHERE user_id=$1 AND revoked IS NULL"
+
+	if _, err := repo.pool.Exec(ctx, query, userID); err != nil {
+		return repositoryError("revoke refresh token families", err)
+	}
+
+	return nil
+}
+
+// RevokeAllExcept revokes all refresh token families of the user except the provided one in PostgreSQL database.
+func (repo *RefreshTokensRepository) RevokeAllExcept(ctx context.Context, userID, familyID string) error {
+	query := "UPDATE refresh_token_families SET revoked=now() WHERE user_id=$1 AND id<>$2 AND revoked IS NULL"
+
+	if _, err := repo.pool.Exec(ctx, query, userID, familyID); err != nil {
+		return repositoryError("revoke other refresh token families", err)
+	}
+
+	return nil
+}
+
+// RevokeAllOfClient revokes all refresh token families issued to OAuth client in PostgreSQL database.
+func (repo *RefreshTokensRepository) RevokeAllOfClient(ctx context.Context, clientID string) error {
+	query := "UPDATE refresh_token_families SET revoked=now() WHERE client_id=$1 AND revoked IS NULL"
+
+	if _, err := repo.pool.Exec(ctx, query, clientID); err != nil {
+		return repositoryError("revoke client refresh token families", err)
+	}
+
+	return nil
+}
+
+func revokeFamily(ctx context.Context, tx pgx.Tx, familyID string) error {
+	query := "UPDATE refresh_token_families SET revoked=now() WHERE id=$1 AND revoked IS NULL"
+
+	if _, err := tx.Exec(ctx, query, familyID); err != nil {
+		return repositoryError("revoke refresh token family", err)
+	}
+
+	return nil
+}
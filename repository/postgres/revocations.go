@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.Revocations = (*RevocationsRepository)(nil)
+
+// RevocationsRepository implements repository.Revocations interface using PostgreSQL database.
+type RevocationsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRevocationsRepository creates new revocations repository using PostgreSQL database.
+func NewRevocationsRepository(conn *pgxpool.Pool) *RevocationsRepository {
+	return &RevocationsRepository{pool: conn}
+}
+
+// Revoke revokes single token in PostgreSQL database.
+func (repo *RevocationsRepository) Revoke(ctx context.Context, jti, subject string, expires time.Time) error {
+	query := `INSERT INTO revoked_tokens (jti, subject, expires) VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), jti, subject, expires); err != nil {
+		return repositoryError("revoke token", err)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all tokens of the subject issued until now in PostgreSQL database. Since iat claim has second
+// precision, tokens issued within the current second are revoked as well.
+func (repo *RevocationsRepository) RevokeAll(ctx context.Context, subject string) error {
+	query := `INSERT INTO revoked_subjects (subject, issued_before) VALUES ($1, now())
+ON CONFLICT (subject) DO UPDATE SET issued_before=excluded.issued_before`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), subject); err != nil {
+		return repositoryError("revoke all tokens", err)
+	}
+
+	return nil
+}
+
+// IsRevoked checks if token is revoked in PostgreSQL database.
+func (repo *RevocationsRepository) IsRevoked(ctx context.Context, jti, subject string,
+	issuedAt time.Time,
+) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti=$1)
+OR EXISTS (SELECT 1 FROM revoked_subjects WHERE subject=$2 AND issued_before>=$3)`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), jti, subject, issuedAt)
+
+	var revoked bool
+
+	if err := row.Scan(&revoked); err != nil {
+		return false, repositoryError("is revoked", err)
+	}
+
+	return revoked, nil
+}
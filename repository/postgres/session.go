@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// Session entity.
+type Session struct {
+	ID        string
+	UserID    string
+	UserAgent string
+	IP        string
+	Created   pgtype.Timestamptz
+	LastSeen  pgtype.Timestamptz
+}
+
+// DomainSession converts session entity to domain session.
+func (s *Session) DomainSession() *domain.Session {
+	domainSession := &domain.Session{ //nolint:exhaustruct
+		ID:        s.ID,
+		UserID:    s.UserID,
+		UserAgent: s.UserAgent,
+		IP:        s.IP,
+	}
+
+	if s.Created.Status == pgtype.Present {
+		domainSession.Created = &s.Created.Time
+	}
+
+	if s.LastSeen.Status == pgtype.Present {
+		domainSession.LastSeen = &s.LastSeen.Time
+	}
+
+	return domainSession
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.Sessions = (*SessionsRepository)(nil)
+
+// SessionsRepository implements repository.Sessions interface using PostgreSQL database.
+type SessionsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewSessionsRepository creates new sessions repository using PostgreSQL database.
+func NewSessionsRepository(conn *pgxpool.Pool) *SessionsRepository {
+	return &SessionsRepository{pool: conn}
+}
+
+// Create records new session in PostgreSQL database.
+func (repo *SessionsRepository) Create(ctx context.Context, session *domain.Session) (*domain.Session, error) {
+	query := `INSERT INTO sessions (id, user_id, user_agent, ip) VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, user_agent, ip, created, last_seen`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), session.ID, session.UserID,
+		session.UserAgent, session.IP)
+
+	var entity Session
+
+	if err := row.Scan(&entity.ID, &entity.UserID, &entity.UserAgent, &entity.IP, &entity.Created,
+		&entity.LastSeen); err != nil {
+		return nil, repositoryError("create session", err)
+	}
+
+	return entity.DomainSession(), nil
+}
+
+// FindAll fetches all active sessions of the user from PostgreSQL database. Session is active while its refresh
+// token family is not revoked and contains unused and not expired refresh token.
+func (repo *SessionsRepository) FindAll(ctx context.Context, userID string) ([]domain.Session, error) {
+	query := `SELECT s.id, s.user_id, s.user_agent, s.ip, s.created, s.last_seen FROM sessions s
+JOIN refresh_token_families f ON f.id=s.id WHERE s.user_id=$1 AND f.revoked IS NULL
+AND EXISTS (SELECT 1 FROM refresh_tokens t WHERE t.family_id=s.id AND t.used IS NULL AND t.expires>now())
+ORDER BY s.last_seen DESC`
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), userID)
+	if err != nil {
+		return nil, repositoryError("fetch all sessions", err)
+	}
+
+	defer rows.Close()
+
+	domainSessions := []domain.Session{}
+
+	for rows.Next() {
+		var session Session
+
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IP, &session.Created,
+			&session.LastSeen); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		domainSessions = append(domainSessions, *session.DomainSession())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return domainSessions, nil
+}
+
+// Touch updates last seen time of the session in PostgreSQL database.
+func (repo *SessionsRepository) Touch(ctx context.Context, id string) error {
+	if _, err := repo.pool.Exec(ctx, "UPDATE sessions SET last_seen=now() WHERE id=$1", id); err != nil {
+		return repositoryError("touch session", err)
+	}
+
+	return nil
+}
+
+// Revoke revokes refresh token family of the user's session in PostgreSQL database.
+func (repo *SessionsRepository) Revoke(ctx context.Context, userID, id string) error {
+	query := `UPDATE refresh_token_families f SET revoked=coalesce(f.revoked, now()) FROM sessions s
+WHERE s.id=f.id AND s.id=$1 AND s.user_id=$2`
+
+	tag, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), id, userID)
+	if err != nil {
+		return repositoryError("revoke session", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// IsActive checks if refresh token family identified by session ID is not revoked in PostgreSQL database.
+// Unknown sessions are not active.
+func (repo *SessionsRepository) IsActive(ctx context.Context, id string) (bool, error) {
+	query := "SELECT EXISTS (SELECT 1 FROM refresh_token_families WHERE id=$1 AND revoked IS NULL)"
+
+	var active bool
+
+	if err := repo.pool.QueryRow(ctx, query, id).Scan(&active); err != nil {
+		if err = repositoryError("is session active", err); errors.Is(err, repository.ErrResourceNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return active, nil
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// TOTP entity.
+type TOTP struct {
+	UserID    string
+	Secret    string
+	LastStep  int64
+	Failures  int
+	Confirmed pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
+// DomainTOTP converts TOTP entity to domain TOTP.
+func (t *TOTP) DomainTOTP() *domain.TOTP {
+	domainTOTP := &domain.TOTP{ //nolint:exhaustruct
+		UserID:   t.UserID,
+		Secret:   t.Secret,
+		LastStep: t.LastStep,
+		Failures: t.Failures,
+	}
+
+	if t.Confirmed.Status == pgtype.Present {
+		domainTOTP.Confirmed = &t.Confirmed.Time
+	}
+
+	if t.Created.Status == pgtype.Present {
+		domainTOTP.Created = &t.Created.Time
+	}
+
+	return domainTOTP
+}
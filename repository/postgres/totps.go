@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.TOTPs = (*TOTPsRepository)(nil)
+
+// TOTPsRepository implements repository.TOTPs interface using PostgreSQL database.
+type TOTPsRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewTOTPsRepository creates new TOTPs repository using PostgreSQL database.
+func NewTOTPsRepository(conn *pgxpool.Pool) *TOTPsRepository {
+	return &TOTPsRepository{pool: conn}
+}
+
+// Enroll stores new TOTP secret of the user in PostgreSQL database unless enrollment is already confirmed.
+func (repo *TOTPsRepository) Enroll(ctx context.Context, userID, secret string) (*domain.TOTP, error) {
+	query := `INSERT INTO user_totp (user_id, secret) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE
+SET secret=EXCLUDED.secret, last_step=0, failures=0, created=now() WHERE user_totp.confirmed IS NULL
+RETURNING user_id, secret, last_step, failures, confirmed, created`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), userID, secret)
+
+	var totp TOTP
+
+	if err := row.Scan(&totp.UserID, &totp.Secret, &totp.LastStep, &totp.Failures, &totp.Confirmed,
+		&totp.Created); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repository.ErrUniqueViolation
+		}
+
+		return nil, repositoryError("enroll totp", err)
+	}
+
+	return totp.DomainTOTP(), nil
+}
+
+// FindOne fetches TOTP of the user from PostgreSQL database.
+func (repo *TOTPsRepository) FindOne(ctx context.Context, userID string) (*domain.TOTP, error) {
+	query := "SELECT user_id, secret, last_step, failures, confirmed, created FROM user_totp WHERE user_id=$1"
+
+	var totp TOTP
+
+	if err := repo.pool.QueryRow(ctx, query, userID).Scan(&totp.UserID, &totp.Secret, &totp.LastStep,
+		&totp.Failures, &totp.Confirmed, &totp.Created); err != nil {
+		return nil, repositoryError("find totp", err)
+	}
+
+	return totp.DomainTOTP(), nil
+}
+
+// Confirm confirms TOTP enrollment and replaces recovery codes of the user in PostgreSQL database.
+func (repo *TOTPsRepository) Confirm(ctx context.Context, userID string, step int64,
+	recoveryCodeHashes [][]byte,
+) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `UPDATE user_totp SET confirmed=now(), last_step=$2, failures=0
+WHERE user_id=$1 AND confirmed IS NULL AND last_step<$2`
+
+	tag, err := tx.Exec(ctx, repository.StripWhitespaces(query), userID, step)
+	if err != nil {
+		return repositoryError("confirm totp", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		return repositoryError("delete recovery codes", err)
+	}
+
+	for _, codeHash := range recoveryCodeHashes {
+		query := "INSERT INTO recovery_codes (code_hash, user_id) VALUES ($1, $2)"
+
+		if _, err := tx.Exec(ctx, query, codeHash, userID); err != nil {
+			return repositoryError("create recovery code", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return repositoryError("commit", err)
+	}
+
+	return nil
+}
+
+// Use stores time step of the valid code in PostgreSQL database.
+func (repo *TOTPsRepository) Use(ctx context.Context, userID string, step int64) error {
+	query := "UPDATE user_totp SET last_step=$2, failures=0 WHERE user_id=$1 AND last_step<$2"
+
+	tag, err := repo.pool.Exec(ctx, query, userID, step)
+	if err != nil {
+		return repositoryError("use totp", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrTokenReused
+	}
+
+	return nil
+}
+
+// Attempt increments consecutive failures in PostgreSQL database unless the limit has been reached.
+func (repo *TOTPsRepository) Attempt(ctx context.Context, userID string, maxFailures int) error {
+	query := "UPDATE user_totp SET failures=failures+1 WHERE user_id=$1 AND failures<$2 RETURNING failures"
+
+	var failures int
+
+	if err := repo.pool.QueryRow(ctx, query, userID, maxFailures).Scan(&failures); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return repository.ErrTooManyAttempts
+		}
+
+		return repositoryError("attempt totp", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks recovery code as used in PostgreSQL database.
+func (repo *TOTPsRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := "UPDATE recovery_codes SET used=now() WHERE code_hash=$1 AND user_id=$2 AND used IS NULL"
+
+	tag, err := tx.Exec(ctx, query, codeHash, userID)
+	if err != nil {
+		return repositoryError("use recovery code", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE user_totp SET failures=0 WHERE user_id=$1", userID); err != nil {
+		return repositoryError("reset totp failures", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return repositoryError("commit", err)
+	}
+
+	return nil
+}
+
+// Delete deletes TOTP and recovery codes of the user from PostgreSQL database.
+func (repo *TOTPsRepository) Delete(ctx context.Context, userID string) error {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return repositoryError("begin transaction", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, "DELETE FROM user_totp WHERE user_id=$1", userID)
+	if err != nil {
+		return repositoryError("delete totp", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM recovery_codes WHERE user_id=$1", userID); err != nil {
+		return repositoryError("delete recovery codes", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return repositoryError("commit", err)
+	}
+
+	return nil
+}
@@ -1,23 +1,32 @@
 package postgres
 
 import (
-	"fmt"
-
 	"github.com/jackc/pgtype"
 	"go.ectobit.com/arc/domain"
 )
 
 // User entity.
 type User struct {
-	ID              string
-	Email           string
-	Password        pgtype.Bytea
-	Activated       pgtype.Timestamptz
-	Created         pgtype.Timestamptz
-	Updated         pgtype.Timestamptz
-	ActivationToken pgtype.UUID
-	RecoveryToken   pgtype.UUID
-	Active          bool
+	ID                    string
+	Email                 string
+	Password              pgtype.Bytea
+	Role                  string
+	Activated             pgtype.Timestamptz
+	Created               pgtype.Timestamptz
+	Updated               pgtype.Timestamptz
+	ActivationTokenIssued pgtype.Timestamptz
+	RecoveryTokenIssued   pgtype.Timestamptz
+	Active                bool
+	Status                string
+	StatusUntil           pgtype.Timestamptz
+	StatusReason          string
+	StatusActor           string
+	StatusChanged         pgtype.Timestamptz
+	DeletionScheduled     pgtype.Timestamptz
+	DisplayName           string
+	Locale                string
+	Timezone              string
+	Metadata              []byte
 }
 
 // DomainUser converts user entity to domain user.
@@ -25,7 +34,19 @@ func (u *User) DomainUser() (*domain.User, error) {
 	domainUser := &domain.User{ //nolint:exhaustruct
 		ID:     u.ID,
 		Email:  u.Email,
+		Role:   u.Role,
 		Active: &u.Active,
+		Status: domain.UserStatus{ //nolint:exhaustruct
+			Status: u.Status,
+			Reason: u.StatusReason,
+			Actor:  u.StatusActor,
+		},
+		Profile: domain.Profile{
+			DisplayName: u.DisplayName,
+			Locale:      u.Locale,
+			Timezone:    u.Timezone,
+			Metadata:    u.Metadata,
+		},
 	}
 
 	if u.Password.Status == pgtype.Present {
@@ -44,16 +65,24 @@ func (u *User) DomainUser() (*domain.User, error) {
 		domainUser.Updated = &u.Updated.Time
 	}
 
-	if u.ActivationToken.Status == pgtype.Present {
-		if err := u.ActivationToken.AssignTo(&domainUser.ActivationToken); err != nil {
-			return nil, fmt.Errorf("assign activation token: %w", err)
-		}
+	if u.ActivationTokenIssued.Status == pgtype.Present {
+		domainUser.ActivationTokenIssued = &u.ActivationTokenIssued.Time
+	}
+
+	if u.RecoveryTokenIssued.Status == pgtype.Present {
+		domainUser.RecoveryTokenIssued = &u.RecoveryTokenIssued.Time
+	}
+
+	if u.StatusUntil.Status == pgtype.Present {
+		domainUser.Status.Until = &u.StatusUntil.Time
+	}
+
+	if u.StatusChanged.Status == pgtype.Present {
+		domainUser.Status.Changed = &u.StatusChanged.Time
 	}
 
-	if u.RecoveryToken.Status == pgtype.Present {
-		if err := u.RecoveryToken.AssignTo(&domainUser.RecoveryToken); err != nil {
-			return nil, fmt.Errorf("assign password reset token: %w", err)
-		}
+	if u.DeletionScheduled.Status == pgtype.Present {
+		domainUser.DeletionScheduled = &u.DeletionScheduled.Time
 	}
 
 	return domainUser, nil
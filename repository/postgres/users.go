@@ -2,8 +2,14 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.ectobit.com/arc/domain"
 	"go.ectobit.com/arc/repository"
@@ -22,16 +28,19 @@ func NewUserRepository(conn *pgxpool.Pool) *UsersRepository {
 }
 
 // Create creates new user in PostgreSQL database.
-func (repo *UsersRepository) Create(ctx context.Context, email string, password []byte) (*domain.User, error) {
-	query := `INSERT INTO users (email, password) VALUES ($1, $2)
-		RETURNING id, email, password, created, activation_token, active`
+func (repo *UsersRepository) Create(ctx context.Context, email string,
+	password, activationTokenHash []byte,
+) (*domain.User, error) {
+	query := `INSERT INTO users (email, password, activation_token_hash, activation_token_issued)
+VALUES ($1, $2, $3, now()) RETURNING id, email, password, created, activation_token_issued, active, display_name,
+locale, timezone, metadata`
 
-	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email, password)
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email, password, activationTokenHash)
 
 	var user User
 
-	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.ActivationToken,
-		&user.Active); err != nil {
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.ActivationTokenIssued,
+		&user.Active, &user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
 		return nil, repositoryError("create user", err)
 	}
 
@@ -43,17 +52,42 @@ func (repo *UsersRepository) Create(ctx context.Context, email string, password
 	return domainUser, nil
 }
 
+// CreateActivated creates new already activated user in PostgreSQL database.
+func (repo *UsersRepository) CreateActivated(ctx context.Context, email string, password []byte) (*domain.User, error) {
+	query := `INSERT INTO users (email, password, active, activated) VALUES ($1, $2, TRUE, now())
+RETURNING id, email, password, created, activated, active, display_name, locale, timezone, metadata`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email, password)
+
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.Activated, &user.Active,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("create activated user", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
 // FindOne fetches user from PostgreSQL database using ID.
 func (repo *UsersRepository) FindOne(ctx context.Context, id string) (*domain.User, error) {
-	query := `SELECT id, email, password, created, updated, activation_token, recovery_token, active
-FROM users WHERE id=$1`
+	query := `SELECT id, email, password, role, created, updated, activation_token_issued, recovery_token_issued,
+active, status, status_until, status_reason, status_actor, status_changed, deletion_scheduled, display_name, locale,
+timezone, metadata FROM users WHERE id=$1`
 
 	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), id)
 
 	var user User
 
-	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.Updated,
-		&user.ActivationToken, &user.RecoveryToken, &user.Active); err != nil {
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.Created, &user.Updated,
+		&user.ActivationTokenIssued, &user.RecoveryTokenIssued, &user.Active, &user.Status,
+		&user.StatusUntil, &user.StatusReason, &user.StatusActor, &user.StatusChanged, &user.DeletionScheduled,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
 		return nil, repositoryError("find one", err)
 	}
 
@@ -67,15 +101,18 @@ FROM users WHERE id=$1`
 
 // FindOneByEmail fetches user from PostgreSQL database using email address.
 func (repo *UsersRepository) FindOneByEmail(ctx context.Context, email string) (*domain.User, error) {
-	query := `SELECT id, email, password, created, updated, activation_token, recovery_token, active
-FROM users WHERE email=$1`
+	query := `SELECT id, email, password, role, created, updated, activation_token_issued, recovery_token_issued,
+active, status, status_until, status_reason, status_actor, status_changed, deletion_scheduled, display_name, locale,
+timezone, metadata FROM users WHERE email=$1`
 
 	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email)
 
 	var user User
 
-	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.Updated,
-		&user.ActivationToken, &user.RecoveryToken, &user.Active); err != nil {
+	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Role, &user.Created, &user.Updated,
+		&user.ActivationTokenIssued, &user.RecoveryTokenIssued, &user.Active, &user.Status,
+		&user.StatusUntil, &user.StatusReason, &user.StatusActor, &user.StatusChanged, &user.DeletionScheduled,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
 		return nil, repositoryError("fetch user by email", err)
 	}
 
@@ -87,25 +124,50 @@ FROM users WHERE email=$1`
 	return domainUser, nil
 }
 
-// FindAll fetches alls users from PostgreSQL.
-func (repo *UsersRepository) FindAll(ctx context.Context) ([]domain.User, error) {
-	query := `SELECT id, email, password, activation_token, recovery_token, activated, active, created, updated
-FROM users`
+// FindAll fetches page of users matching the query from PostgreSQL database, without their passwords. Pages are
+// fetched by keyset pagination on sort key and ID, so the cost of the page does not grow with its position.
+func (repo *UsersRepository) FindAll(ctx context.Context,
+	query *repository.UsersQuery,
+) (*repository.UsersPage, error) {
+	conditions, args := usersConditions(query)
+
+	page := &repository.UsersPage{} //nolint:exhaustruct
+
+	if err := repo.pool.QueryRow(ctx, "SELECT count(*) FROM users"+where(conditions), args...).
+		Scan(&page.Total); err != nil {
+		return nil, repositoryError("count users", err)
+	}
+
+	if query.After != nil {
+		if _, err := uuid.Parse(query.After.ID); err != nil {
+			return nil, repository.ErrInvalidCursor
+		}
+	}
+
+	conditions, args, orderBy := usersKeyset(query, conditions, args)
+	args = append(args, query.Limit+1)
 
-	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query))
+	sql := fmt.Sprintf(`SELECT id, email, role, activation_token_issued, recovery_token_issued, activated, active,
+created, updated, status, status_until, status_reason, status_actor, status_changed, deletion_scheduled, display_name,
+locale, timezone, metadata FROM users%s
+ORDER BY %s LIMIT $%d`, where(conditions), orderBy, len(args))
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(sql), args...)
 	if err != nil {
-		return nil, repositoryError("fetch all users", err)
+		return nil, repositoryError("fetch users", err)
 	}
 
 	defer rows.Close()
 
-	domainUsers := []domain.User{}
+	page.Users = []domain.User{}
 
 	for rows.Next() {
 		var user User
 
-		if err := rows.Scan(&user.ID, &user.Email, &user.Password, &user.ActivationToken, &user.RecoveryToken,
-			&user.Activated, &user.Active, &user.Created, &user.Updated); err != nil {
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.ActivationTokenIssued, &user.RecoveryTokenIssued,
+			&user.Activated, &user.Active, &user.Created, &user.Updated, &user.Status, &user.StatusUntil,
+			&user.StatusReason, &user.StatusActor, &user.StatusChanged, &user.DeletionScheduled, &user.DisplayName,
+			&user.Locale, &user.Timezone, &user.Metadata); err != nil {
 			return nil, repositoryError("scan", err)
 		}
 
@@ -114,26 +176,36 @@ FROM users`
 			return nil, fmt.Errorf("convert to domain user: %w", err)
 		}
 
-		domainUsers = append(domainUsers, *domainUser)
+		page.Users = append(page.Users, *domainUser)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, repositoryError("rows err", err)
 	}
 
-	return domainUsers, nil
+	trimUsersPage(page, query)
+
+	return page, nil
 }
 
-// Activate activates user account in PostgreSQL database.
-func (repo *UsersRepository) Activate(ctx context.Context, token string) (*domain.User, error) {
-	query := `UPDATE users SET updated=now(), activation_token=NULL, activated=current_timestamp, active=TRUE
-		WHERE activation_token=$1 RETURNING id, email, password, created, updated`
+// Activate activates user account in PostgreSQL database if activation token has been issued after provided time.
+func (repo *UsersRepository) Activate(ctx context.Context, activationTokenHash []byte,
+	issuedAfter time.Time,
+) (*domain.User, error) {
+	query := `UPDATE users SET updated=now(), activation_token_hash=NULL, activation_token_issued=NULL,
+activated=current_timestamp, active=TRUE WHERE activation_token_hash=$1 AND activation_token_issued>$2
+RETURNING id, email, password, created, updated`
 
-	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), token)
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), activationTokenHash, issuedAfter)
 
 	var user User
 
 	if err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Created, &user.Updated); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.expiredOrUnknown(ctx,
+				"SELECT EXISTS (SELECT 1 FROM users WHERE activation_token_hash=$1)", activationTokenHash)
+		}
+
 		return nil, repositoryError("activate user account", err)
 	}
 
@@ -145,17 +217,232 @@ func (repo *UsersRepository) Activate(ctx context.Context, token string) (*domai
 	return domainUser, nil
 }
 
-// FetchRecoveryToken sets user's password reset token in PostgreSQL repository.
-func (repo *UsersRepository) FetchRecoveryToken(ctx context.Context, email string) (*domain.User, error) {
-	query := `UPDATE users SET recovery_token=gen_random_uuid()
-WHERE email=$1 AND active RETURNING id, email, recovery_token`
+// UpdateProfile updates profile of the user in PostgreSQL database.
+func (repo *UsersRepository) UpdateProfile(ctx context.Context, id string,
+	profile *domain.Profile,
+) (*domain.User, error) {
+	query := `UPDATE users SET display_name=$2, locale=$3, timezone=$4, metadata=$5, updated=now() WHERE id=$1
+RETURNING id, email, created, updated, active, display_name, locale, timezone, metadata`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), id, profile.DisplayName, profile.Locale,
+		profile.Timezone, profile.Metadata)
 
-	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email)
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Email, &user.Created, &user.Updated, &user.Active, &user.DisplayName,
+		&user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("update profile", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// ChangePassword sets new password of the active user in PostgreSQL database.
+func (repo *UsersRepository) ChangePassword(ctx context.Context, id string, password []byte) error {
+	query := "UPDATE users SET password=$2, updated=now() WHERE id=$1 AND active"
+
+	tag, err := repo.pool.Exec(ctx, query, id, password)
+	if err != nil {
+		return repositoryError("change password", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// ScheduleDeletion sets deletion time of the user in PostgreSQL database.
+func (repo *UsersRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) (*domain.User, error) {
+	query := `UPDATE users SET deletion_scheduled=$2, updated=now() WHERE id=$1
+RETURNING id, email, created, updated, active, deletion_scheduled, display_name, locale, timezone, metadata`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), id, at)
+
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Email, &user.Created, &user.Updated, &user.Active, &user.DeletionScheduled,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("schedule deletion", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// CancelDeletion clears deletion time of the user in PostgreSQL database.
+func (repo *UsersRepository) CancelDeletion(ctx context.Context, id string) error {
+	query := "UPDATE users SET deletion_scheduled=NULL, updated=now() WHERE id=$1 AND deletion_scheduled IS NOT NULL"
+
+	tag, err := repo.pool.Exec(ctx, query, id)
+	if err != nil {
+		return repositoryError("cancel deletion", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// DeleteScheduled deletes users whose deletion time has passed from PostgreSQL database. Data of the users in other
+// tables is deleted by cascade.
+func (repo *UsersRepository) DeleteScheduled(ctx context.Context) (int64, error) {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM users WHERE deletion_scheduled<=now()")
+	if err != nil {
+		return 0, repositoryError("delete scheduled", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// SetStatus sets status of the user in PostgreSQL database and records the change in status history within the
+// same transaction, so both share the same change time.
+func (repo *UsersRepository) SetStatus(ctx context.Context, id string,
+	status *domain.UserStatus,
+) (*domain.User, error) {
+	tx, err := repo.pool.Begin(ctx)
+	if err != nil {
+		return nil, repositoryError("begin", err)
+	}
+
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	query := `UPDATE users SET status=$2, status_until=$3, status_reason=$4, status_actor=$5, status_changed=now(),
+updated=now() WHERE id=$1 RETURNING id, email, role, created, updated, activated, active, status, status_until,
+status_reason, status_actor, status_changed, deletion_scheduled, display_name, locale, timezone, metadata`
+
+	row := tx.QueryRow(ctx, repository.StripWhitespaces(query), id, status.Status, status.Until, status.Reason,
+		status.Actor)
+
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Email, &user.Role, &user.Created, &user.Updated, &user.Activated,
+		&user.Active, &user.Status, &user.StatusUntil, &user.StatusReason, &user.StatusActor, &user.StatusChanged,
+		&user.DeletionScheduled, &user.DisplayName, &user.Locale, &user.Timezone, &user.Metadata); err != nil {
+		return nil, repositoryError("set status", err)
+	}
+
+	query = `INSERT INTO user_status_changes (user_id, status, until, reason, actor) VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.Exec(ctx, query, id, status.Status, status.Until, status.Reason, status.Actor); err != nil {
+		return nil, repositoryError("record status change", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, repositoryError("commit", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// FindStatusHistory fetches status changes of the user from PostgreSQL database, the newest first.
+func (repo *UsersRepository) FindStatusHistory(ctx context.Context, id string) ([]domain.UserStatus, error) {
+	query := `SELECT status, until, reason, actor, created FROM user_status_changes WHERE user_id=$1
+ORDER BY created DESC`
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), id)
+	if err != nil {
+		return nil, repositoryError("fetch status history", err)
+	}
+
+	defer rows.Close()
+
+	statuses := []domain.UserStatus{}
+
+	for rows.Next() {
+		var (
+			status  domain.UserStatus
+			until   pgtype.Timestamptz
+			changed time.Time
+		)
+
+		if err := rows.Scan(&status.Status, &until, &status.Reason, &status.Actor, &changed); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		if until.Status == pgtype.Present {
+			status.Until = &until.Time
+		}
+
+		status.Changed = &changed
+		statuses = append(statuses, status)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return statuses, nil
+}
+
+// Delete deletes the user from PostgreSQL database. Data of the user in other tables is deleted by cascade.
+func (repo *UsersRepository) Delete(ctx context.Context, id string) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM users WHERE id=$1", id)
+	if err != nil {
+		return repositoryError("delete user", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// SetActivationToken replaces hash of the activation token of not yet active user in PostgreSQL database.
+func (repo *UsersRepository) SetActivationToken(ctx context.Context, email string, activationTokenHash []byte,
+	issuedBefore time.Time,
+) (*domain.User, error) {
+	query := `UPDATE users SET activation_token_hash=$2, activation_token_issued=now()
+WHERE email=$1 AND NOT active AND (activation_token_issued IS NULL OR activation_token_issued<$3)
+RETURNING id, email, activation_token_issued`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email, activationTokenHash, issuedBefore)
+
+	var user User
+
+	if err := row.Scan(&user.ID, &user.Email, &user.ActivationTokenIssued); err != nil {
+		return nil, repositoryError("set activation token", err)
+	}
+
+	domainUser, err := user.DomainUser()
+	if err != nil {
+		return nil, fmt.Errorf("convert to domain user: %w", err)
+	}
+
+	return domainUser, nil
+}
+
+// SetRecoveryToken stores hash of the active user's password reset token in PostgreSQL repository.
+func (repo *UsersRepository) SetRecoveryToken(ctx context.Context, email string,
+	recoveryTokenHash []byte,
+) (*domain.User, error) {
+	query := `UPDATE users SET recovery_token_hash=$2, recovery_token_issued=now()
+WHERE email=$1 AND active RETURNING id, email, recovery_token_issued`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), email, recoveryTokenHash)
 
 	var user User
 
-	if err := row.Scan(&user.ID, &user.Email, &user.RecoveryToken); err != nil {
-		return nil, repositoryError("fetch pasword reset token", err)
+	if err := row.Scan(&user.ID, &user.Email, &user.RecoveryTokenIssued); err != nil {
+		return nil, repositoryError("set password reset token", err)
 	}
 
 	domainUser, err := user.DomainUser()
@@ -166,18 +453,24 @@ WHERE email=$1 AND active RETURNING id, email, recovery_token`
 	return domainUser, nil
 }
 
-// ResetPassword sets new user's password in PostgreSQL database.
-func (repo *UsersRepository) ResetPassword(ctx context.Context, recoveryToken string,
+// ResetPassword sets new user's password in PostgreSQL database if recovery token has been issued after provided
+// time.
+func (repo *UsersRepository) ResetPassword(ctx context.Context, recoveryTokenHash []byte, issuedAfter time.Time,
 	password []byte,
 ) (*domain.User, error) {
-	query := `UPDATE users SET password=$1, recovery_token=NULL
-WHERE recovery_token=$2 AND active RETURNING id, email`
+	query := `UPDATE users SET password=$1, recovery_token_hash=NULL, recovery_token_issued=NULL
+WHERE recovery_token_hash=$2 AND recovery_token_issued>$3 AND active RETURNING id, email`
 
-	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), password, recoveryToken)
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), password, recoveryTokenHash, issuedAfter)
 
 	var user User
 
 	if err := row.Scan(&user.ID, &user.Email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, repo.expiredOrUnknown(ctx,
+				"SELECT EXISTS (SELECT 1 FROM users WHERE recovery_token_hash=$1 AND active)", recoveryTokenHash)
+		}
+
 		return nil, repositoryError("reset password", err)
 	}
 
@@ -188,3 +481,97 @@ WHERE recovery_token=$2 AND active RETURNING id, email`
 
 	return domainUser, nil
 }
+
+// expiredOrUnknown distinguishes expired token from unknown one, once conditional update hasn't matched any row.
+// Query has to check existence of the token hash regardless of its issue time.
+func (repo *UsersRepository) expiredOrUnknown(ctx context.Context, query string, tokenHash []byte) error {
+	var exists bool
+
+	if err := repo.pool.QueryRow(ctx, query, tokenHash).Scan(&exists); err != nil {
+		return repositoryError("check token", err)
+	}
+
+	if exists {
+		return repository.ErrTokenExpired
+	}
+
+	return repository.ErrResourceNotFound
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// usersConditions translates filters of users query to SQL conditions and their arguments.
+func usersConditions(query *repository.UsersQuery) ([]string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if query.EmailPrefix != "" {
+		args = append(args, likeEscaper.Replace(query.EmailPrefix)+"%")
+		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", len(args)))
+	}
+
+	if query.Active != nil {
+		args = append(args, *query.Active)
+		conditions = append(conditions, fmt.Sprintf("active = $%d", len(args)))
+	}
+
+	if query.CreatedFrom != nil {
+		args = append(args, *query.CreatedFrom)
+		conditions = append(conditions, fmt.Sprintf("created >= $%d", len(args)))
+	}
+
+	if query.CreatedTo != nil {
+		args = append(args, *query.CreatedTo)
+		conditions = append(conditions, fmt.Sprintf("created < $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// usersKeyset appends condition selecting users after the cursor of the query to conditions and returns ORDER BY
+// clause of its sort order. Users with the same sort key are ordered by id, so the cursor points to exactly one user.
+func usersKeyset(query *repository.UsersQuery, conditions []string,
+	args []interface{},
+) ([]string, []interface{}, string) {
+	column, columnType, operator, direction := "created", "timestamptz", ">", "ASC"
+	if query.Sort == repository.UsersByEmail {
+		column, columnType = "email", "text"
+	}
+
+	if query.Descending {
+		operator, direction = "<", "DESC"
+	}
+
+	if query.After != nil {
+		args = append(args, query.After.Value, query.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d::uuid)", column, operator,
+			len(args)-1, columnType, len(args)))
+	}
+
+	return conditions, args, fmt.Sprintf("%s %s, id %s", column, direction, direction)
+}
+
+// trimUsersPage trims users fetched one over the limit of the query to the page and sets cursor of the next page
+// pointing to the last user of the page. Cursor is not set if there are no more users.
+func trimUsersPage(page *repository.UsersPage, query *repository.UsersQuery) {
+	if len(page.Users) <= query.Limit {
+		return
+	}
+
+	page.Users = page.Users[:query.Limit]
+	last := page.Users[query.Limit-1]
+
+	page.Next = &repository.Cursor{Sort: query.SortOrder(), Value: last.Email, ID: last.ID}
+	if query.Sort != repository.UsersByEmail {
+		page.Next.Value = last.Created.Format(time.RFC3339Nano)
+	}
+}
+
+// where joins conditions to SQL WHERE clause, which is empty if there are no conditions.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND ")
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+func TestUsersKeyset(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		query          *repository.UsersQuery
+		wantConditions []string
+		wantArgs       []interface{}
+		wantOrderBy    string
+	}{
+		"first page": {
+			&repository.UsersQuery{Sort: repository.UsersByCreated}, //nolint:exhaustruct
+			[]string{"active = $1"}, []interface{}{true}, "created ASC, id ASC",
+		},
+		"created after cursor": {
+			&repository.UsersQuery{ //nolint:exhaustruct
+				Sort:  repository.UsersByCreated,
+				After: &repository.Cursor{Sort: "created", Value: "2021-10-01T12:00:00Z", ID: "id"},
+			},
+			[]string{"active = $1", "(created, id) > ($2::timestamptz, $3::uuid)"},
+			[]interface{}{true, "2021-10-01T12:00:00Z", "id"}, "created ASC, id ASC",
+		},
+		"email descending after cursor": {
+			&repository.UsersQuery{ //nolint:exhaustruct
+				Sort: repository.UsersByEmail, Descending: true,
+				After: &repository.Cursor{Sort: "-email", Value: "john.doe@sixpack.com", ID: "id"},
+			},
+			[]string{"active = $1", "(email, id) < ($2::text, $3::uuid)"},
+			[]interface{}{true, "john.doe@sixpack.com", "id"}, "email DESC, id DESC",
+		},
+	}
+
+	for n, test := range tests {
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			conditions, args, orderBy := usersKeyset(test.query, []string{"active = $1"}, []interface{}{true})
+
+			if diff := cmp.Diff(test.wantConditions, conditions); diff != "" {
+				t.Errorf("usersKeyset() conditions mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.wantArgs, args); diff != "" {
+				t.Errorf("usersKeyset() args mismatch (-want +got):\n%s", diff)
+			}
+
+			if orderBy != test.wantOrderBy {
+				t.Errorf("usersKeyset() order by = %q; want %q", orderBy, test.wantOrderBy)
+			}
+		})
+	}
+}
+
+func TestTrimUsersPage(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2021, time.October, 1, 12, 0, 0, 0, time.UTC)
+	users := []domain.User{
+		{ID: "c", Email: "c@sixpack.com", Created: &created}, //nolint:exhaustruct
+		{ID: "b", Email: "b@sixpack.com", Created: &created}, //nolint:exhaustruct
+		{ID: "a", Email: "a@sixpack.com", Created: &created}, //nolint:exhaustruct
+	}
+
+	tests := map[string]struct {
+		query     *repository.UsersQuery
+		wantUsers int
+		wantNext  *repository.Cursor
+	}{
+		"one over limit": {
+			&repository.UsersQuery{Sort: repository.UsersByCreated, Descending: true, Limit: 2}, //nolint:exhaustruct
+			2, &repository.Cursor{Sort: "-created", Value: "2021-10-01T12:00:00Z", ID: "b"},
+		},
+		"email one over limit": {
+			&repository.UsersQuery{Sort: repository.UsersByEmail, Descending: true, Limit: 2}, //nolint:exhaustruct
+			2, &repository.Cursor{Sort: "-email", Value: "b@sixpack.com", ID: "b"},
+		},
+		"exactly limit": {
+			&repository.UsersQuery{Sort: repository.UsersByCreated, Limit: 3}, //nolint:exhaustruct
+			3, nil,
+		},
+	}
+
+	for n, test := range tests {
+		test := test
+
+		t.Run(n, func(t *testing.T) {
+			t.Parallel()
+
+			page := &repository.UsersPage{Users: append([]domain.User{}, users...)} //nolint:exhaustruct
+
+			trimUsersPage(page, test.query)
+
+			if len(page.Users) != test.wantUsers {
+				t.Errorf("trimUsersPage() = %d users; want %d", len(page.Users), test.wantUsers)
+			}
+
+			if diff := cmp.Diff(test.wantNext, page.Next); diff != "" {
+				t.Errorf("trimUsersPage() next mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.ectobit.com/arc/domain"
+	"go.ectobit.com/arc/repository"
+)
+
+var _ repository.WebAuthn = (*WebAuthnRepository)(nil)
+
+// WebAuthnRepository implements repository.WebAuthn interface using PostgreSQL database.
+type WebAuthnRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewWebAuthnRepository creates new WebAuthn repository using PostgreSQL database.
+func NewWebAuthnRepository(conn *pgxpool.Pool) *WebAuthnRepository {
+	return &WebAuthnRepository{pool: conn}
+}
+
+// CreateCredential stores new credential in PostgreSQL database.
+func (repo *WebAuthnRepository) CreateCredential(ctx context.Context,
+	credential *domain.WebAuthnCredential,
+) (*domain.WebAuthnCredential, error) {
+	query := `INSERT INTO webauthn_credentials (id, user_id, name, public_key, sign_count)
+VALUES ($1, $2, $3, $4, $5) RETURNING id, user_id, name, public_key, sign_count, last_used, created`
+
+	row := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), credential.ID, credential.UserID,
+		credential.Name, credential.PublicKey, int64(credential.SignCount))
+
+	var entity WebAuthnCredential
+
+	if err := row.Scan(&entity.ID, &entity.UserID, &entity.Name, &entity.PublicKey, &entity.SignCount,
+		&entity.LastUsed, &entity.Created); err != nil {
+		return nil, repositoryError("create webauthn credential", err)
+	}
+
+	return entity.DomainWebAuthnCredential(), nil
+}
+
+// FindCredential fetches credential by its ID from PostgreSQL database.
+func (repo *WebAuthnRepository) FindCredential(ctx context.Context, id []byte) (*domain.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, name, public_key, sign_count, last_used, created FROM webauthn_credentials
+WHERE id=$1`
+
+	var entity WebAuthnCredential
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), id).Scan(&entity.ID, &entity.UserID,
+		&entity.Name, &entity.PublicKey, &entity.SignCount, &entity.LastUsed, &entity.Created); err != nil {
+		return nil, repositoryError("find webauthn credential", err)
+	}
+
+	return entity.DomainWebAuthnCredential(), nil
+}
+
+// FindCredentials fetches all credentials of the user from PostgreSQL database.
+func (repo *WebAuthnRepository) FindCredentials(ctx context.Context,
+	userID string,
+) ([]domain.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, name, public_key, sign_count, last_used, created FROM webauthn_credentials
+WHERE user_id=$1 ORDER BY created`
+
+	rows, err := repo.pool.Query(ctx, repository.StripWhitespaces(query), userID)
+	if err != nil {
+		return nil, repositoryError("fetch webauthn credentials", err)
+	}
+
+	defer rows.Close()
+
+	domainCredentials := []domain.WebAuthnCredential{}
+
+	for rows.Next() {
+		var entity WebAuthnCredential
+
+		if err := rows.Scan(&entity.ID, &entity.UserID, &entity.Name, &entity.PublicKey, &entity.SignCount,
+			&entity.LastUsed, &entity.Created); err != nil {
+			return nil, repositoryError("scan", err)
+		}
+
+		domainCredentials = append(domainCredentials, *entity.DomainWebAuthnCredential())
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, repositoryError("rows err", err)
+	}
+
+	return domainCredentials, nil
+}
+
+// UseCredential stores signature counter and last usage time of the credential in PostgreSQL database.
+func (repo *WebAuthnRepository) UseCredential(ctx context.Context, id []byte, signCount uint32) error {
+	query := `UPDATE webauthn_credentials SET sign_count=$2, last_used=now()
+WHERE id=$1 AND (sign_count<$2 OR sign_count=0 AND $2=0)`
+
+	tag, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), id, int64(signCount))
+	if err != nil {
+		return repositoryError("use webauthn credential", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrTokenReused
+	}
+
+	return nil
+}
+
+// DeleteCredential deletes credential of the user from PostgreSQL database.
+func (repo *WebAuthnRepository) DeleteCredential(ctx context.Context, userID string, id []byte) error {
+	tag, err := repo.pool.Exec(ctx, "DELETE FROM webauthn_credentials WHERE id=$1 AND user_id=$2", id, userID)
+	if err != nil {
+		return repositoryError("delete webauthn credential", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return repository.ErrResourceNotFound
+	}
+
+	return nil
+}
+
+// CreateChallenge stores challenge hash in PostgreSQL database.
+func (repo *WebAuthnRepository) CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error {
+	query := `INSERT INTO webauthn_challenges (challenge_hash, user_id, ceremony, expires)
+VALUES ($1, NULLIF($2, '')::uuid, $3, $4)`
+
+	if _, err := repo.pool.Exec(ctx, repository.StripWhitespaces(query), challenge.Hash, challenge.UserID,
+		challenge.Ceremony, challenge.Expires); err != nil {
+		return repositoryError("create webauthn challenge", err)
+	}
+
+	return nil
+}
+
+// ConsumeChallenge deletes not expired challenge from PostgreSQL database and returns it. Expired challenges are
+// deleted as well.
+func (repo *WebAuthnRepository) ConsumeChallenge(ctx context.Context, hash []byte,
+	ceremony string,
+) (*domain.WebAuthnChallenge, error) {
+	if _, err := repo.pool.Exec(ctx, "DELETE FROM webauthn_challenges WHERE expires<=now()"); err != nil {
+		return nil, repositoryError("delete expired webauthn challenges", err)
+	}
+
+	query := `DELETE FROM webauthn_challenges WHERE challenge_hash=$1 AND ceremony=$2 AND expires>now()
+RETURNING challenge_hash, user_id, ceremony, expires, created`
+
+	var entity WebAuthnChallenge
+
+	if err := repo.pool.QueryRow(ctx, repository.StripWhitespaces(query), hash, ceremony).Scan(&entity.Hash,
+		&entity.UserID, &entity.Ceremony, &entity.Expires, &entity.Created); err != nil {
+		return nil, repositoryError("consume webauthn challenge", err)
+	}
+
+	return entity.DomainWebAuthnChallenge()
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"go.ectobit.com/arc/domain"
+)
+
+// WebAuthnCredential entity.
+type WebAuthnCredential struct {
+	ID        []byte
+	UserID    string
+	Name      string
+	PublicKey []byte
+	SignCount int64
+	LastUsed  pgtype.Timestamptz
+	Created   pgtype.Timestamptz
+}
+
+// DomainWebAuthnCredential converts WebAuthn credential entity to domain WebAuthn credential.
+func (c *WebAuthnCredential) DomainWebAuthnCredential() *domain.WebAuthnCredential {
+	domainCredential := &domain.WebAuthnCredential{ //nolint:exhaustruct
+		ID:        c.ID,
+		UserID:    c.UserID,
+		Name:      c.Name,
+		PublicKey: c.PublicKey,
+		SignCount: uint32(c.SignCount),
+	}
+
+	if c.LastUsed.Status == pgtype.Present {
+		domainCredential.LastUsed = &c.LastUsed.Time
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainCredential.Created = &c.Created.Time
+	}
+
+	return domainCredential
+}
+
+// WebAuthnChallenge entity.
+type WebAuthnChallenge struct {
+	Hash     []byte
+	UserID   pgtype.UUID
+	Ceremony string
+	Expires  pgtype.Timestamptz
+	Created  pgtype.Timestamptz
+}
+
+// DomainWebAuthnChallenge converts WebAuthn challenge entity to domain WebAuthn challenge.
+func (c *WebAuthnChallenge) DomainWebAuthnChallenge() (*domain.WebAuthnChallenge, error) {
+	domainChallenge := &domain.WebAuthnChallenge{ //nolint:exhaustruct
+		Hash:     c.Hash,
+		Ceremony: c.Ceremony,
+		Expires:  c.Expires.Time,
+	}
+
+	if c.UserID.Status == pgtype.Present {
+		if err := c.UserID.AssignTo(&domainChallenge.UserID); err != nil {
+			return nil, fmt.Errorf("assign user id: %w", err)
+		}
+	}
+
+	if c.Created.Status == pgtype.Present {
+		domainChallenge.Created = &c.Created.Time
+	}
+
+	return domainChallenge, nil
+}
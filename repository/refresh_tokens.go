@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// RefreshTokens abstracts refresh token families repository methods.
+type RefreshTokens interface {
+	// Create starts new refresh token family using provided token hash. Client ID is empty for families of
+	// first-party sessions.
+	Create(ctx context.Context, familyID, userID, clientID string, tokenHash []byte,
+		expires time.Time) (*domain.RefreshToken, error)
+	// Rotate marks refresh token as used and stores its successor within the same family. If already used
+	// token is provided, the whole family gets revoked.
+	Rotate(ctx context.Context, tokenHash, newTokenHash []byte, expires time.Time) (*domain.RefreshToken, error)
+	// FindOne fetches refresh token using token hash.
+	FindOne(ctx context.Context, tokenHash []byte) (*domain.RefreshToken, error)
+	// Revoke revokes refresh token family.
+	Revoke(ctx context.Context, familyID string) error
+	// RevokeAll revokes all refresh token families of the user.
+	RevokeAll(ctx context.Context, userID string) error
+	// RevokeAllExcept revokes all refresh token families of the user except the provided one. This covers both
+	// sessions and families issued to OAuth clients.
+	RevokeAllExcept(ctx context.Context, userID, familyID string) error
+	// RevokeAllOfClient revokes all refresh token families issued to OAuth client.
+	RevokeAllOfClient(ctx context.Context, clientID string) error
+}
@@ -2,6 +2,8 @@
 package repository
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"strings"
 )
@@ -10,8 +12,44 @@ import (
 var (
 	ErrUniqueViolation  = errors.New("resource already existing")
 	ErrResourceNotFound = errors.New("resource not found")
+	ErrTokenReused      = errors.New("token reused")
+	ErrTokenRevoked     = errors.New("token revoked")
+	ErrTokenExpired     = errors.New("token expired")
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrTooManyAttempts  = errors.New("too many attempts")
 )
 
+// Cursor points to the last item of the page in keyset paginated listing. It is bound to the sort order of the
+// listing and the value of sort key together with ID of the item.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// String encodes cursor as opaque URL safe string.
+func (c *Cursor) String() string {
+	data, _ := json.Marshal(c) //nolint:errchkjson
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// ParseCursor decodes cursor encoded by String. ErrInvalidCursor is returned for malformed cursors.
+func ParseCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort == "" || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
 // StripWhitespaces strips out all duplicated whitespaces in a string.
 func StripWhitespaces(s string) string {
 	if s = regex.ReplaceAllString(strings.TrimSpace(s), " "); s != " " {
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"errors"
 	"testing"
 
 	"go.ectobit.com/arc/repository"
@@ -34,3 +35,24 @@ b  c `, "a b c"},
 		})
 	}
 }
+
+func TestCursor(t *testing.T) {
+	t.Parallel()
+
+	cursor := &repository.Cursor{Sort: "-email", Value: "john.doe@sixpack.com", ID: "john"}
+
+	got, err := repository.ParseCursor(cursor.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *got != *cursor {
+		t.Errorf("ParseCursor() = %+v; want %+v", got, cursor)
+	}
+
+	for _, encoded := range []string{"", "invalid!", "e30"} {
+		if _, err := repository.ParseCursor(encoded); !errors.Is(err, repository.ErrInvalidCursor) {
+			t.Errorf("ParseCursor(%q) error = %v; want %v", encoded, err, repository.ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// Revocations abstracts revoked tokens repository methods.
+type Revocations interface {
+	// Revoke revokes single token identified by jti until its expiration.
+	Revoke(ctx context.Context, jti, subject string, expires time.Time) error
+	// RevokeAll revokes all tokens of the subject issued until now, including tokens issued within the current
+	// second, since iat claim has second precision.
+	RevokeAll(ctx context.Context, subject string) error
+	// IsRevoked checks if token is revoked either directly or by revocation of all subject's tokens.
+	IsRevoked(ctx context.Context, jti, subject string, issuedAt time.Time) (bool, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// Sessions abstracts sessions repository methods.
+type Sessions interface {
+	// Create records new session started by login. Refresh token family identified by session ID must exist.
+	Create(ctx context.Context, session *domain.Session) (*domain.Session, error)
+	// FindAll fetches all active sessions of the user.
+	FindAll(ctx context.Context, userID string) ([]domain.Session, error)
+	// Touch updates last seen time of the session.
+	Touch(ctx context.Context, id string) error
+	// Revoke revokes session of the user together with its refresh token family.
+	Revoke(ctx context.Context, userID, id string) error
+	// IsActive checks if refresh token family identified by session ID is not revoked.
+	IsActive(ctx context.Context, id string) (bool, error)
+}
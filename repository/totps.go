@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// TOTPs abstracts TOTP second factor and recovery codes repository methods.
+type TOTPs interface {
+	// Enroll stores new TOTP secret of the user replacing unconfirmed one. ErrUniqueViolation is returned if the
+	// user has already confirmed enrollment.
+	Enroll(ctx context.Context, userID, secret string) (*domain.TOTP, error)
+	// FindOne fetches TOTP of the user.
+	FindOne(ctx context.Context, userID string) (*domain.TOTP, error)
+	// Confirm confirms enrollment using time step of the first valid code and replaces recovery codes.
+	Confirm(ctx context.Context, userID string, step int64, recoveryCodeHashes [][]byte) error
+	// Use stores time step of the valid code and resets failures. ErrTokenReused is returned if code of the same
+	// or later time step has already been used.
+	Use(ctx context.Context, userID string, step int64) error
+	// Attempt counts verification attempt as failure, until Use resets failures, unless there are already
+	// maxFailures consecutive failures. Check and increment are atomic, so parallel attempts can't exceed the limit.
+	// ErrTooManyAttempts is returned if the limit has been reached or TOTP has been deleted meanwhile.
+	Attempt(ctx context.Context, userID string, maxFailures int) error
+	// UseRecoveryCode marks unused recovery code as used and resets failures. ErrResourceNotFound is returned if
+	// there is no such unused code.
+	UseRecoveryCode(ctx context.Context, userID string, codeHash []byte) error
+	// Delete deletes TOTP and recovery codes of the user.
+	Delete(ctx context.Context, userID string) error
+}
@@ -3,24 +3,96 @@ package repository
 import (
 	"context"
 	"regexp"
+	"time"
 
 	"go.ectobit.com/arc/domain"
 )
 
 var regex = regexp.MustCompile(`\s+`)
 
+// Sort keys of users listing.
+const (
+	UsersByCreated = "created"
+	UsersByEmail   = "email"
+)
+
+// UsersQuery contains filters, sort order and page of users listing. Zero values don't filter.
+type UsersQuery struct {
+	// EmailPrefix matches start of the email address case insensitively.
+	EmailPrefix string
+	Active      *bool
+	// CreatedFrom is inclusive and CreatedTo exclusive bound of creation time.
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Sort is either UsersByCreated or UsersByEmail. Ties are broken by ID.
+	Sort       string
+	Descending bool
+	// After is the cursor of the previous page, nil for the first one.
+	After *Cursor
+	Limit int
+}
+
+// SortOrder returns sort key prefixed by minus for descending order, as used in cursors.
+func (q *UsersQuery) SortOrder() string {
+	if q.Descending {
+		return "-" + q.Sort
+	}
+
+	return q.Sort
+}
+
+// UsersPage contains one page of users listing, total count of users matching the filters and cursor of the next
+// page, which is nil for the last one.
+type UsersPage struct {
+	Users []domain.User
+	Total int64
+	Next  *Cursor
+}
+
 // Users abstracts users repository methods.
 type Users interface {
-	// Create creates new user in users repository.
-	Create(ctx context.Context, email string, password []byte) (*domain.User, error)
+	// Create creates new user in users repository storing hash of the activation token.
+	Create(ctx context.Context, email string, password, activationTokenHash []byte) (*domain.User, error)
+	// CreateActivated creates new already activated user in users repository.
+	CreateActivated(ctx context.Context, email string, password []byte) (*domain.User, error)
+	// FindAll fetches page of users matching the query from users repository, without their passwords.
+	// ErrInvalidCursor is returned if the cursor of the query can't point to any user.
+	FindAll(ctx context.Context, query *UsersQuery) (*UsersPage, error)
 	// FindOne fetches user from users repository using ID.
 	FindOne(ctx context.Context, email string) (*domain.User, error)
 	// FindOneByEmail fetches user from users repository using email address.
 	FindOneByEmail(ctx context.Context, email string) (*domain.User, error)
-	// Activate activates user account in users repository.
-	Activate(ctx context.Context, token string) (*domain.User, error)
-	// FetchRecveryToken sets user's password reset token in users repository.
-	FetchRecoveryToken(ctx context.Context, email string) (*domain.User, error)
-	// ResetPassword sets new user's password in users repository.
-	ResetPassword(ctx context.Context, recoveryToken string, password []byte) (*domain.User, error)
+	// Activate activates user account in users repository if activation token has been issued after provided time.
+	// ErrTokenExpired is returned for older tokens and ErrResourceNotFound for unknown ones.
+	Activate(ctx context.Context, activationTokenHash []byte, issuedAfter time.Time) (*domain.User, error)
+	// UpdateProfile replaces profile of the user in users repository.
+	UpdateProfile(ctx context.Context, id string, profile *domain.Profile) (*domain.User, error)
+	// ChangePassword sets new password of the active user in users repository.
+	ChangePassword(ctx context.Context, id string, password []byte) error
+	// SetStatus sets status of the user in users repository and records the change in status history.
+	SetStatus(ctx context.Context, id string, status *domain.UserStatus) (*domain.User, error)
+	// FindStatusHistory fetches status changes of the user from users repository, the newest first.
+	FindStatusHistory(ctx context.Context, id string) ([]domain.UserStatus, error)
+	// Delete deletes the user from users repository together with all user's data.
+	Delete(ctx context.Context, id string) error
+	// ScheduleDeletion schedules deletion of the user in users repository at provided time.
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) (*domain.User, error)
+	// CancelDeletion cancels scheduled deletion of the user in users repository. ErrResourceNotFound is returned if
+	// deletion hasn't been scheduled.
+	CancelDeletion(ctx context.Context, id string) error
+	// DeleteScheduled deletes users whose scheduled deletion time has passed from users repository together with
+	// all their data and returns their count.
+	DeleteScheduled(ctx context.Context) (int64, error)
+	// SetActivationToken replaces hash of the activation token of not yet active user in users repository, unless
+	// the previous token has been issued after provided time. ErrResourceNotFound is returned if there is no such
+	// user or the previous token is too recent.
+	SetActivationToken(ctx context.Context, email string, activationTokenHash []byte,
+		issuedBefore time.Time) (*domain.User, error)
+	// SetRecoveryToken stores hash of the active user's password reset token in users repository, replacing the
+	// previous one.
+	SetRecoveryToken(ctx context.Context, email string, recoveryTokenHash []byte) (*domain.User, error)
+	// ResetPassword sets new user's password in users repository if recovery token has been issued after provided
+	// time. ErrTokenExpired is returned for older tokens and ErrResourceNotFound for unknown ones.
+	ResetPassword(ctx context.Context, recoveryTokenHash []byte, issuedAfter time.Time,
+		password []byte) (*domain.User, error)
 }
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+
+	"go.ectobit.com/arc/domain"
+)
+
+// WebAuthn abstracts WebAuthn credentials and challenges repository methods.
+type WebAuthn interface {
+	// CreateCredential stores new credential. ErrUniqueViolation is returned if credential is already registered.
+	CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) (*domain.WebAuthnCredential, error)
+	// FindCredential fetches credential by its ID.
+	FindCredential(ctx context.Context, id []byte) (*domain.WebAuthnCredential, error)
+	// FindCredentials fetches all credentials of the user.
+	FindCredentials(ctx context.Context, userID string) ([]domain.WebAuthnCredential, error)
+	// UseCredential stores signature counter of the verified assertion and last usage time. ErrTokenReused is
+	// returned if stored counter is not lower, unless both are zero.
+	UseCredential(ctx context.Context, id []byte, signCount uint32) error
+	// DeleteCredential deletes credential of the user.
+	DeleteCredential(ctx context.Context, userID string, id []byte) error
+	// CreateChallenge stores challenge hash.
+	CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error
+	// ConsumeChallenge deletes not expired challenge of the ceremony and returns it. ErrResourceNotFound is
+	// returned if there is no such challenge.
+	ConsumeChallenge(ctx context.Context, hash []byte, ceremony string) (*domain.WebAuthnChallenge, error)
+}